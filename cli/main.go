@@ -3,20 +3,59 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/tenebris-tech/x2md/convert"
+	"github.com/tenebris-tech/x2md/document"
 	"github.com/tenebris-tech/x2md/docx2md"
+	"github.com/tenebris-tech/x2md/imageutil"
 	"github.com/tenebris-tech/x2md/pdf2md"
 	"github.com/tenebris-tech/x2md/xlsx2md"
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "mcp":
+			runMCP(os.Args[2:])
+			return
+		}
+	}
+
+	// An isolation worker takes the conversion flags of its parent with its
+	// job on standard input (see -isolate)
+	worker := len(os.Args) > 1 && os.Args[1] == "worker"
+
 	// Parse command line flags
 	recursive := flag.Bool("r", false, "Recursively process directories")
-	outputDir := flag.String("output-dir", "", "Output directory for converted files (flat structure)")
-	outputFile := flag.String("output", "", "Output file path (single file mode only)")
+	outputDir := flag.String("output-dir", "", "Output directory for converted files (flat structure unless -mirror)")
+	mirror := flag.Bool("mirror", false, "Recreate the source directory tree under -output-dir")
+	outputName := flag.String("name", convert.DefaultOutputName, "Output file name template: {file}, {name}, {ext}, {format}")
+	outputFile := flag.String("output", "", "Output file path, or - for standard output (single file mode only)")
+	inputType := flag.String("type", "", "Input format for standard input or -output -: pdf, docx or xlsx (default: detected)")
+	outputFormat := flag.String("format", "markdown", "Output format: markdown or json")
+	var include, exclude stringList
+	flag.Var(&include, "include", "Only convert files matching this glob, e.g. '*.pdf' (repeatable)")
+	flag.Var(&exclude, "exclude", "Skip files and directories matching this glob, e.g. 'node_modules/' (repeatable)")
+	maxSize := flag.String("max-size", "", "Skip files larger than this size, e.g. 500K, 100M, 2G")
 	skipExisting := flag.Bool("skip-existing", true, "Skip files where .md already exists")
+	incremental := flag.Bool("incremental", false, "Reconvert only files changed since the last run, tracked in "+convert.ManifestName)
+	prune := flag.Bool("prune", false, "Remove outputs of deleted source files (with -incremental)")
+	jobs := flag.Int("j", 1, "Number of files to convert in parallel (0 = one per CPU)")
+	timeout := flag.Duration("timeout", 0, "Maximum time per file, e.g. 2m (0 = no limit)")
+	trustExt := flag.Bool("trust-extensions", false, "Choose the converter from the file extension only (no content detection)")
+	reportFile := flag.String("report", "", "Write a JSON report of every file's outcome to this path")
+	isolate := flag.Bool("isolate", false, "Convert each file in a separate worker process, so crashes and hangs fail only that file")
+	isolateCPU := flag.Duration("isolate-cpu", 0, "CPU time limit per worker with -isolate, e.g. 30s (0 = no limit)")
+	isolateMemory := flag.String("isolate-memory", "", "Memory limit per worker with -isolate, e.g. 2G")
 
 	// PDF-specific options
 	stripNone := flag.Bool("strip-none", false, "Don't strip anything (overrides default) [PDF only]")
@@ -28,6 +67,12 @@ func main() {
 	noLists := flag.Bool("no-lists", false, "Don't detect lists [PDF only]")
 	noHeadings := flag.Bool("no-headings", false, "Don't detect headings [PDF only]")
 	noScanMode := flag.Bool("no-scan-mode", false, "Disable automatic scanned page detection [PDF only]")
+	noFormFields := flag.Bool("no-form-fields", false, "Don't add the fields of fillable forms [PDF only]")
+	outlineTOC := flag.Bool("outline-toc", false, "Add a table of contents from the document outline [PDF only]")
+	structureTree := flag.Bool("structure-tree", false, "Convert tagged PDFs by their structure tree [PDF only]")
+	pages := flag.String("pages", "", "Only convert these pages, e.g. 1-5,10,20- [PDF only]")
+	password := flag.String("password", "", "Password for encrypted files, user or owner [PDF only]")
+	passwordFile := flag.String("password-file", "", "Read the password for encrypted files from this file [PDF only]")
 
 	// XLSX-specific options
 	noFormulas := flag.Bool("no-formulas", false, "Don't show formulas, only values [XLSX only]")
@@ -35,11 +80,24 @@ func main() {
 	// Common options
 	noFormatting := flag.Bool("no-formatting", false, "Don't preserve bold/italic formatting")
 	noImages := flag.Bool("no-images", false, "Don't extract images")
+	inlineImages := flag.Bool("inline-images", false, "Embed images in the Markdown as data: URIs instead of writing files")
+	imageDir := flag.String("image-dir", "", "Write images to this directory, named by SHA-256 of their content")
+	imageURL := flag.String("image-url", "", "Link prefix for images written with -image-dir, e.g. a CDN base URL (default: the -image-dir path)")
 	compact := flag.Bool("compact", false, "Remove excessive blank lines from output")
 	verbose := flag.Bool("v", false, "Show file disposition (converted/skipped/error)")
 	debug := flag.Bool("d", false, "Debug output (includes page/font/style details)")
 
-	flag.Parse()
+	var workerJob *convert.WorkerJob
+	if worker {
+		var err error
+		if workerJob, err = convert.ReadWorkerJob(os.Stdin); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		_ = flag.CommandLine.Parse(workerJob.Args)
+	} else {
+		flag.Parse()
+	}
 
 	// Debug implies verbose
 	if *debug {
@@ -52,11 +110,69 @@ func main() {
 		inputPath = flag.Arg(0)
 	}
 
-	if inputPath == "" {
+	if inputPath == "" && !worker {
 		printUsage()
 		os.Exit(1)
 	}
 
+	// Standard input is written to standard output unless -output is given.
+	// When standard output carries the document, progress goes to stderr and
+	// images are dropped unless inlined or written to -image-dir.
+	fromStdin := inputPath == "-"
+	if fromStdin && *outputFile == "" {
+		*outputFile = "-"
+	}
+	toStdout := *outputFile == "-"
+	logOut := io.Writer(os.Stdout)
+	if toStdout {
+		logOut = os.Stderr
+		if !*inlineImages && *imageDir == "" {
+			*noImages = true
+		}
+	}
+	if (fromStdin || toStdout) && *recursive {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: -r cannot be used with standard input or output\n")
+		os.Exit(1)
+	}
+	if *reportFile != "" && *outputFile != "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: -report cannot be used with -output\n")
+		os.Exit(1)
+	}
+
+	var format convert.OutputFormat
+	switch *outputFormat {
+	case "markdown", "md":
+		format = convert.OutputMarkdown
+	case "json":
+		format = convert.OutputJSON
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "Error: unknown output format %q (use markdown or json)\n", *outputFormat)
+		os.Exit(1)
+	}
+
+	maxFileSize, err := parseSize(*maxSize)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: invalid -max-size %q\n", *maxSize)
+		os.Exit(1)
+	}
+	if *passwordFile != "" {
+		if *password != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: -password and -password-file cannot be used together\n")
+			os.Exit(1)
+		}
+		data, err := os.ReadFile(*passwordFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: reading password: %v\n", err)
+			os.Exit(1)
+		}
+		*password = strings.TrimRight(string(data), "\r\n")
+	}
+	workerMemory, err := parseSize(*isolateMemory)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: invalid -isolate-memory %q\n", *isolateMemory)
+		os.Exit(1)
+	}
+
 	// Build PDF options
 	var pdfOpts []pdf2md.Option
 	if *stripNone || *stripHeaders || *stripPageNumbers || *stripTOC || *stripFootnotes || *stripBlankPages {
@@ -96,6 +212,21 @@ func main() {
 	if *compact {
 		pdfOpts = append(pdfOpts, pdf2md.WithCompact(true))
 	}
+	if *noFormFields {
+		pdfOpts = append(pdfOpts, pdf2md.WithFormFields(false))
+	}
+	if *outlineTOC {
+		pdfOpts = append(pdfOpts, pdf2md.WithOutlineTOC(true))
+	}
+	if *structureTree {
+		pdfOpts = append(pdfOpts, pdf2md.WithStructureTree(true))
+	}
+	if *pages != "" {
+		pdfOpts = append(pdfOpts, pdf2md.WithPages(*pages))
+	}
+	if *password != "" {
+		pdfOpts = append(pdfOpts, pdf2md.WithPassword(*password))
+	}
 
 	// Build DOCX options
 	var docxOpts []docx2md.Option
@@ -122,9 +253,38 @@ func main() {
 	var converterOpts []convert.Option
 	converterOpts = append(converterOpts, convert.WithRecursion(*recursive))
 	converterOpts = append(converterOpts, convert.WithSkipExisting(*skipExisting))
+	converterOpts = append(converterOpts, convert.WithInclude(include...))
+	converterOpts = append(converterOpts, convert.WithExclude(exclude...))
+	converterOpts = append(converterOpts, convert.WithMaxFileSize(maxFileSize))
+	converterOpts = append(converterOpts, convert.WithIncremental(*incremental))
+	converterOpts = append(converterOpts, convert.WithPruneDeleted(*prune))
+	converterOpts = append(converterOpts, convert.WithConcurrency(*jobs))
+	converterOpts = append(converterOpts, convert.WithFileTimeout(*timeout))
+	converterOpts = append(converterOpts, convert.WithTrustExtensions(*trustExt))
+	converterOpts = append(converterOpts, convert.WithOutputFormat(format))
+
+	// Select where images go (default: <name>_images beside each output)
+	if *inlineImages {
+		converterOpts = append(converterOpts, convert.WithImageSink(imageutil.DataURISink{}))
+	} else if *imageDir != "" {
+		linkPrefix := *imageURL
+		if linkPrefix == "" {
+			linkPrefix = filepath.ToSlash(*imageDir)
+		}
+		sink, err := imageutil.NewHashSink(*imageDir, linkPrefix)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		converterOpts = append(converterOpts, convert.WithImageSink(sink))
+	}
 
 	if *outputDir != "" {
 		converterOpts = append(converterOpts, convert.WithOutputDirectory(*outputDir))
+		converterOpts = append(converterOpts, convert.WithPreserveStructure(*mirror))
+	}
+	if *outputName != convert.DefaultOutputName {
+		converterOpts = append(converterOpts, convert.WithOutputName(*outputName))
 	}
 
 	if len(pdfOpts) > 0 {
@@ -137,55 +297,100 @@ func main() {
 		converterOpts = append(converterOpts, convert.WithXLSXOptions(xlsxOpts...))
 	}
 
+	// Standard output carries a worker's results, so it reports no progress
+	if worker {
+		runWorker(workerJob, converterOpts)
+		return
+	}
+
+	// Workers are this executable, given the conversion flags with each job,
+	// so that a password does not show on their command line
+	if *isolate {
+		exe, err := os.Executable()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		converterOpts = append(converterOpts, convert.WithIsolation(convert.Isolation{
+			Command: []string{exe, "worker"},
+			Args:    workerArgs(),
+			CPUTime: *isolateCPU,
+			Memory:  workerMemory,
+		}))
+	}
+
 	// Add verbose callbacks (-v: one line per file)
 	if *verbose {
 		converterOpts = append(converterOpts, convert.WithOnFileComplete(func(path, outputPath string, err error) {
 			if err != nil {
-				fmt.Printf("Error: %s: %v\n", path, err)
+				_, _ = fmt.Fprintf(logOut, "Error: %s: %v\n", path, err)
 			} else {
-				fmt.Printf("Converted: %s\n", path)
+				_, _ = fmt.Fprintf(logOut, "Converted: %s\n", path)
 			}
 		}))
 		converterOpts = append(converterOpts, convert.WithOnFileSkipped(func(path, outputPath, reason string) {
-			fmt.Printf("Skipped: %s (%s)\n", path, reason)
+			_, _ = fmt.Fprintf(logOut, "Skipped: %s (%s)\n", path, reason)
+		}))
+		converterOpts = append(converterOpts, convert.WithOnFileRemoved(func(path, outputPath string) {
+			_, _ = fmt.Fprintf(logOut, "Removed: %s (source %s deleted)\n", outputPath, path)
+		}))
+		converterOpts = append(converterOpts, convert.WithOnFileWarning(func(path string, w document.Warning) {
+			if path == "" {
+				path = inputPath
+			}
+			_, _ = fmt.Fprintf(logOut, "Warning: %s: %s [%s]\n", path, w, w.Code)
 		}))
 	}
 
 	// Add debug callbacks (-d: detailed processing info)
 	if *debug {
 		// PDF-specific debug callbacks
-		pdfOpts = append(pdfOpts,
+		converterOpts = append(converterOpts, convert.WithPDFOptions(
 			pdf2md.WithOnPageParsed(func(pageNum, totalPages int) {
-				fmt.Printf("  Page %d/%d\n", pageNum, totalPages)
+				_, _ = fmt.Fprintf(logOut, "  Page %d/%d\n", pageNum, totalPages)
 			}),
 			pdf2md.WithOnFontParsed(func(fontName string) {
-				fmt.Printf("  Font: %s\n", fontName)
+				_, _ = fmt.Fprintf(logOut, "  Font: %s\n", fontName)
 			}),
-		)
-		converterOpts = append(converterOpts, convert.WithPDFOptions(pdfOpts...))
+		))
 
 		// DOCX-specific debug callbacks
-		docxOpts = append(docxOpts,
+		converterOpts = append(converterOpts, convert.WithDOCXOptions(
 			docx2md.WithOnDocumentParsed(func() {
-				fmt.Println("  Document parsed")
+				_, _ = fmt.Fprintln(logOut, "  Document parsed")
 			}),
 			docx2md.WithOnStylesParsed(func(count int) {
-				fmt.Printf("  Styles: %d\n", count)
+				_, _ = fmt.Fprintf(logOut, "  Styles: %d\n", count)
 			}),
-		)
-		converterOpts = append(converterOpts, convert.WithDOCXOptions(docxOpts...))
+		))
 
-		xlsxOpts = append(xlsxOpts,
+		converterOpts = append(converterOpts, convert.WithXLSXOptions(
 			xlsx2md.WithOnSheetParsed(func(name string, rows, cols int) {
-				fmt.Printf("  Sheet: %s (%d x %d)\n", name, rows, cols)
+				_, _ = fmt.Fprintf(logOut, "  Sheet: %s (%d x %d)\n", name, rows, cols)
 			}),
-		)
-		converterOpts = append(converterOpts, convert.WithXLSXOptions(xlsxOpts...))
+		))
+	}
+
+	// Handle standard input and output
+	if fromStdin || toStdout {
+		// Images go beside an output file unless a sink was chosen
+		var sink imageutil.Sink
+		if !toStdout && !*inlineImages && *imageDir == "" {
+			sink = &lazyImageWriter{mdOutputPath: *outputFile}
+		}
+		if err := convertStream(convert.New(converterOpts...), inputPath, *outputFile, *inputType, sink); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *verbose {
+			_, _ = fmt.Fprintf(logOut, "Converted: %s\n", inputPath)
+		}
+		return
 	}
 
 	// Handle single file with explicit output path
 	if *outputFile != "" && !*recursive {
-		// Use the underlying converters directly for explicit output path
+		// Convert directly to the explicit output path
 		info, err := os.Stat(inputPath)
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -200,7 +405,7 @@ func main() {
 			fmt.Printf("Converting %s to %s...\n", inputPath, *outputFile)
 		}
 
-		err = convertSingleFile(inputPath, *outputFile, pdfOpts, docxOpts, xlsxOpts)
+		err = convert.New(converterOpts...).ConvertFileToFile(inputPath, *outputFile)
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -218,10 +423,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *reportFile != "" {
+		if err := writeReport(*reportFile, result); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: writing report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Print summary
 	if *recursive || *verbose {
-		fmt.Printf("\nComplete: %d converted, %d skipped, %d failed\n",
+		fmt.Printf("\nComplete: %d converted, %d skipped, %d failed",
 			result.Converted, result.Skipped, result.Failed)
+		if result.Removed > 0 {
+			fmt.Printf(", %d removed", result.Removed)
+		}
+		fmt.Println()
 	} else if result.Converted > 0 {
 		fmt.Println("Conversion complete!")
 	}
@@ -233,6 +449,8 @@ func main() {
 
 func printUsage() {
 	fmt.Println("Usage: x2md [options] <input.pdf|input.docx|input.xlsx|directory>")
+	fmt.Println("       x2md serve [options]")
+	fmt.Println("       x2md mcp [options]")
 	fmt.Println()
 	fmt.Println("Converts PDF, DOCX, or XLSX files to Markdown.")
 	fmt.Println()
@@ -240,42 +458,52 @@ func printUsage() {
 	flag.PrintDefaults()
 }
 
-func convertSingleFile(inputPath, outputPath string, pdfOpts []pdf2md.Option, docxOpts []docx2md.Option, xlsxOpts []xlsx2md.Option) error {
-	ext := getExtension(inputPath)
-
-	switch ext {
-	case ".pdf":
-		converter := pdf2md.New(pdfOpts...)
-		return converter.ConvertFileToFile(inputPath, outputPath)
-	case ".docx":
-		converter := docx2md.New(docxOpts...)
-		return converter.ConvertFileToFile(inputPath, outputPath)
-	case ".xlsx":
-		converter := xlsx2md.New(xlsxOpts...)
-		return converter.ConvertFileToFile(inputPath, outputPath)
-	default:
-		return fmt.Errorf("unsupported file type: %s (supported: .pdf, .docx, .xlsx)", ext)
+// writeReport writes the JSON batch report to path
+func writeReport(path string, result *convert.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := result.WriteJSON(f); err != nil {
+		_ = f.Close()
+		return err
 	}
+	return f.Close()
 }
 
-func getExtension(path string) string {
-	for i := len(path) - 1; i >= 0; i-- {
-		if path[i] == '.' {
-			ext := path[i:]
-			// Convert to lowercase
-			result := make([]byte, len(ext))
-			for j := 0; j < len(ext); j++ {
-				c := ext[j]
-				if c >= 'A' && c <= 'Z' {
-					c += 'a' - 'A'
-				}
-				result[j] = c
-			}
-			return string(result)
-		}
-		if path[i] == '/' || path[i] == '\\' {
-			break
-		}
+// stringList is a flag that may be repeated to collect several values
+type stringList []string
+
+func (g *stringList) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *stringList) Set(value string) error {
+	*g = append(*g, value)
+	return nil
+}
+
+// parseSize parses a byte count with an optional K, M or G suffix (powers of 1024)
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	if s == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	switch s[len(s)-1] {
+	case 'K':
+		multiplier = 1 << 10
+	case 'M':
+		multiplier = 1 << 20
+	case 'G':
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size")
 	}
-	return ""
+	return n * multiplier, nil
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tenebris-tech/x2md/mcp"
+)
+
+// runMCP serves the Model Context Protocol over stdin and stdout
+func runMCP(args []string) {
+	flags := flag.NewFlagSet("mcp", flag.ExitOnError)
+	var roots stringList
+	flags.Var(&roots, "root", "Only allow documents under this directory (repeatable; default: any path)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: x2md mcp [options]")
+		_, _ = fmt.Fprintln(os.Stderr)
+		_, _ = fmt.Fprintln(os.Stderr, "Runs a Model Context Protocol server on stdin/stdout exposing the")
+		_, _ = fmt.Fprintln(os.Stderr, "convert_document, get_pages, list_sheets and document_info tools.")
+		_, _ = fmt.Fprintln(os.Stderr)
+		_, _ = fmt.Fprintln(os.Stderr, "Options:")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// stdout carries the protocol, so errors go to stderr only
+	if err := mcp.New(mcp.WithRoots(roots...)).Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tenebris-tech/x2md/server"
+)
+
+// runServe runs the HTTP conversion server until interrupted
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "Address to listen on")
+	maxSize := flags.String("max-size", "64M", "Maximum request size, e.g. 64M")
+	maxConcurrent := flags.Int("j", 0, "Number of requests read and converted at once (0 = one per CPU)")
+	timeout := flags.Duration("timeout", 2*time.Minute, "Maximum time per conversion (0 = no limit)")
+	flags.Usage = func() {
+		fmt.Println("Usage: x2md serve [options]")
+		fmt.Println()
+		fmt.Println("Serves document conversion over HTTP: POST /convert, GET /healthz, GET /metrics.")
+		fmt.Println()
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	maxRequestSize, err := parseSize(*maxSize)
+	if err != nil || maxRequestSize == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: invalid -max-size %q\n", *maxSize)
+		os.Exit(1)
+	}
+
+	srv := &http.Server{
+		Addr: *addr,
+		Handler: server.New(
+			server.WithMaxRequestSize(maxRequestSize),
+			server.WithMaxConcurrent(*maxConcurrent),
+			server.WithTimeout(*timeout),
+		),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Shut down gracefully on SIGINT or SIGTERM, letting conversions finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("x2md serving on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
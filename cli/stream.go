@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
+	"github.com/tenebris-tech/x2md/convert"
+	"github.com/tenebris-tech/x2md/imageutil"
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+)
+
+// convertStream converts a single document where the input or output is a
+// stream: inputPath "-" reads standard input and outputPath "-" writes standard
+// output. inputType names the input format; if empty, it is detected. Images go
+// to sink, or to the converter's image sink if sink is nil.
+func convertStream(c *convert.Converter, inputPath, outputPath, inputType string, sink imageutil.Sink) error {
+	var data []byte
+	var err error
+	if inputPath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(inputPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Write files only once conversion succeeded
+	var out bytes.Buffer
+	r := bytes.NewReader(data)
+	if inputType != "" {
+		err = c.ConvertFormatToWriter(context.Background(), inputType, r, int64(len(data)), &out, sink)
+	} else {
+		err = c.ConvertToWriter(context.Background(), r, int64(len(data)), &out, sink)
+	}
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "-" {
+		_, err = out.WriteTo(os.Stdout)
+		return err
+	}
+	return os.WriteFile(outputPath, out.Bytes(), 0644)
+}
+
+// lazyImageWriter writes images beside a Markdown file, creating the image
+// directory only when the first image arrives
+type lazyImageWriter struct {
+	mdOutputPath string
+	writer       *imageutil.ImageWriter
+}
+
+// Put writes the image and returns its link
+func (w *lazyImageWriter) Put(img *models.ImageItem) (string, error) {
+	if w.writer == nil {
+		writer, err := imageutil.NewImageWriter(w.mdOutputPath)
+		if err != nil {
+			return "", err
+		}
+		w.writer = writer
+	}
+	return w.writer.Put(img)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tenebris-tech/x2md/convert"
+)
+
+// runWorker converts the document of a job read from stdin for an x2md
+// -isolate parent, writing the result to stdout
+func runWorker(job *convert.WorkerJob, opts []convert.Option) {
+	if err := convert.New(opts...).ServeWorkerJob(context.Background(), job, os.Stdout); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// conversionFlags are the flags that change how a document is converted,
+// which are all a worker needs from its parent
+var conversionFlags = []string{
+	"strip-none", "strip-headers", "strip-page-numbers", "strip-toc", "strip-footnotes", "strip-blank-pages",
+	"no-lists", "no-headings", "no-scan-mode", "no-form-fields", "outline-toc", "structure-tree", "pages", "password",
+	"no-formulas", "no-formatting", "no-images", "compact",
+}
+
+// workerArgs returns the conversion flags whose values differ from their
+// defaults, as set after parsing, such as a password read from -password-file
+func workerArgs() []string {
+	var args []string
+	for _, name := range conversionFlags {
+		if f := flag.Lookup(name); f != nil && f.Value.String() != f.DefValue {
+			args = append(args, "-"+name+"="+f.Value.String())
+		}
+	}
+	return args
+}
@@ -1,20 +1,42 @@
 // Package convert provides a unified API for converting documents to Markdown.
 // It wraps the pdf2md, docx2md, and xlsx2md packages and adds support for batch processing,
-// recursive directory traversal, and output directory management.
+// recursive directory traversal, and output directory management. Additional
+// document formats can be plugged in through the Format interface.
 package convert
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/tenebris-tech/x2md/document"
 	"github.com/tenebris-tech/x2md/docx2md"
+	"github.com/tenebris-tech/x2md/imageutil"
 	"github.com/tenebris-tech/x2md/pdf2md"
+	"github.com/tenebris-tech/x2md/pdf2md/models"
 	"github.com/tenebris-tech/x2md/xlsx2md"
 )
 
-// DefaultExtensions lists the file extensions supported by default
+// ErrUnsupportedFormat is returned by the streaming API for content in an
+// unknown or disabled format. It matches ErrUnsupported.
+var ErrUnsupportedFormat = fmt.Errorf("%w format", ErrUnsupported)
+
+// ErrCompoundFile is returned for OLE compound files, such as legacy .doc/.xls
+// files and password-protected OOXML files, which no format converts. It
+// matches ErrUnsupported.
+var ErrCompoundFile = fmt.Errorf("%w: OLE compound file (legacy or encrypted Office)", ErrUnsupported)
+
+// DefaultExtensions lists the file extensions supported by default.
+// Register adds the extensions of newly registered formats.
 var DefaultExtensions = []string{".pdf", ".docx", ".xlsx"}
 
 // Converter handles document to Markdown conversion
@@ -23,6 +45,32 @@ type Converter struct {
 	// Track visited directories and files to avoid loops and duplicates
 	visitedDirs    map[string]bool
 	processedFiles map[string]bool
+	// reservedOutputs holds output paths claimed by queued conversions that
+	// may not have been written yet, so two files never target the same path
+	reservedOutputs map[string]bool
+
+	// mu guards the tracking maps and the Result counters
+	mu sync.Mutex
+	// callbackMu serializes user callbacks invoked from worker goroutines
+	callbackMu sync.Mutex
+	// jobs feeds the worker pool when Concurrency > 1 (nil otherwise)
+	jobs chan fileJob
+	// ctx is the context of the Convert call in progress
+	ctx context.Context
+	// root is the directory that relative output paths are computed from
+	root string
+	// manifest tracks converted sources in incremental mode (nil otherwise)
+	manifest     *Manifest
+	manifestPath string
+}
+
+// fileJob describes a single file queued for conversion
+type fileJob struct {
+	path       string // Real path of the source
+	outputPath string
+	format     Format
+	result     *Result
+	source     *sourceState // Manifest state in incremental mode
 }
 
 // Options holds configuration for the converter
@@ -33,13 +81,59 @@ type Options struct {
 	// Extensions lists file extensions to convert (default: .pdf, .docx, .xlsx)
 	Extensions []string
 
-	// SkipExisting skips files where .md already exists (default: true)
+	// Include limits directory walks to files matching at least one of these
+	// globs. Exclude skips files and directories matching any of them. Both use
+	// the gitignore pattern syntax described at IgnoreFileName, relative to the
+	// converted directory. IgnoreFileName files found while walking are honored.
+	Include []string
+	Exclude []string
+
+	// MaxFileSize skips files larger than this many bytes (0 means no limit)
+	MaxFileSize int64
+
+	// SkipExisting skips files where .md already exists (default: true).
+	// It is ignored in incremental mode, where the manifest decides.
 	SkipExisting bool
 
-	// OutputDirectory writes all output files to this directory (flat structure)
-	// If empty, output files are placed next to source files
+	// Incremental reconverts only sources whose content, x2md version or
+	// options changed since they were last converted, as recorded in a
+	// manifest (ManifestName) in the output directory. The manifest is saved
+	// after every file, so an interrupted batch resumes where it stopped.
+	Incremental bool
+
+	// PruneDeleted removes the outputs of sources that were recorded in the
+	// manifest but no longer exist. It applies in incremental mode only.
+	PruneDeleted bool
+
+	// TrustExtensions selects the converter from the file extension alone.
+	// When false (the default), file content is sniffed so that files with a
+	// missing, unknown or wrong extension are still converted correctly.
+	TrustExtensions bool
+
+	// OutputDirectory writes all output files to this directory (flat structure
+	// unless PreserveStructure is set). If empty, output files are placed next
+	// to source files
 	OutputDirectory string
 
+	// PreserveStructure recreates the source directory layout under
+	// OutputDirectory instead of writing all files to its top level
+	PreserveStructure bool
+
+	// OutputName is the template for output file names (default: DefaultOutputName).
+	// See WithOutputName for the placeholders.
+	OutputName string
+
+	// OutputFormat selects Markdown (default) or JSON output.
+	// JSON output files use the .json extension instead of .md.
+	OutputFormat OutputFormat
+
+	// Concurrency is the number of files converted in parallel (default: 1)
+	Concurrency int
+
+	// FileTimeout limits how long a single file may take to convert.
+	// Files that exceed it are counted as failed. Zero means no limit.
+	FileTimeout time.Duration
+
 	// PDFOptions are passed to the PDF converter
 	PDFOptions []pdf2md.Option
 
@@ -49,7 +143,21 @@ type Options struct {
 	// XLSXOptions are passed to the XLSX converter
 	XLSXOptions []xlsx2md.Option
 
-	// OnFileStart is called when starting to convert a file
+	// Limits bounds the work done for each document, for all formats
+	// (default: document.DefaultLimits). Limits set through the pass-through
+	// options take precedence.
+	Limits document.Limits
+
+	// ImageSink stores extracted images for all formats.
+	// If nil, images are written to a <name>_images directory beside each output.
+	ImageSink imageutil.Sink
+
+	// Formats are additional formats for this converter only.
+	// They take precedence over the globally registered formats.
+	Formats []Format
+
+	// OnFileStart is called when starting to convert a file.
+	// Callbacks are never invoked concurrently, even when Concurrency > 1.
 	OnFileStart func(path string)
 
 	// OnFileComplete is called when a file conversion completes
@@ -57,14 +165,52 @@ type Options struct {
 
 	// OnFileSkipped is called when a file is skipped (e.g., .md already exists)
 	OnFileSkipped func(path, outputPath, reason string)
+
+	// OnFileRemoved is called when the output of a deleted source is removed
+	OnFileRemoved func(path, outputPath string)
+
+	// OnFileWarning is called for each problem that did not stop the
+	// conversion of a file, such as an image that could not be decoded.
+	// path is empty for content converted with ConvertToWriter.
+	OnFileWarning func(path string, w document.Warning)
+
+	// Logger, if set, logs conversion warnings at warn level
+	Logger *slog.Logger
+
+	// Isolation, if set, converts each document in a worker process with
+	// resource limits, containing crashes and hangs (see Isolation)
+	Isolation *Isolation
 }
 
+// OutputFormat is the format of converted output
+type OutputFormat string
+
+// Output formats
+const (
+	// OutputMarkdown writes Markdown
+	OutputMarkdown OutputFormat = "markdown"
+	// OutputJSON writes a structured document.Document as JSON
+	OutputJSON OutputFormat = "json"
+)
+
+// DefaultOutputName is the default output file name template. It appends the
+// output extension to the source file name, e.g. report.pdf -> report.pdf.md.
+const DefaultOutputName = "{file}.{format}"
+
 // Result contains the results of a conversion operation
 type Result struct {
 	Converted int
 	Skipped   int
 	Failed    int
+	Removed   int // Outputs of deleted sources removed by PruneDeleted
 	Errors    []error
+
+	// Files holds one record per file counted above, in completion order
+	Files []FileResult
+
+	// Started is when the conversion began, Duration how long it took
+	Started  time.Time
+	Duration time.Duration
 }
 
 // Option is a functional option for configuring the converter
@@ -76,6 +222,10 @@ func DefaultOptions() *Options {
 		Recursion:    false,
 		Extensions:   DefaultExtensions,
 		SkipExisting: true,
+		OutputName:   DefaultOutputName,
+		OutputFormat: OutputMarkdown,
+		Concurrency:  1,
+		Limits:       document.DefaultLimits(),
 	}
 }
 
@@ -92,16 +242,35 @@ func WithExtensions(exts []string) Option {
 		// Normalize extensions to lowercase with leading dot
 		normalized := make([]string, len(exts))
 		for i, ext := range exts {
-			ext = strings.ToLower(ext)
-			if !strings.HasPrefix(ext, ".") {
-				ext = "." + ext
-			}
-			normalized[i] = ext
+			normalized[i] = normalizeExtension(ext)
 		}
 		o.Extensions = normalized
 	}
 }
 
+// WithInclude limits directory walks to files matching one of the given globs,
+// e.g. "*.pdf" or "reports/**"
+func WithInclude(globs ...string) Option {
+	return func(o *Options) {
+		o.Include = append(o.Include, globs...)
+	}
+}
+
+// WithExclude skips files and directories matching any of the given globs,
+// e.g. "~$*", "node_modules/" or "archive/**"
+func WithExclude(globs ...string) Option {
+	return func(o *Options) {
+		o.Exclude = append(o.Exclude, globs...)
+	}
+}
+
+// WithMaxFileSize skips files larger than n bytes (0 means no limit)
+func WithMaxFileSize(n int64) Option {
+	return func(o *Options) {
+		o.MaxFileSize = n
+	}
+}
+
 // WithSkipExisting sets whether to skip files where .md already exists
 func WithSkipExisting(skip bool) Option {
 	return func(o *Options) {
@@ -109,6 +278,28 @@ func WithSkipExisting(skip bool) Option {
 	}
 }
 
+// WithIncremental enables manifest-based incremental conversion
+func WithIncremental(incremental bool) Option {
+	return func(o *Options) {
+		o.Incremental = incremental
+	}
+}
+
+// WithPruneDeleted sets whether incremental mode removes the outputs of deleted sources
+func WithPruneDeleted(prune bool) Option {
+	return func(o *Options) {
+		o.PruneDeleted = prune
+	}
+}
+
+// WithTrustExtensions sets whether to choose converters by extension only,
+// skipping content detection
+func WithTrustExtensions(trust bool) Option {
+	return func(o *Options) {
+		o.TrustExtensions = trust
+	}
+}
+
 // WithOutputDirectory sets the output directory for converted files
 func WithOutputDirectory(dir string) Option {
 	return func(o *Options) {
@@ -116,24 +307,105 @@ func WithOutputDirectory(dir string) Option {
 	}
 }
 
-// WithPDFOptions sets options to pass to the PDF converter
+// WithPreserveStructure sets whether to mirror the source directory tree under
+// the output directory. Without it, all files are written to the top level.
+func WithPreserveStructure(preserve bool) Option {
+	return func(o *Options) {
+		o.PreserveStructure = preserve
+	}
+}
+
+// WithOutputName sets the template for output file names. The placeholders are
+// {file} (source file name), {name} (source file name without extension),
+// {ext} (source extension without the dot) and {format} (md or json).
+// For example, "{name}.{format}" converts report.pdf to report.md. The
+// template must end in {format} or a literal extension that no source has,
+// so that an output cannot replace its source.
+func WithOutputName(template string) Option {
+	return func(o *Options) {
+		o.OutputName = template
+	}
+}
+
+// WithOutputFormat sets the output format (OutputMarkdown or OutputJSON)
+func WithOutputFormat(f OutputFormat) Option {
+	return func(o *Options) {
+		o.OutputFormat = f
+	}
+}
+
+// WithConcurrency sets the number of files converted in parallel.
+// A value of zero or less uses one worker per CPU.
+func WithConcurrency(n int) Option {
+	return func(o *Options) {
+		if n <= 0 {
+			n = runtime.NumCPU()
+		}
+		o.Concurrency = n
+	}
+}
+
+// WithFileTimeout sets the maximum time allowed to convert a single file.
+// A file that times out is recorded as failed and the batch continues.
+func WithFileTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.FileTimeout = d
+	}
+}
+
+// WithPDFOptions adds options to pass to the PDF converter. Options
+// given in later calls are applied after earlier ones.
 func WithPDFOptions(opts ...pdf2md.Option) Option {
 	return func(o *Options) {
-		o.PDFOptions = opts
+		o.PDFOptions = append(o.PDFOptions, opts...)
 	}
 }
 
-// WithDOCXOptions sets options to pass to the DOCX converter
+// WithDOCXOptions adds options to pass to the DOCX converter. Options
+// given in later calls are applied after earlier ones.
 func WithDOCXOptions(opts ...docx2md.Option) Option {
 	return func(o *Options) {
-		o.DOCXOptions = opts
+		o.DOCXOptions = append(o.DOCXOptions, opts...)
 	}
 }
 
-// WithXLSXOptions sets options to pass to the XLSX converter
+// WithXLSXOptions adds options to pass to the XLSX converter. Options
+// given in later calls are applied after earlier ones.
 func WithXLSXOptions(opts ...xlsx2md.Option) Option {
 	return func(o *Options) {
-		o.XLSXOptions = opts
+		o.XLSXOptions = append(o.XLSXOptions, opts...)
+	}
+}
+
+// WithLimits sets the limits for hostile input, such as the largest
+// decompressed stream, for all formats. Documents that exceed them fail with
+// ErrLimitExceeded.
+func WithLimits(limits document.Limits) Option {
+	return func(o *Options) {
+		o.Limits = limits
+	}
+}
+
+// WithImageSink sets where extracted images are stored for all formats
+func WithImageSink(sink imageutil.Sink) Option {
+	return func(o *Options) {
+		o.ImageSink = sink
+	}
+}
+
+// WithFormat adds a format for this converter only and enables its extensions.
+// Formats added this way take precedence over registered formats with the same extension.
+func WithFormat(f Format) Option {
+	return func(o *Options) {
+		o.Formats = append(o.Formats, f)
+		extensions := append([]string(nil), o.Extensions...)
+		for _, ext := range f.Extensions() {
+			ext = normalizeExtension(ext)
+			if !containsString(extensions, ext) {
+				extensions = append(extensions, ext)
+			}
+		}
+		o.Extensions = extensions
 	}
 }
 
@@ -158,6 +430,36 @@ func WithOnFileSkipped(callback func(path, outputPath, reason string)) Option {
 	}
 }
 
+// WithOnFileRemoved sets the callback for when the output of a deleted source is removed
+func WithOnFileRemoved(callback func(path, outputPath string)) Option {
+	return func(o *Options) {
+		o.OnFileRemoved = callback
+	}
+}
+
+// WithOnFileWarning sets the callback for conversion warnings
+func WithOnFileWarning(callback func(path string, w document.Warning)) Option {
+	return func(o *Options) {
+		o.OnFileWarning = callback
+	}
+}
+
+// WithLogger sets a logger for conversion warnings
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithIsolation converts each document in a separate worker process run by
+// iso.Command, so that a document that crashes or hangs its converter is
+// recorded as failed and the batch continues
+func WithIsolation(iso Isolation) Option {
+	return func(o *Options) {
+		o.Isolation = &iso
+	}
+}
+
 // New creates a new Converter with the given options
 func New(opts ...Option) *Converter {
 	options := DefaultOptions()
@@ -165,9 +467,10 @@ func New(opts ...Option) *Converter {
 		opt(options)
 	}
 	return &Converter{
-		options:        options,
-		visitedDirs:    make(map[string]bool),
-		processedFiles: make(map[string]bool),
+		options:         options,
+		visitedDirs:     make(map[string]bool),
+		processedFiles:  make(map[string]bool),
+		reservedOutputs: make(map[string]bool),
 	}
 }
 
@@ -176,11 +479,22 @@ func New(opts ...Option) *Converter {
 // If path is a directory and Recursion is enabled, it recursively converts all matching files.
 // Returns an error if path is a directory and Recursion is disabled.
 func (c *Converter) Convert(path string) (*Result, error) {
+	return c.ConvertContext(context.Background(), path)
+}
+
+// ConvertContext is like Convert but stops starting new conversions once ctx is
+// cancelled and aborts conversions in progress. The partial Result is returned
+// together with ctx.Err().
+func (c *Converter) ConvertContext(ctx context.Context, path string) (*Result, error) {
+	c.ctx = ctx
+
 	// Reset tracking maps for each Convert call
 	c.visitedDirs = make(map[string]bool)
 	c.processedFiles = make(map[string]bool)
+	c.reservedOutputs = make(map[string]bool)
 
-	result := &Result{}
+	result := &Result{Started: time.Now()}
+	defer func() { result.Duration = time.Since(result.Started) }()
 
 	// Get file info, following symlinks
 	info, err := os.Stat(path)
@@ -195,25 +509,87 @@ func (c *Converter) Convert(path string) (*Result, error) {
 		}
 	}
 
-	if info.IsDir() {
-		if !c.options.Recursion {
-			return nil, fmt.Errorf("%s is a directory; use WithRecursion(true) to process directories", path)
+	if info.IsDir() && !c.options.Recursion {
+		return nil, fmt.Errorf("%s is a directory; use WithRecursion(true) to process directories", path)
+	}
+
+	if err := c.checkOutputName(); err != nil {
+		return nil, err
+	}
+	rules, err := c.filterRules()
+	if err != nil {
+		return nil, err
+	}
+
+	// Output paths mirror the layout below the converted directory
+	c.root = path
+	if !info.IsDir() {
+		c.root = filepath.Dir(path)
+	}
+
+	// Load the manifest from the output root
+	c.manifest = nil
+	if c.options.Incremental {
+		root := c.options.OutputDirectory
+		if root == "" {
+			root = c.root
+		}
+		c.manifestPath = filepath.Join(root, ManifestName)
+		if c.manifest, err = LoadManifest(c.manifestPath); err != nil {
+			return nil, err
 		}
-		c.walkDir(path, result)
+	}
+
+	// Start the worker pool; walkDir and processFile queue jobs onto it
+	var wg sync.WaitGroup
+	if c.options.Concurrency > 1 {
+		c.jobs = make(chan fileJob)
+		for i := 0; i < c.options.Concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range c.jobs {
+					c.convertFile(job)
+				}
+			}()
+		}
+	}
+
+	if info.IsDir() {
+		c.walkDir(path, rules, result)
 	} else {
 		c.processFile(path, result)
 	}
 
+	if c.jobs != nil {
+		close(c.jobs)
+		wg.Wait()
+		c.jobs = nil
+	}
+
+	if c.manifest != nil && c.options.PruneDeleted && info.IsDir() && ctx.Err() == nil {
+		c.pruneDeleted(path, result)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
 	return result, nil
 }
 
-// walkDir recursively walks a directory, following symlinks
-func (c *Converter) walkDir(dir string, result *Result) {
+// addFailure records a file (or directory) at path that failed before or
+// outside conversion
+func (c *Converter) addFailure(result *Result, path string, err error) {
+	c.addFile(result, FileResult{Input: path, Status: StatusFailed, Err: err})
+}
+
+// walkDir recursively walks a directory, following symlinks.
+// rules are the exclusions inherited from the options and parent directories.
+func (c *Converter) walkDir(dir string, rules []ignoreRule, result *Result) {
 	// Resolve to real path to detect loops
 	realDir, err := filepath.EvalSymlinks(dir)
 	if err != nil {
-		result.Failed++
-		result.Errors = append(result.Errors, fmt.Errorf("cannot resolve %s: %w", dir, err))
+		c.addFailure(result, dir, fmt.Errorf("cannot resolve %s: %w", dir, err))
 		return
 	}
 
@@ -226,12 +602,27 @@ func (c *Converter) walkDir(dir string, result *Result) {
 	// Read directory contents
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		result.Failed++
-		result.Errors = append(result.Errors, fmt.Errorf("cannot read directory %s: %w", dir, err))
+		c.addFailure(result, dir, fmt.Errorf("cannot read directory %s: %w", dir, err))
 		return
 	}
 
+	// Add the rules of this directory's ignore file, if any
+	base := c.relPath(dir)
+	if base == "." {
+		base = ""
+	}
+	dirRules, err := readIgnoreFile(filepath.Join(dir, IgnoreFileName), base)
+	if err != nil {
+		c.addFailure(result, filepath.Join(dir, IgnoreFileName), fmt.Errorf("reading ignore file: %w", err))
+	} else if len(dirRules) > 0 {
+		rules = append(rules[:len(rules):len(rules)], dirRules...)
+	}
+
 	for _, entry := range entries {
+		if c.ctx.Err() != nil {
+			return
+		}
+
 		path := filepath.Join(dir, entry.Name())
 
 		// Get file info, following symlinks
@@ -240,80 +631,224 @@ func (c *Converter) walkDir(dir string, result *Result) {
 			// Only count as failure if it looks like a convertible file
 			ext := strings.ToLower(filepath.Ext(path))
 			if c.hasExtension(ext) {
-				result.Failed++
-				result.Errors = append(result.Errors, fmt.Errorf("cannot access %s: %w", path, err))
+				c.addFailure(result, path, fmt.Errorf("cannot access %s: %w", path, err))
 			}
 			// Silently skip broken symlinks to directories or non-convertible files
 			continue
 		}
 
+		// Apply include/exclude patterns; excluded directories are not entered
+		rel := c.relPath(path)
+		if skip, reason := ignored(rules, rel, info.IsDir()); skip || (!info.IsDir() && !c.included(rel)) {
+			if reason == "" {
+				reason = "not matched by include patterns"
+			}
+			if !info.IsDir() && c.hasExtension(strings.ToLower(filepath.Ext(path))) {
+				c.skipFile(result, path, "", reason)
+			}
+			continue
+		}
+
 		if info.IsDir() {
-			c.walkDir(path, result)
+			c.walkDir(path, rules, result)
 		} else {
 			c.processFile(path, result)
 		}
 	}
 }
 
-// processFile converts a single file if it matches the configured extensions
+// processFile converts a single file if it matches the configured extensions.
+// When a worker pool is running, the conversion is queued rather than run inline.
 func (c *Converter) processFile(path string, result *Result) {
-	// Check file extension
-	ext := strings.ToLower(filepath.Ext(path))
-	if !c.hasExtension(ext) {
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	// Determine the format from the extension and, unless trusted, the content.
+	// OLE compound files are reported only under a configured extension.
+	format, formatErr := c.resolveFormat(path)
+	if format == nil && (formatErr == nil || !c.hasExtension(strings.ToLower(filepath.Ext(path)))) {
 		return
 	}
 
-	// Resolve symlinks to get real path
-	realPath, err := filepath.EvalSymlinks(path)
+	// Resolve symlinks to get the absolute real path
+	realPath, err := resolvePath(path)
 	if err != nil {
-		result.Failed++
-		result.Errors = append(result.Errors, fmt.Errorf("cannot resolve %s: %w", path, err))
+		c.addFailure(result, path, fmt.Errorf("cannot resolve %s: %w", path, err))
 		return
 	}
 
 	// Skip if we've already processed this real file
+	c.mu.Lock()
 	if c.processedFiles[realPath] {
+		c.mu.Unlock()
 		return
 	}
 	c.processedFiles[realPath] = true
+	c.mu.Unlock()
 
-	// Determine output path
-	outputPath, skip, reason := c.getOutputPath(realPath)
-	if skip {
-		if c.options.OnFileSkipped != nil {
-			c.options.OnFileSkipped(realPath, outputPath, reason)
+	if formatErr != nil {
+		c.addFailure(result, path, fmt.Errorf("%s: %w", path, formatErr))
+		return
+	}
+
+	// Skip files over the size limit
+	if c.options.MaxFileSize > 0 {
+		info, err := os.Stat(realPath)
+		if err != nil {
+			c.addFailure(result, path, fmt.Errorf("cannot access %s: %w", path, err))
+			return
+		}
+		if info.Size() > c.options.MaxFileSize {
+			c.skipFile(result, realPath, "", fmt.Sprintf("size %d bytes exceeds limit of %d bytes", info.Size(), c.options.MaxFileSize))
+			return
+		}
+	}
+
+	// Compare with the manifest in incremental mode
+	var source *sourceState
+	if c.manifest != nil {
+		source, err = c.inspectSource(realPath, format)
+		if err != nil {
+			c.addFailure(result, realPath, err)
+			return
 		}
-		result.Skipped++
+	}
+
+	// Claim an output path
+	c.mu.Lock()
+	var outputPath, reason string
+	var skip bool
+	if source != nil {
+		outputPath, skip, reason = c.incrementalOutputPath(path, realPath, source)
+	} else {
+		outputPath, skip, reason = c.getOutputPath(path, realPath)
+	}
+	if !skip {
+		c.reservedOutputs[outputPath] = true
+	}
+	c.mu.Unlock()
+
+	if skip {
+		c.skipFile(result, realPath, outputPath, reason)
 		return
 	}
 
+	job := fileJob{path: realPath, outputPath: outputPath, format: format, result: result, source: source}
+	if c.jobs != nil {
+		c.jobs <- job
+		return
+	}
+	c.convertFile(job)
+}
+
+// resolvePath returns the absolute path of a file with symlinks resolved, which
+// identifies it independently of the working directory
+func resolvePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(resolved)
+}
+
+// skipFile records a skipped file in the result and notifies OnFileSkipped
+func (c *Converter) skipFile(result *Result, path, outputPath, reason string) {
+	c.addFile(result, FileResult{Input: path, Output: outputPath, Status: StatusSkipped, Reason: reason})
+
+	if c.options.OnFileSkipped != nil {
+		c.callbackMu.Lock()
+		c.options.OnFileSkipped(path, outputPath, reason)
+		c.callbackMu.Unlock()
+	}
+}
+
+// convertFile runs the conversion for a queued file and records the outcome
+func (c *Converter) convertFile(job fileJob) {
 	// Notify start
 	if c.options.OnFileStart != nil {
-		c.options.OnFileStart(realPath)
+		c.callbackMu.Lock()
+		c.options.OnFileStart(job.path)
+		c.callbackMu.Unlock()
+	}
+
+	// Apply the per-file timeout, if any
+	stats := c.newStats(job.path)
+	ctx := withStats(c.ctx, stats)
+	if c.options.FileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.FileTimeout)
+		defer cancel()
 	}
 
 	// Convert the file
-	var convErr error
-	switch ext {
-	case ".pdf":
-		convErr = c.convertPDF(realPath, outputPath)
-	case ".docx":
-		convErr = c.convertDOCX(realPath, outputPath)
-	case ".xlsx":
-		convErr = c.convertXLSX(realPath, outputPath)
+	start := time.Now()
+	convErr := c.convertWithFormat(ctx, job.format, job.path, job.outputPath)
+	if errors.Is(convErr, context.DeadlineExceeded) && c.ctx.Err() == nil {
+		convErr = fmt.Errorf("conversion timed out after %s: %w", c.options.FileTimeout, convErr)
+	}
+	file := FileResult{
+		Input:    job.path,
+		Output:   job.outputPath,
+		Format:   job.format.Name(),
+		Status:   StatusConverted,
+		Pages:    stats.pages,
+		Images:   stats.images,
+		Duration: time.Since(start),
+		Warnings: stats.warnings.Warnings(),
 	}
 
-	// Notify completion
+	// Record the source in the manifest; failing to save it fails the file
+	if convErr == nil && job.source != nil {
+		if err := c.recordSource(job.path, job.outputPath, job.source); err != nil {
+			convErr = fmt.Errorf("saving manifest: %w", err)
+		}
+	}
+	if convErr != nil {
+		file.Status, file.Err = StatusFailed, fmt.Errorf("%s: %w", job.path, convErr)
+	}
+
+	// Notify completion with the final outcome
 	if c.options.OnFileComplete != nil {
-		c.options.OnFileComplete(realPath, outputPath, convErr)
+		c.callbackMu.Lock()
+		c.options.OnFileComplete(job.path, job.outputPath, convErr)
+		c.callbackMu.Unlock()
 	}
+	c.addFile(job.result, file)
+}
 
-	if convErr != nil {
-		result.Failed++
-		result.Errors = append(result.Errors, fmt.Errorf("%s: %w", realPath, convErr))
-	} else {
-		result.Converted++
+// resolveFormat returns the format to use for path, or nil if the file should
+// be ignored. Content detection wins over the file extension when it recognizes
+// a format; otherwise the format registered for the extension is used. Only
+// files with a configured, missing or unregistered extension are sniffed, so
+// text and source files, such as .md, and files in disabled formats are not
+// opened. OLE compound files return ErrCompoundFile.
+func (c *Converter) resolveFormat(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	sniff := c.hasExtension(ext) || (c.formatForExtension(ext) == nil && !plainExtensions[ext])
+	if !c.options.TrustExtensions && sniff {
+		var detected Format
+		var compound bool
+		err := withFile(path, func(f *os.File, size int64) {
+			detected = detectFormat(c.formats(), f, size)
+			compound = detected == nil && bytes.HasPrefix(readHead(f), cfbSignature)
+		})
+		if err == nil && detected != nil {
+			if c.handlesFormat(detected) {
+				return detected, nil
+			}
+			return nil, nil
+		}
+		if compound {
+			return nil, ErrCompoundFile
+		}
+	}
+
+	// Unknown content: fall back to the extension and let the converter report errors
+	if c.hasExtension(ext) {
+		return c.formatForExtension(ext), nil
 	}
+	return nil, nil
 }
 
 // hasExtension checks if the given extension is in the configured list
@@ -326,23 +861,15 @@ func (c *Converter) hasExtension(ext string) bool {
 	return false
 }
 
-// getOutputPath determines the output path for a given input file.
+// getOutputPath determines the output path for a given input file, found at
+// path while walking and resolving to realPath.
 // Returns the output path, whether to skip the file, and the skip reason.
-func (c *Converter) getOutputPath(inputPath string) (string, bool, string) {
-	// Append .md to full filename (e.g., file.pdf -> file.pdf.md)
-	baseName := filepath.Base(inputPath)
-
-	var outputPath string
-	if c.options.OutputDirectory != "" {
-		// Output to specified directory
-		outputPath = filepath.Join(c.options.OutputDirectory, baseName+".md")
-	} else {
-		// Output next to source file
-		outputPath = inputPath + ".md"
-	}
+// Paths reserved by queued conversions are treated as existing. Callers must hold c.mu.
+func (c *Converter) getOutputPath(path, realPath string) (string, bool, string) {
+	outputPath := c.defaultOutputPath(path, realPath)
 
 	// Check if output file already exists
-	if _, err := os.Stat(outputPath); err == nil {
+	if c.outputExists(outputPath) {
 		if c.options.SkipExisting {
 			return outputPath, true, "output file exists"
 		}
@@ -353,6 +880,74 @@ func (c *Converter) getOutputPath(inputPath string) (string, bool, string) {
 	return outputPath, false, ""
 }
 
+// defaultOutputPath returns the output path for a file before conflicts are
+// resolved. path is the file as found below c.root, realPath its resolved path.
+func (c *Converter) defaultOutputPath(path, realPath string) string {
+	name := c.outputName(filepath.Base(realPath))
+
+	if c.options.OutputDirectory == "" {
+		// Output next to source file
+		return filepath.Join(filepath.Dir(realPath), name)
+	}
+
+	if c.options.PreserveStructure {
+		// Output to the same relative directory under the output directory
+		if rel, err := filepath.Rel(c.root, filepath.Dir(path)); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join(c.options.OutputDirectory, rel, name)
+		}
+	}
+
+	// Output to specified directory
+	return filepath.Join(c.options.OutputDirectory, name)
+}
+
+// outputName applies the OutputName template to a source file name
+func (c *Converter) outputName(fileName string) string {
+	template := c.options.OutputName
+	if template == "" {
+		template = DefaultOutputName
+	}
+	ext := filepath.Ext(fileName)
+	return strings.NewReplacer(
+		"{file}", fileName,
+		"{name}", strings.TrimSuffix(fileName, ext),
+		"{ext}", strings.TrimPrefix(ext, "."),
+		"{format}", c.outputExtension(),
+	).Replace(template)
+}
+
+// checkOutputName reports an invalid OutputName template
+func (c *Converter) checkOutputName() error {
+	template := c.options.OutputName
+	if template == "" {
+		return nil
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("invalid output name template %q: must be a file name", template)
+	}
+	if !strings.Contains(template, "{file}") && !strings.Contains(template, "{name}") {
+		return fmt.Errorf("invalid output name template %q: must contain {file} or {name}", template)
+	}
+
+	// The extension must differ from every source's, so that no output can
+	// take the name of the file it was converted from
+	dot := strings.LastIndex(template, ".")
+	ext := strings.ToLower(template[dot+1:])
+	if dot < 0 || !strings.Contains(ext, "{format}") &&
+		(strings.Contains(ext, "{") || c.hasExtension("."+ext) || c.formatForExtension("."+ext) != nil) {
+		return fmt.Errorf("invalid output name template %q: must end in .{format} or an extension no source has", template)
+	}
+	return nil
+}
+
+// outputExtension returns the extension of output files, without the dot
+func (c *Converter) outputExtension() string {
+	if c.options.OutputFormat == OutputJSON {
+		return "json"
+	}
+	return "md"
+}
+
 // findUniquePath finds a unique output path by appending a number
 func (c *Converter) findUniquePath(basePath string) string {
 	ext := filepath.Ext(basePath)
@@ -360,26 +955,242 @@ func (c *Converter) findUniquePath(basePath string) string {
 
 	for i := 1; ; i++ {
 		candidate := fmt.Sprintf("%s-%d%s", nameWithoutExt, i, ext)
-		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+		if !c.outputExists(candidate) {
 			return candidate
 		}
 	}
 }
 
-// convertPDF converts a PDF file to Markdown
-func (c *Converter) convertPDF(inputPath, outputPath string) error {
-	converter := pdf2md.New(c.options.PDFOptions...)
-	return converter.ConvertFileToFile(inputPath, outputPath)
+// outputExists reports whether an output path is on disk or reserved by a queued conversion
+func (c *Converter) outputExists(path string) bool {
+	if c.reservedOutputs[path] {
+		return true
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ConvertFileToFile converts a single file to an explicit output path, choosing
+// the format the same way Convert does. Images are written beside outputPath.
+func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
+	return c.ConvertFileToFileContext(context.Background(), inputPath, outputPath)
+}
+
+// ConvertFileToFileContext is like ConvertFileToFile but stops when ctx is cancelled
+func (c *Converter) ConvertFileToFileContext(ctx context.Context, inputPath, outputPath string) error {
+	format, err := c.resolveFormat(inputPath)
+	if err != nil {
+		return err
+	}
+	if format == nil {
+		var supported []string
+		for _, f := range c.formats() {
+			for _, ext := range f.Extensions() {
+				if c.hasExtension(normalizeExtension(ext)) && !containsString(supported, ext) {
+					supported = append(supported, ext)
+				}
+			}
+		}
+		return document.MarkError(ErrUnsupported, fmt.Errorf("unsupported file type: %s (supported: %s)",
+			filepath.Ext(inputPath), strings.Join(supported, ", ")))
+	}
+	return c.convertWithFormat(withStats(ctx, c.newStats(inputPath)), format, inputPath, outputPath)
+}
+
+// ConvertFileToDocument converts a file to a structured document, choosing the
+// format the same way Convert does. The returned images are not stored; image
+// blocks refer to them by ID.
+func (c *Converter) ConvertFileToDocument(ctx context.Context, path string) (*document.Document, []*models.ImageItem, error) {
+	format, err := c.resolveFormat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if format == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	df, ok := format.(DocumentFormat)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s format does not support structured output", format.Name())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading file: %w", err)
+	}
+	conv, err := c.convert(withStats(ctx, c.newStats(path)), df, data, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conv.Document, conv.Images, nil
+}
+
+// ConvertToWriter converts a document read from r, writes the Markdown (or JSON,
+// per OutputFormat) to w and hands images to sink. The format is detected from
+// the content. If sink is nil, the ImageSink option is used; if that is nil too,
+// images are not stored: their ![id] placeholders are left in the Markdown and
+// JSON images have no src.
+func (c *Converter) ConvertToWriter(ctx context.Context, r io.ReaderAt, size int64, w io.Writer, sink imageutil.Sink) error {
+	format := detectFormat(c.formats(), r, size)
+	if format == nil || !c.handlesFormat(format) {
+		return fmt.Errorf("%w: content not recognized", ErrUnsupportedFormat)
+	}
+	return c.convertToWriter(ctx, format, r, size, w, sink)
+}
+
+// ConvertFormatToWriter is like ConvertToWriter but converts the content as the
+// named format (e.g. "pdf", or an extension such as ".pdf") instead of detecting it
+func (c *Converter) ConvertFormatToWriter(ctx context.Context, name string, r io.ReaderAt, size int64, w io.Writer, sink imageutil.Sink) error {
+	format := c.formatByName(name)
+	if format == nil || !c.handlesFormat(format) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, name)
+	}
+	return c.convertToWriter(ctx, format, r, size, w, sink)
+}
+
+// convertToWriter converts the content of r with format and writes the output to w
+func (c *Converter) convertToWriter(ctx context.Context, format Format, r io.ReaderAt, size int64, w io.Writer, sink imageutil.Sink) error {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return fmt.Errorf("reading input: %w", err)
+	}
+
+	if sink == nil {
+		sink = c.options.ImageSink
+	}
+	output, err := c.render(withStats(ctx, c.newStats("")), format, data, func() (imageutil.Sink, error) {
+		return sink, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(output)
+	return err
+}
+
+// convertWithFormat converts inputPath with the given format, writing the
+// output to outputPath and any images beside it
+func (c *Converter) convertWithFormat(ctx context.Context, format Format, inputPath, outputPath string) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	output, err := c.render(ctx, format, data, func() (imageutil.Sink, error) {
+		if c.options.ImageSink != nil {
+			return c.options.ImageSink, nil
+		}
+		return imageutil.NewImageWriter(outputPath)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	return os.WriteFile(outputPath, output, 0644)
+}
+
+// render converts data with format into the configured output format.
+// newSink is called only when there are images to store; a nil sink leaves them unstored.
+func (c *Converter) render(ctx context.Context, format Format, data []byte, newSink func() (imageutil.Sink, error)) ([]byte, error) {
+	structured := c.options.OutputFormat == OutputJSON
+	if _, ok := format.(DocumentFormat); structured && !ok {
+		return nil, fmt.Errorf("%s format does not support JSON output", format.Name())
+	}
+	conv, err := c.convert(ctx, format, data, structured)
+	if err != nil {
+		return nil, err
+	}
+	images := conv.Images
+	stats := statsFrom(ctx)
+	stats.images = len(images)
+
+	if structured {
+		doc := conv.Document
+		if doc.Metadata != nil && doc.Metadata.Pages > 0 {
+			stats.pages = doc.Metadata.Pages
+		}
+		for _, w := range doc.Warnings {
+			stats.warnings.Warn(w)
+		}
+
+		if len(images) > 0 {
+			sink, err := newSink()
+			if err != nil {
+				return nil, fmt.Errorf("writing images: %w", err)
+			}
+			if sink != nil {
+				reported := len(stats.warnings.Warnings())
+				doc.SetImageLinks(storeImages(ctx, images, sink))
+				doc.Warnings = append(doc.Warnings, stats.warnings.Warnings()[reported:]...)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := doc.WriteJSON(&buf); err != nil {
+			return nil, fmt.Errorf("encoding JSON: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	markdown := conv.Markdown
+	if len(images) > 0 {
+		sink, err := newSink()
+		if err != nil {
+			return nil, fmt.Errorf("writing images: %w", err)
+		}
+		if sink != nil {
+			markdown = imageutil.LinkImages(markdown, images, storeImages(ctx, images, sink))
+		}
+	}
+
+	return []byte(markdown), nil
+}
+
+// conversion is a document converted by a format, before its images are stored
+type conversion struct {
+	Markdown string             // Set for Markdown output
+	Document *document.Document // Set for structured output
+	Images   []*models.ImageItem
+}
+
+// convert converts data with format to Markdown, or to a structured document
+// if structured is set, in a worker process when isolation is enabled
+func (c *Converter) convert(ctx context.Context, format Format, data []byte, structured bool) (*conversion, error) {
+	if c.options.Isolation != nil {
+		return c.convertIsolated(ctx, format, data, structured)
+	}
+	return c.convertData(ctx, format, data, structured)
 }
 
-// convertDOCX converts a DOCX file to Markdown
-func (c *Converter) convertDOCX(inputPath, outputPath string) error {
-	converter := docx2md.New(c.options.DOCXOptions...)
-	return converter.ConvertFileToFile(inputPath, outputPath)
+// convertData converts data with format in the current process
+func (c *Converter) convertData(ctx context.Context, format Format, data []byte, structured bool) (*conversion, error) {
+	if !structured {
+		markdown, images, err := format.Convert(ctx, data, c.options)
+		if err != nil {
+			return nil, err
+		}
+		return &conversion{Markdown: markdown, Images: images}, nil
+	}
+
+	df, ok := format.(DocumentFormat)
+	if !ok {
+		return nil, fmt.Errorf("%s format does not support structured output", format.Name())
+	}
+	doc, images, err := df.ConvertDocument(ctx, data, c.options)
+	if err != nil {
+		return nil, err
+	}
+	return &conversion{Document: doc, Images: images}, nil
 }
 
-// convertXLSX converts an XLSX file to Markdown
-func (c *Converter) convertXLSX(inputPath, outputPath string) error {
-	converter := xlsx2md.New(c.options.XLSXOptions...)
-	return converter.ConvertFileToFile(inputPath, outputPath)
+// storeImages stores images in sink and returns their links. Images the sink
+// fails to store are left unlinked and reported as warnings.
+func storeImages(ctx context.Context, images []*models.ImageItem, sink imageutil.Sink) map[string]string {
+	warnings := statsFrom(ctx).warnings
+	return imageutil.StoreImagesReport(images, sink, func(img *models.ImageItem, err error) {
+		warnings.Warnf(document.WarnImageNotStored, 0, "image %s: %v", img.ID, err)
+	})
 }
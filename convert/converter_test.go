@@ -3,9 +3,14 @@ package convert
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tenebris-tech/x2md/docx2md"
 	"github.com/tenebris-tech/x2md/pdf2md"
@@ -106,6 +111,12 @@ func TestWithPDFOptions(t *testing.T) {
 	if len(c.options.PDFOptions) != 1 {
 		t.Errorf("Expected 1 PDF option, got %d", len(c.options.PDFOptions))
 	}
+
+	// Later calls add to the options of earlier ones
+	c = New(WithPDFOptions(pdf2md.WithExtractImages(false)), WithPDFOptions(pdf2md.WithCompact(true)))
+	if len(c.options.PDFOptions) != 2 {
+		t.Errorf("Expected 2 PDF options, got %d", len(c.options.PDFOptions))
+	}
 }
 
 func TestWithDOCXOptions(t *testing.T) {
@@ -429,6 +440,65 @@ func TestOutputDirectoryConflictingNames(t *testing.T) {
 	}
 }
 
+func TestPreserveStructure(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	outDir := filepath.Join(tmpDir, "out")
+	for _, dir := range []string{"a", filepath.Join("b", "c")} {
+		if err := os.MkdirAll(filepath.Join(srcDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Files with the same name in different directories must not collide
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	for _, dir := range []string{"a", filepath.Join("b", "c")} {
+		if err := os.WriteFile(filepath.Join(srcDir, dir, "report.docx"), docxData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New(WithRecursion(true), WithOutputDirectory(outDir), WithPreserveStructure(true))
+	result, err := c.Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 2 {
+		t.Errorf("Expected 2 converted, got %d", result.Converted)
+	}
+
+	for _, rel := range []string{"a/report.docx.md", "b/c/report.docx.md"} {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("Expected %s in output directory", rel)
+		}
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+
+	docxPath := filepath.Join(tmpDir, "report.docx")
+	if err := os.WriteFile(docxPath, createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(WithOutputDirectory(outDir), WithOutputName("{name}-{ext}.{format}"), WithOutputFormat(OutputJSON))
+	if _, err := c.Convert(docxPath); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "report-docx.json")); err != nil {
+		t.Error("Expected report-docx.json in output directory")
+	}
+
+	// Templates must produce a file name with an extension no source has
+	for _, template := range []string{"sub/{name}.md", "output.md", "{file}", "{name}", "{name}.{ext}", "{name}.DOCX", "{name}.pdf"} {
+		if _, err := New(WithOutputName(template)).Convert(docxPath); err == nil {
+			t.Errorf("Expected error for template %q", template)
+		}
+	}
+}
+
 func TestExtensionFilter(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "convert_test")
 	if err != nil {
@@ -509,3 +579,127 @@ func TestCallbacks(t *testing.T) {
 		t.Errorf("Expected no error, got: %v", completeErr)
 	}
 }
+
+func TestWithConcurrency(t *testing.T) {
+	c := New(WithConcurrency(4))
+	if c.options.Concurrency != 4 {
+		t.Errorf("Expected Concurrency 4, got %d", c.options.Concurrency)
+	}
+
+	c = New(WithConcurrency(0))
+	if c.options.Concurrency < 1 {
+		t.Errorf("Expected Concurrency >= 1 for 0, got %d", c.options.Concurrency)
+	}
+}
+
+func TestConcurrentConversion(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	srcDir := filepath.Join(tmpDir, "src")
+	outDir := filepath.Join(tmpDir, "out")
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+
+	// Same file name in several directories to exercise output path reservation
+	for i := 0; i < 8; i++ {
+		dir := filepath.Join(srcDir, fmt.Sprintf("d%d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "test.docx"), docxData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var started, completed int
+	c := New(
+		WithRecursion(true),
+		WithConcurrency(4),
+		WithOutputDirectory(outDir),
+		WithSkipExisting(false),
+		WithOnFileStart(func(path string) {
+			started++
+		}),
+		WithOnFileComplete(func(path, outputPath string, err error) {
+			completed++
+		}),
+	)
+	result, err := c.Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if result.Converted != 8 || result.Failed != 0 {
+		t.Errorf("Expected 8 converted and 0 failed, got %d and %d", result.Converted, result.Failed)
+	}
+	if started != 8 || completed != 8 {
+		t.Errorf("Expected 8 start/complete callbacks, got %d/%d", started, completed)
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 8 {
+		t.Errorf("Expected 8 distinct output files, got %d", len(entries))
+	}
+}
+
+func TestFileTimeout(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	for _, name := range []string{"a.docx", "b.docx"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), docxData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New(WithRecursion(true), WithFileTimeout(time.Nanosecond))
+	result, err := c.Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	// Both files time out, but the batch keeps going
+	if result.Failed != 2 {
+		t.Errorf("Expected 2 failed, got %d", result.Failed)
+	}
+	for _, e := range result.Errors {
+		if !errors.Is(e, context.DeadlineExceeded) || !strings.Contains(e.Error(), "timed out") {
+			t.Errorf("Expected timeout error, got: %v", e)
+		}
+	}
+}
+
+func TestConvertContextCancelled(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "test.docx"), docxData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := New(WithRecursion(true))
+	result, err := c.ConvertContext(ctx, tmpDir)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if result == nil || result.Converted != 0 {
+		t.Errorf("Expected no conversions after cancellation, got %+v", result)
+	}
+}
@@ -0,0 +1,174 @@
+package convert
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FormatCFB is returned by DetectFormat for OLE Compound File Binary documents:
+// legacy .doc/.xls files and password-protected OOXML files. No converter supports them.
+const FormatCFB = ".cfb"
+
+// sniffSize is how many leading bytes are examined for a file signature.
+// The PDF specification allows junk before the %PDF- header, and readers
+// commonly accept it within the first 1024 bytes.
+const sniffSize = 1024
+
+// plainExtensions are extensions of text, markup and source files. Their
+// content is never sniffed, so that a Markdown or source file mentioning a
+// signature is not taken for a document.
+var plainExtensions = map[string]bool{
+	".md": true, ".markdown": true, ".txt": true, ".text": true, ".log": true,
+	".csv": true, ".tsv": true, ".json": true, ".xml": true, ".yaml": true,
+	".yml": true, ".toml": true, ".ini": true, ".html": true, ".htm": true,
+	".css": true, ".js": true, ".ts": true, ".go": true, ".py": true,
+	".rb": true, ".java": true, ".c": true, ".h": true, ".cpp": true,
+	".rs": true, ".sh": true, ".tex": true, ".rst": true, ".svg": true,
+}
+
+var (
+	pdfSignature = []byte("%PDF-")
+	zipSignature = []byte("PK\x03\x04")
+	cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+)
+
+// DetectFormat inspects document content and returns the canonical extension
+// of the registered format that recognizes it (e.g. ".pdf", ".docx" or ".xlsx"),
+// FormatCFB for unsupported OLE compound files, or "" if the content is not recognized.
+func DetectFormat(data []byte) string {
+	return detectExtension(Formats(), bytes.NewReader(data), int64(len(data)))
+}
+
+// DetectFile is like DetectFormat but reads only as much of the file as needed
+func DetectFile(path string) (string, error) {
+	var ext string
+	err := withFile(path, func(f *os.File, size int64) {
+		ext = detectExtension(Formats(), f, size)
+	})
+	return ext, err
+}
+
+// detectFile returns the first of formats that recognizes the file at path, or nil
+func detectFile(formats []Format, path string) (Format, error) {
+	var format Format
+	err := withFile(path, func(f *os.File, size int64) {
+		format = detectFormat(formats, f, size)
+	})
+	return format, err
+}
+
+// withFile opens a regular file and passes it with its size to fn
+func withFile(path string, fn func(f *os.File, size int64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	fn(f, info.Size())
+	return nil
+}
+
+// detectExtension returns the canonical extension of the detected format,
+// FormatCFB for OLE compound files no format claims, or ""
+func detectExtension(formats []Format, r io.ReaderAt, size int64) string {
+	if f := detectFormat(formats, r, size); f != nil {
+		if exts := f.Extensions(); len(exts) > 0 {
+			return normalizeExtension(exts[0])
+		}
+	}
+	if bytes.HasPrefix(readHead(r), cfbSignature) {
+		return FormatCFB
+	}
+	return ""
+}
+
+// detectFormat returns the first of formats whose Detect accepts the content, or nil
+func detectFormat(formats []Format, r io.ReaderAt, size int64) Format {
+	for _, f := range formats {
+		if f.Detect(r, size) {
+			return f
+		}
+	}
+	return nil
+}
+
+// hasPDFHeader reports whether head starts with the %PDF- header, or has
+// it within the first sniffSize bytes after binary junk such as a MacBinary
+// header. A header after text only is not accepted, since it is a mention
+// of the signature rather than a PDF file.
+func hasPDFHeader(head []byte) bool {
+	i := bytes.Index(head, pdfSignature)
+	if i < 0 {
+		return false
+	}
+	if rest := head[i+len(pdfSignature):]; len(rest) < 3 || rest[0] < '1' || rest[0] > '9' || rest[1] != '.' {
+		return false
+	}
+	return i == 0 || isBinary(head[:i])
+}
+
+// isBinary reports whether data holds a NUL or other control byte that
+// does not occur in text
+func isBinary(data []byte) bool {
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' {
+			return true
+		}
+	}
+	return false
+}
+
+// readHead reads up to sniffSize leading bytes
+func readHead(r io.ReaderAt) []byte {
+	head := make([]byte, sniffSize)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil
+	}
+	return head[:n]
+}
+
+// detectOOXML distinguishes Word and Excel packages by their main part
+func detectOOXML(r io.ReaderAt, size int64) string {
+	if !bytes.HasPrefix(readHead(r), zipSignature) {
+		return ""
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ""
+	}
+
+	var hasContentTypes, hasDocument, hasWorkbook bool
+	for _, f := range zr.File {
+		switch f.Name {
+		case "[Content_Types].xml":
+			hasContentTypes = true
+		case "word/document.xml":
+			hasDocument = true
+		case "xl/workbook.xml":
+			hasWorkbook = true
+		}
+	}
+
+	switch {
+	case !hasContentTypes:
+		return ""
+	case hasDocument:
+		return ".docx"
+	case hasWorkbook:
+		return ".xlsx"
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package convert
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createTestZip creates a zip archive containing empty entries with the given names
+func createTestZip(names ...string) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, name := range names {
+		f, _ := w.Create(name)
+		_, _ = f.Write([]byte("<x/>"))
+	}
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"pdf", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), ".pdf"},
+		{"pdf with leading junk", append([]byte("\x00\x05junk\r\n"), []byte("%PDF-1.4\n")...), ".pdf"},
+		{"pdf signature after text", []byte("Files start with %PDF-1.4 headers\n"), ""},
+		{"pdf signature without version", []byte("%PDF-\n"), ""},
+		{"docx", createTestDocx(`<w:p/>`), ".docx"},
+		{"xlsx", createTestZip("[Content_Types].xml", "xl/workbook.xml"), ".xlsx"},
+		{"zip without content types", createTestZip("word/document.xml"), ""},
+		{"plain zip", createTestZip("readme.txt"), ""},
+		{"cfb", []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1, 0, 0}, FormatCFB},
+		{"text", []byte("hello world"), ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.data); got != tt.want {
+				t.Errorf("DetectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertDetectsContent(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	// A DOCX without an extension and a DOCX mislabelled as PDF
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "document.bin"), docxData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "mislabelled.pdf"), docxData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Unrecognized content is still ignored, and text files are never sniffed
+	if err := os.WriteFile(filepath.Join(tmpDir, "notes.txt"), []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "readme.md"), []byte("%PDF-1.7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(WithRecursion(true))
+	result, err := c.Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 2 || result.Failed != 0 {
+		t.Errorf("Expected 2 converted and 0 failed, got %d and %d: %v", result.Converted, result.Failed, result.Errors)
+	}
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "mislabelled.pdf.md"))
+	if !bytes.Contains(content, []byte("Hello")) {
+		t.Errorf("Expected mislabelled file converted as DOCX, got: %s", content)
+	}
+}
+
+func TestTrustExtensions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "document.bin"), docxData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(WithRecursion(true), WithTrustExtensions(true))
+	result, err := c.Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 0 {
+		t.Errorf("Expected extension-less file to be ignored, got %d converted", result.Converted)
+	}
+}
+
+func TestCompoundFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	// An encrypted DOCX fails clearly; a legacy .doc is not a configured extension
+	cfbData := append(append([]byte{}, cfbSignature...), make([]byte, 504)...)
+	locked := filepath.Join(tmpDir, "locked.docx")
+	if err := os.WriteFile(locked, cfbData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "legacy.doc"), cfbData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Files in a disabled format are not sniffed, whatever they contain
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "sheet.xlsx"), docxData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(WithRecursion(true), WithExtensions([]string{".docx"}))
+	result, err := c.Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 0 || result.Failed != 1 {
+		t.Fatalf("Expected 0 converted and 1 failed, got %d and %d: %v", result.Converted, result.Failed, result.Errors)
+	}
+	if !errors.Is(result.Errors[0], ErrUnsupported) || !strings.Contains(result.Errors[0].Error(), "OLE compound file") {
+		t.Errorf("Expected an OLE compound file error, got %v", result.Errors[0])
+	}
+
+	err = c.ConvertFileToFile(locked, filepath.Join(tmpDir, "locked.md"))
+	if !errors.Is(err, ErrCompoundFile) {
+		t.Errorf("Expected ErrCompoundFile, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the per-directory ignore file honored during
+// directory walks. It uses gitignore syntax: one glob per line, # comments,
+// ! to re-include, a trailing / to match directories only, and a leading or
+// inner / to anchor the pattern to the directory holding the ignore file.
+const IgnoreFileName = ".x2mdignore"
+
+// ignoreRule is a single exclusion pattern in effect during a directory walk
+type ignoreRule struct {
+	base    string // Slash-separated directory the pattern is relative to ("" for the walk root)
+	pattern string
+	negate  bool
+	dirOnly bool
+	origin  string // Ignore file the rule came from, or "" for WithExclude
+}
+
+// newIgnoreRule parses a gitignore-style pattern relative to base
+func newIgnoreRule(line, base, origin string) (ignoreRule, error) {
+	rule := ignoreRule{base: base, origin: origin}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	rule.pattern = line
+	if err := checkPattern(line); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// readIgnoreFile reads the rules of an ignore file. A missing file has no rules.
+func readIgnoreFile(file, base string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, `\`) // \# and \! escape a leading character
+		rule, err := newIgnoreRule(line, base, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, lineNum, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// ignored reports whether rel (slash-separated, relative to the walk root) is
+// excluded by rules, and the reason. Later rules override earlier ones.
+func ignored(rules []ignoreRule, rel string, isDir bool) (bool, string) {
+	excluded, reason := false, ""
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if !matchPattern(rule.pattern, name) {
+			continue
+		}
+		excluded = !rule.negate
+		if rule.origin != "" {
+			reason = fmt.Sprintf("ignored by pattern %q in %s", rule.pattern, rule.origin)
+		} else {
+			reason = fmt.Sprintf("excluded by pattern %q", rule.pattern)
+		}
+	}
+	return excluded, reason
+}
+
+// matchPattern reports whether a gitignore-style glob matches the slash-separated
+// path rel. Patterns without an inner slash match at any depth; ** matches any
+// number of directories.
+func matchPattern(pattern, rel string) bool {
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// checkPattern reports a malformed glob
+func checkPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// filterRules returns the WithExclude patterns as ignore rules
+func (c *Converter) filterRules() ([]ignoreRule, error) {
+	for _, pattern := range c.options.Include {
+		if err := checkPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+	var rules []ignoreRule
+	for _, pattern := range c.options.Exclude {
+		rule, err := newIgnoreRule(pattern, "", "")
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// included reports whether a file at rel matches the WithInclude patterns
+func (c *Converter) included(rel string) bool {
+	if len(c.options.Include) == 0 {
+		return true
+	}
+	for _, pattern := range c.options.Include {
+		if matchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// relPath returns path relative to the walk root, slash-separated
+func (c *Converter) relPath(p string) string {
+	rel, err := filepath.Rel(c.root, p)
+	if err != nil {
+		return filepath.ToSlash(filepath.Base(p))
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,117 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.pdf", "report.pdf", true},
+		{"*.pdf", "a/b/report.pdf", true},
+		{"~$*", "docs/~$report.docx", true},
+		{"node_modules", "web/node_modules", true},
+		{"/archive", "archive", true},
+		{"/archive", "old/archive", false},
+		{"reports/*.pdf", "reports/q1.pdf", true},
+		{"reports/*.pdf", "x/reports/q1.pdf", false},
+		{"reports/**", "reports/2024/q1.pdf", true},
+		{"**/drafts/*.docx", "a/b/drafts/x.docx", true},
+		{"*.pdf", "report.docx", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIncludeExcludeFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	files := []string{
+		"keep.docx",
+		"~$keep.docx",
+		"big.docx",
+		"node_modules/dep.docx",
+		"archive/old.docx",
+		"archive/important.docx",
+		"drafts/draft.docx",
+	}
+	for _, name := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		data := docxData
+		if name == "big.docx" {
+			data = append(append([]byte(nil), docxData...), make([]byte, 4096)...)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The ignore file in archive/ re-includes one file
+	ignore := "# Old material\n*.docx\n!important.docx\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "archive", IgnoreFileName), []byte(ignore), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reasons := make(map[string]string)
+	c := New(
+		WithRecursion(true),
+		WithExclude("~$*", "node_modules/"),
+		WithInclude("*.docx"),
+		WithExclude("drafts/"),
+		WithMaxFileSize(int64(len(docxData)+1024)),
+		WithOnFileSkipped(func(path, outputPath, reason string) {
+			reasons[filepath.Base(path)] = reason
+		}),
+	)
+	result, err := c.Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	var converted []string
+	for _, name := range files {
+		if _, err := os.Stat(filepath.Join(tmpDir, filepath.FromSlash(name)+".md")); err == nil {
+			converted = append(converted, name)
+		}
+	}
+	sort.Strings(converted)
+	if got := strings.Join(converted, ","); got != "archive/important.docx,keep.docx" {
+		t.Errorf("Converted %s, want archive/important.docx,keep.docx", got)
+	}
+
+	// Excluded directories are not entered, so only files are reported
+	if result.Skipped != 3 {
+		t.Errorf("Expected 3 skipped, got %d (%v)", result.Skipped, reasons)
+	}
+	if !strings.Contains(reasons["~$keep.docx"], `"~$*"`) {
+		t.Errorf("Unexpected reason for lock file: %q", reasons["~$keep.docx"])
+	}
+	if !strings.Contains(reasons["old.docx"], IgnoreFileName) {
+		t.Errorf("Unexpected reason for ignored file: %q", reasons["old.docx"])
+	}
+	if !strings.Contains(reasons["big.docx"], "exceeds limit") {
+		t.Errorf("Unexpected reason for large file: %q", reasons["big.docx"])
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	tmpDir := t.TempDir()
+	if _, err := New(WithRecursion(true), WithExclude("[")).Convert(tmpDir); err == nil {
+		t.Error("Expected error for invalid exclude pattern")
+	}
+}
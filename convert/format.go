@@ -0,0 +1,286 @@
+package convert
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/docx2md"
+	"github.com/tenebris-tech/x2md/pdf2md"
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+	"github.com/tenebris-tech/x2md/xlsx2md"
+)
+
+// Format is a document type that can be converted to Markdown.
+// Implementations must be safe for concurrent use.
+type Format interface {
+	// Name returns a short identifier such as "pdf"
+	Name() string
+
+	// Extensions returns the file extensions handled by the format,
+	// lowercase with a leading dot. The first one is the canonical extension.
+	Extensions() []string
+
+	// Detect reports whether the content of r is in this format.
+	// It should read no more than it needs to recognize the format.
+	Detect(r io.ReaderAt, size int64) bool
+
+	// Convert converts document data to Markdown and returns the images to
+	// write alongside it. Images are referenced in the Markdown by ![ID]
+	// placeholders, which are replaced with links once the images are written.
+	// opts carries the converter options, including pass-through options.
+	Convert(ctx context.Context, data []byte, opts *Options) (string, []*models.ImageItem, error)
+}
+
+// DocumentFormat is implemented by formats that can also produce a structured
+// document, which is required for JSON output. The built-in formats implement it.
+type DocumentFormat interface {
+	Format
+
+	// ConvertDocument converts document data to a structured document and
+	// returns the images referenced by its image blocks
+	ConvertDocument(ctx context.Context, data []byte, opts *Options) (*document.Document, []*models.ImageItem, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	// registry holds the globally registered formats in detection order
+	registry = []Format{pdfFormat{}, docxFormat{}, xlsxFormat{}}
+)
+
+// Register adds a format to the global registry, replacing any registered
+// format with the same name, and adds its extensions to DefaultExtensions.
+// Register is intended to be called from init functions.
+func Register(f Format) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	replaced := false
+	for i, existing := range registry {
+		if existing.Name() == f.Name() {
+			registry[i] = f
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		registry = append(registry, f)
+	}
+
+	for _, ext := range f.Extensions() {
+		ext = normalizeExtension(ext)
+		if !containsString(DefaultExtensions, ext) {
+			DefaultExtensions = append(DefaultExtensions, ext)
+		}
+	}
+}
+
+// Formats returns the globally registered formats
+func Formats() []Format {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Format(nil), registry...)
+}
+
+// formats returns the formats available to this converter, per-converter
+// formats first so they take precedence over the global registry
+func (c *Converter) formats() []Format {
+	return append(append([]Format(nil), c.options.Formats...), Formats()...)
+}
+
+// formatForExtension returns the format that handles ext, or nil
+func (c *Converter) formatForExtension(ext string) Format {
+	for _, f := range c.formats() {
+		for _, e := range f.Extensions() {
+			if normalizeExtension(e) == ext {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// formatByName returns the format with the given name or extension, or nil
+func (c *Converter) formatByName(name string) Format {
+	name = strings.ToLower(name)
+	for _, f := range c.formats() {
+		if f.Name() == name {
+			return f
+		}
+	}
+	return c.formatForExtension(normalizeExtension(name))
+}
+
+// handlesFormat reports whether any of the format's extensions is enabled
+func (c *Converter) handlesFormat(f Format) bool {
+	for _, ext := range f.Extensions() {
+		if c.hasExtension(normalizeExtension(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeExtension lowercases ext and ensures a leading dot
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// pdfFormat is the built-in PDF format backed by pdf2md
+type pdfFormat struct{}
+
+func (pdfFormat) Name() string         { return "pdf" }
+func (pdfFormat) Extensions() []string { return []string{".pdf"} }
+
+func (pdfFormat) Detect(r io.ReaderAt, size int64) bool {
+	return hasPDFHeader(readHead(r))
+}
+
+func (pdfFormat) Convert(ctx context.Context, data []byte, opts *Options) (string, []*models.ImageItem, error) {
+	markdown, images, err := newPDFConverter(ctx, opts).ConvertWithImagesContext(ctx, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Only write images when extraction is enabled
+	pdfOpts := pdf2md.DefaultOptions()
+	for _, opt := range pdfOptions(opts) {
+		opt(pdfOpts)
+	}
+	if !pdfOpts.ExtractImages {
+		images = nil
+	}
+	return markdown, images, nil
+}
+
+func (pdfFormat) ConvertDocument(ctx context.Context, data []byte, opts *Options) (*document.Document, []*models.ImageItem, error) {
+	return newPDFConverter(ctx, opts).ConvertDocumentContext(ctx, data)
+}
+
+// newPDFConverter returns a PDF converter with the pass-through options that
+// also records the page count and warnings in the conversion's stats
+func newPDFConverter(ctx context.Context, opts *Options) *pdf2md.Converter {
+	pdfOpts := pdf2md.DefaultOptions()
+	for _, opt := range pdfOptions(opts) {
+		opt(pdfOpts)
+	}
+	onParsed, onWarning := pdfOpts.OnPageParsed, pdfOpts.OnWarning
+
+	stats := statsFrom(ctx)
+	return pdf2md.New(append(pdfOptions(opts),
+		pdf2md.WithOnPageParsed(func(pageNum, totalPages int) {
+			stats.pages = totalPages
+			if onParsed != nil {
+				onParsed(pageNum, totalPages)
+			}
+		}),
+		pdf2md.WithOnWarning(func(w document.Warning) {
+			stats.warnings.Warn(w)
+			if onWarning != nil {
+				onWarning(w)
+			}
+		}),
+	)...)
+}
+
+// pdfOptions returns the PDF pass-through options, led by the shared limits
+func pdfOptions(opts *Options) []pdf2md.Option {
+	return append([]pdf2md.Option{pdf2md.WithLimits(opts.Limits)}, opts.PDFOptions...)
+}
+
+// docxFormat is the built-in DOCX format backed by docx2md
+type docxFormat struct{}
+
+func (docxFormat) Name() string         { return "docx" }
+func (docxFormat) Extensions() []string { return []string{".docx"} }
+
+func (docxFormat) Detect(r io.ReaderAt, size int64) bool {
+	return detectOOXML(r, size) == ".docx"
+}
+
+func (docxFormat) Convert(ctx context.Context, data []byte, opts *Options) (string, []*models.ImageItem, error) {
+	markdown, images, err := newDOCXConverter(ctx, opts).ConvertWithImagesContext(ctx, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Only write images when image preservation is enabled
+	docxOpts := docx2md.DefaultOptions()
+	for _, opt := range docxOptions(opts) {
+		opt(docxOpts)
+	}
+	if !docxOpts.PreserveImages {
+		images = nil
+	}
+	return markdown, images, nil
+}
+
+func (docxFormat) ConvertDocument(ctx context.Context, data []byte, opts *Options) (*document.Document, []*models.ImageItem, error) {
+	return newDOCXConverter(ctx, opts).ConvertDocumentContext(ctx, data)
+}
+
+// newDOCXConverter returns a DOCX converter with the pass-through options that
+// also records warnings in the conversion's stats
+func newDOCXConverter(ctx context.Context, opts *Options) *docx2md.Converter {
+	docxOpts := docx2md.DefaultOptions()
+	for _, opt := range docxOptions(opts) {
+		opt(docxOpts)
+	}
+	onWarning := docxOpts.OnWarning
+
+	stats := statsFrom(ctx)
+	return docx2md.New(append(docxOptions(opts),
+		docx2md.WithOnWarning(func(w document.Warning) {
+			stats.warnings.Warn(w)
+			if onWarning != nil {
+				onWarning(w)
+			}
+		}),
+	)...)
+}
+
+// docxOptions returns the DOCX pass-through options, led by the shared limits
+func docxOptions(opts *Options) []docx2md.Option {
+	return append([]docx2md.Option{docx2md.WithLimits(opts.Limits)}, opts.DOCXOptions...)
+}
+
+// xlsxFormat is the built-in XLSX format backed by xlsx2md
+type xlsxFormat struct{}
+
+func (xlsxFormat) Name() string         { return "xlsx" }
+func (xlsxFormat) Extensions() []string { return []string{".xlsx"} }
+
+func (xlsxFormat) Detect(r io.ReaderAt, size int64) bool {
+	return detectOOXML(r, size) == ".xlsx"
+}
+
+func (xlsxFormat) Convert(ctx context.Context, data []byte, opts *Options) (string, []*models.ImageItem, error) {
+	markdown, err := xlsx2md.New(xlsxOptions(opts)...).ConvertContext(ctx, data)
+	return markdown, nil, err
+}
+
+func (xlsxFormat) ConvertDocument(ctx context.Context, data []byte, opts *Options) (*document.Document, []*models.ImageItem, error) {
+	doc, err := xlsx2md.New(xlsxOptions(opts)...).ConvertDocumentContext(ctx, data)
+	return doc, nil, err
+}
+
+// xlsxOptions returns the XLSX pass-through options, led by the shared limits
+func xlsxOptions(opts *Options) []xlsx2md.Option {
+	return append([]xlsx2md.Option{xlsx2md.WithLimits(opts.Limits)}, opts.XLSXOptions...)
+}
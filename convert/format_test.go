@@ -0,0 +1,173 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+)
+
+// textFormat is a test format that converts plain text files
+type textFormat struct{}
+
+func (textFormat) Name() string         { return "text" }
+func (textFormat) Extensions() []string { return []string{".TXT"} }
+
+func (textFormat) Detect(r io.ReaderAt, size int64) bool {
+	head := make([]byte, 5)
+	n, _ := r.ReadAt(head, 0)
+	return bytes.HasPrefix(head[:n], []byte("TEXT:"))
+}
+
+func (textFormat) Convert(ctx context.Context, data []byte, opts *Options) (string, []*models.ImageItem, error) {
+	body := strings.TrimPrefix(string(data), "TEXT:")
+	return "# Text\n\n" + body + "\n", nil, nil
+}
+
+func TestBuiltinFormatsRegistered(t *testing.T) {
+	var names []string
+	for _, f := range Formats() {
+		names = append(names, f.Name())
+	}
+	if strings.Join(names, ",") != "pdf,docx,xlsx" {
+		t.Errorf("Expected built-in formats pdf,docx,xlsx, got %v", names)
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "notes.txt"), []byte("TEXT:hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Detected by content despite the missing extension
+	if err := os.WriteFile(filepath.Join(tmpDir, "notes"), []byte("TEXT:sniffed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(WithRecursion(true), WithFormat(textFormat{}))
+	if !c.hasExtension(".txt") {
+		t.Error("Expected WithFormat to enable the format's extensions")
+	}
+
+	result, err := c.Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 2 {
+		t.Errorf("Expected 2 converted, got %d (%v)", result.Converted, result.Errors)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "notes.md"))
+	if !strings.Contains(string(content), "sniffed") {
+		t.Errorf("Expected custom format output, got: %s", content)
+	}
+
+	// Other converters are unaffected by the per-converter format
+	if New().formatForExtension(".txt") != nil {
+		t.Error("Expected WithFormat to be local to its converter")
+	}
+}
+
+func TestConvertFileToFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	docxPath := filepath.Join(tmpDir, "input.docx")
+	if err := os.WriteFile(docxPath, createTestDocx(`<w:p><w:r><w:t>Explicit</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(tmpDir, "custom.md")
+
+	if err := New().ConvertFileToFile(docxPath, outPath); err != nil {
+		t.Fatalf("ConvertFileToFile failed: %v", err)
+	}
+	content, _ := os.ReadFile(outPath)
+	if !strings.Contains(string(content), "Explicit") {
+		t.Errorf("Expected converted content, got: %s", content)
+	}
+
+	txtPath := filepath.Join(tmpDir, "plain.txt")
+	if err := os.WriteFile(txtPath, []byte("plain"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().ConvertFileToFile(txtPath, outPath); err == nil {
+		t.Error("Expected error for unsupported file type")
+	}
+}
+
+func TestConverterConvertToWriter(t *testing.T) {
+	docx := createTestDocx(`<w:p><w:r><w:t>Streamed</w:t></w:r></w:p>`)
+
+	var out bytes.Buffer
+	err := New().ConvertToWriter(context.Background(), bytes.NewReader(docx), int64(len(docx)), &out, nil)
+	if err != nil {
+		t.Fatalf("ConvertToWriter failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Streamed") {
+		t.Errorf("Expected streamed output, got: %s", out.String())
+	}
+
+	unknown := []byte("not a document")
+	if err := New().ConvertToWriter(context.Background(), bytes.NewReader(unknown), int64(len(unknown)), &out, nil); err == nil {
+		t.Error("Expected error for unrecognized content")
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "convert_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	docxPath := filepath.Join(tmpDir, "input.docx")
+	if err := os.WriteFile(docxPath, createTestDocx(`<w:p><w:r><w:t>Structured</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := New(WithOutputFormat(OutputJSON))
+	result, err := converter.Convert(docxPath)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 1 {
+		t.Fatalf("Expected 1 converted, got %d (errors: %v)", result.Converted, result.Errors)
+	}
+
+	content, err := os.ReadFile(docxPath + ".json")
+	if err != nil {
+		t.Fatalf("Expected .json output: %v", err)
+	}
+	var doc document.Document
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, content)
+	}
+	if doc.Format != "docx" || len(doc.Sections) != 1 || doc.Sections[0].Blocks[0].Text != "Structured" {
+		t.Errorf("Unexpected document: %s", content)
+	}
+
+	// Formats without structured output cannot produce JSON
+	txtPath := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(txtPath, []byte("plain"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converter = New(WithFormat(textFormat{}), WithOutputFormat(OutputJSON))
+	if err := converter.ConvertFileToFile(txtPath, filepath.Join(tmpDir, "notes.json")); err == nil {
+		t.Error("Expected error for format without JSON support")
+	}
+}
@@ -0,0 +1,223 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/tenebris-tech/x2md/document"
+)
+
+// ErrCrashed reports a conversion whose worker process died without a result,
+// such as on a panic or when killed by the operating system
+var ErrCrashed = errors.New("worker process crashed")
+
+// Isolation configures process-isolated conversion. Each document is converted
+// by a new worker process, so a document that crashes or hangs its converter
+// fails alone instead of taking down the batch. FileTimeout is the wall-clock
+// limit: a worker still running when it expires is killed.
+type Isolation struct {
+	// Command is the worker command line: a program that calls ServeWorker
+	// on a converter with the same conversion options, such as "x2md worker"
+	Command []string
+
+	// Args are passed to each worker with its job on standard input rather
+	// than on its command line, where other users could see them in process
+	// listings. "x2md worker" takes the conversion flags this way, including
+	// passwords.
+	Args []string
+
+	// CPUTime limits the CPU time of each worker (0 = no limit)
+	CPUTime time.Duration
+
+	// Memory limits the data segment, and so the heap, of each worker in
+	// bytes (0 = no limit). A worker whose allocations go past it exits.
+	Memory int64
+}
+
+// workerJob is the request sent to a worker on its standard input
+type workerJob struct {
+	Args       []string // Isolation.Args
+	Format     string   // Format name
+	Data       []byte
+	Structured bool // Convert to a document instead of Markdown
+	CPUTime    time.Duration
+	Memory     int64
+}
+
+// workerResult is the reply of a worker on its standard output
+type workerResult struct {
+	Conversion conversion
+	Pages      int
+	Warnings   []document.Warning
+	Err        string
+	ErrClass   ErrorClass
+}
+
+// classErrors maps error classes to the errors that restore them in the parent
+var classErrors = map[ErrorClass]error{
+	ClassEncrypted:     ErrEncrypted,
+	ClassWrongPassword: document.MarkError(ErrEncrypted, ErrWrongPassword),
+	ClassNoTextContent: ErrNoTextContent,
+	ClassCorrupt:       ErrCorrupt,
+	ClassUnsupported:   ErrUnsupported,
+	ClassLimitExceeded: ErrLimitExceeded,
+	ClassTimeout:       context.DeadlineExceeded,
+	ClassCanceled:      context.Canceled,
+}
+
+// WorkerJob is a conversion job read by a worker process
+type WorkerJob struct {
+	// Args are the parent's Isolation.Args, such as conversion flags
+	Args []string
+
+	job workerJob
+}
+
+// ReadWorkerJob reads the job of a worker process from r. Workers that take
+// their configuration from Isolation.Args read the job first, configure a
+// converter from its Args and pass the job to ServeWorkerJob.
+func ReadWorkerJob(r io.Reader) (*WorkerJob, error) {
+	var job workerJob
+	if err := gob.NewDecoder(r).Decode(&job); err != nil {
+		return nil, fmt.Errorf("reading job: %w", err)
+	}
+	return &WorkerJob{Args: job.Args, job: job}, nil
+}
+
+// ServeWorker converts one document for an isolated parent converter: it reads
+// a job from r, applies the job's resource limits to the current process,
+// converts the document and writes the result to w. The converter must be
+// configured like the parent, including any formats added with WithFormat.
+// Images are returned to the parent, which stores them and writes the output.
+func (c *Converter) ServeWorker(ctx context.Context, r io.Reader, w io.Writer) error {
+	job, err := ReadWorkerJob(r)
+	if err != nil {
+		return err
+	}
+	return c.ServeWorkerJob(ctx, job, w)
+}
+
+// ServeWorkerJob is like ServeWorker for a job already read with ReadWorkerJob
+func (c *Converter) ServeWorkerJob(ctx context.Context, received *WorkerJob, w io.Writer) error {
+	job := received.job
+	if err := setWorkerLimits(job.CPUTime, job.Memory); err != nil {
+		return fmt.Errorf("setting limits: %w", err)
+	}
+
+	var result workerResult
+	stats := c.newStats("")
+	format := c.formatByName(job.Format)
+	var conv *conversion
+	var err error
+	if format == nil {
+		err = fmt.Errorf("%w: %s", ErrUnsupportedFormat, job.Format)
+	} else {
+		conv, err = c.convertData(withStats(ctx, stats), format, job.Data, job.Structured)
+	}
+	if err != nil {
+		result.Err, result.ErrClass = err.Error(), Classify(err)
+	} else {
+		result.Conversion = *conv
+	}
+	result.Pages = stats.pages
+	result.Warnings = stats.warnings.Warnings()
+	return gob.NewEncoder(w).Encode(&result)
+}
+
+// convertIsolated converts data with format in a worker process
+func (c *Converter) convertIsolated(ctx context.Context, format Format, data []byte, structured bool) (*conversion, error) {
+	iso := c.options.Isolation
+	if len(iso.Command) == 0 {
+		return nil, errors.New("isolation requires a worker command")
+	}
+
+	cmd := exec.CommandContext(ctx, iso.Command[0], iso.Command[1:]...)
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &limitedWriter{w: &stderr, n: 4096}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting worker: %w", err)
+	}
+
+	// Send the job while reading the reply, so a worker that fails early
+	// cannot block the write
+	job := workerJob{Args: iso.Args, Format: format.Name(), Data: data, Structured: structured, CPUTime: iso.CPUTime, Memory: iso.Memory}
+	go func() {
+		_ = gob.NewEncoder(stdin).Encode(&job)
+		_ = stdin.Close()
+	}()
+	var result workerResult
+	decodeErr := gob.NewDecoder(stdout).Decode(&result)
+	_, _ = io.Copy(io.Discard, stdout)
+	waitErr := cmd.Wait()
+
+	switch {
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("worker killed: %w", ctx.Err())
+	case decodeErr != nil:
+		return nil, workerFailure(cmd, waitErr, stderr.String(), iso)
+	}
+
+	stats := statsFrom(ctx)
+	if result.Pages > 0 {
+		stats.pages = result.Pages
+	}
+	for _, w := range result.Warnings {
+		stats.warnings.Warn(w)
+	}
+	if result.Err != "" {
+		err := errors.New(result.Err)
+		if kind, ok := classErrors[result.ErrClass]; ok {
+			err = document.MarkError(kind, err)
+		}
+		return nil, err
+	}
+	return &result.Conversion, nil
+}
+
+// workerFailure describes a worker that exited without a result, using the
+// first line it wrote to standard error, such as a panic message
+func workerFailure(cmd *exec.Cmd, waitErr error, stderr string, iso *Isolation) error {
+	if waitErr == nil {
+		waitErr = errors.New("no result")
+	}
+	msg := waitErr.Error()
+	line, _, _ := strings.Cut(strings.TrimSpace(stderr), "\n")
+	if line != "" {
+		msg += ": " + line
+	}
+	if exceededLimit(cmd.ProcessState, stderr, iso.CPUTime, iso.Memory) {
+		return document.MarkError(ErrLimitExceeded, fmt.Errorf("worker exceeded its resource limits: %s", msg))
+	}
+	return fmt.Errorf("%w: %s", ErrCrashed, msg)
+}
+
+// limitedWriter keeps the first n bytes written to it and discards the rest
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	keep := min(len(p), l.n)
+	if keep > 0 {
+		_, _ = l.w.Write(p[:keep])
+		l.n -= keep
+	}
+	return len(p), nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd
+
+package convert
+
+import (
+	"os"
+	"time"
+)
+
+// setWorkerLimits does nothing on platforms without resource limits: workers
+// are bounded by FileTimeout only
+func setWorkerLimits(cpuTime time.Duration, memory int64) error {
+	return nil
+}
+
+// exceededLimit reports whether a worker was killed for exceeding its limits
+func exceededLimit(state *os.ProcessState, stderr string, cpuTime time.Duration, memory int64) bool {
+	return false
+}
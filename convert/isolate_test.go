@@ -0,0 +1,199 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenebris-tech/x2md/docx2md"
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+)
+
+// faultFormat is a test format whose conversions crash, spin, hang, run out
+// of memory or are killed
+type faultFormat struct{}
+
+func (faultFormat) Name() string         { return "fault" }
+func (faultFormat) Extensions() []string { return []string{".fault"} }
+
+func (faultFormat) Detect(r io.ReaderAt, size int64) bool { return false }
+
+func (faultFormat) Convert(ctx context.Context, data []byte, opts *Options) (string, []*models.ImageItem, error) {
+	switch strings.TrimSpace(string(data)) {
+	case "panic":
+		panic("poisoned document")
+	case "spin":
+		for {
+		}
+	case "hang":
+		time.Sleep(time.Hour)
+	case "grow":
+		var kept [][]byte
+		for {
+			kept = append(kept, make([]byte, 1<<20))
+		}
+	case "kill":
+		// As another process killing the worker would
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			_ = p.Kill()
+		}
+		time.Sleep(time.Hour)
+	}
+	return "# Fine\n", nil, nil
+}
+
+// TestMain lets the test binary act as an isolation worker. The worker
+// drops formatting if its job's Args ask for it.
+func TestMain(m *testing.M) {
+	if os.Getenv("X2MD_TEST_WORKER") == "1" {
+		job, err := ReadWorkerJob(os.Stdin)
+		if err != nil {
+			os.Exit(1)
+		}
+		opts := []Option{WithFormat(faultFormat{})}
+		if strings.Join(job.Args, " ") == "-no-formatting" {
+			opts = append(opts, WithDOCXOptions(docx2md.WithPreserveFormatting(false)))
+		}
+		if err := New(opts...).ServeWorkerJob(context.Background(), job, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestIsolation(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"fine.fault":  "fine",
+		"panic.fault": "panic",
+		"hang.fault":  "hang",
+		"kill.fault":  "kill",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "good.docx"), createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("X2MD_TEST_WORKER", "1")
+	c := New(
+		WithRecursion(true),
+		WithFormat(faultFormat{}),
+		WithConcurrency(5),
+		WithFileTimeout(3*time.Second),
+		WithIsolation(Isolation{Command: []string{os.Args[0]}, CPUTime: time.Second, Memory: 1 << 30}),
+	)
+	result, err := c.Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	byName := make(map[string]FileResult)
+	for _, f := range result.Files {
+		byName[filepath.Base(f.Input)] = f
+	}
+	for _, name := range []string{"fine.fault", "good.docx"} {
+		if f := byName[name]; f.Status != StatusConverted {
+			t.Errorf("Expected %s to convert, got %+v", name, f)
+		}
+	}
+	if output, err := os.ReadFile(filepath.Join(tmpDir, "good.docx.md")); err != nil || !strings.Contains(string(output), "Hello") {
+		t.Errorf("Expected the worker's Markdown in the output, got %q (%v)", output, err)
+	}
+
+	f := byName["panic.fault"]
+	if f.Status != StatusFailed || !errors.Is(f.Err, ErrCrashed) || !strings.Contains(f.Err.Error(), "poisoned document") {
+		t.Errorf("Expected panic.fault to fail as crashed, got %+v", f)
+	}
+	if f := byName["hang.fault"]; f.Status != StatusFailed || Classify(f.Err) != ClassTimeout {
+		t.Errorf("Expected hang.fault to time out, got %+v", f)
+	}
+	if f := byName["kill.fault"]; f.Status != StatusFailed || !errors.Is(f.Err, ErrCrashed) {
+		t.Errorf("Expected kill.fault to fail as crashed, not over its limits, got %+v", f)
+	}
+}
+
+func TestIsolationCPULimit(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("CPU limits are tested on Linux")
+	}
+	input := filepath.Join(t.TempDir(), "spin.fault")
+	if err := os.WriteFile(input, []byte("spin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The wall-clock limit is far beyond the CPU limit, so that the CPU
+	// limit fires first even on a slow or loaded machine
+	t.Setenv("X2MD_TEST_WORKER", "1")
+	c := New(
+		WithFormat(faultFormat{}),
+		WithFileTimeout(time.Minute),
+		WithIsolation(Isolation{Command: []string{os.Args[0]}, CPUTime: time.Second}),
+	)
+	result, err := c.Convert(input)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(result.Files) != 1 {
+		t.Fatalf("Expected one file, got %+v", result.Files)
+	}
+	if f := result.Files[0]; f.Status != StatusFailed || Classify(f.Err) != ClassLimitExceeded {
+		t.Errorf("Expected spin.fault to exceed its CPU time, got %+v", f)
+	}
+}
+
+func TestIsolationMemoryLimit(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory limits are tested on Linux")
+	}
+	input := filepath.Join(t.TempDir(), "grow.fault")
+	if err := os.WriteFile(input, []byte("grow"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("X2MD_TEST_WORKER", "1")
+	c := New(
+		WithFormat(faultFormat{}),
+		WithFileTimeout(time.Minute),
+		WithIsolation(Isolation{Command: []string{os.Args[0]}, Memory: 256 << 20}),
+	)
+	result, err := c.Convert(input)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(result.Files) != 1 {
+		t.Fatalf("Expected one file, got %+v", result.Files)
+	}
+	if f := result.Files[0]; f.Status != StatusFailed || Classify(f.Err) != ClassLimitExceeded {
+		t.Errorf("Expected grow.fault to exceed its memory limit, got %+v", f)
+	}
+}
+
+func TestIsolationArgs(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "bold.docx")
+	if err := os.WriteFile(input, createTestDocx(`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Bold</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker is configured from the Args sent with its job
+	t.Setenv("X2MD_TEST_WORKER", "1")
+	c := New(WithIsolation(Isolation{Command: []string{os.Args[0]}, Args: []string{"-no-formatting"}}))
+	output := input + ".md"
+	if err := c.ConvertFileToFile(input, output); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	markdown, _ := os.ReadFile(output)
+	if strings.Contains(string(markdown), "**") || !strings.Contains(string(markdown), "Bold") {
+		t.Errorf("Expected the worker to drop formatting, got %q", markdown)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build linux || darwin || freebsd
+
+package convert
+
+import (
+	"os"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// setWorkerLimits limits the CPU time and data segment of the current process.
+// The data limit covers the heap the Go runtime maps, but not the address
+// space it only reserves, which is why the address space is not limited.
+func setWorkerLimits(cpuTime time.Duration, memory int64) error {
+	if cpuTime > 0 {
+		// SIGXCPU at the soft limit, SIGKILL a second later if it is caught
+		seconds := uint64((cpuTime + time.Second - 1) / time.Second)
+		if err := syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: seconds, Max: seconds + 1}); err != nil {
+			return err
+		}
+	}
+	if memory > 0 {
+		// Allocations past the limit fail and the runtime exits; collect
+		// garbage harder as it nears, so that live data gets the most of it
+		if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: uint64(memory), Max: uint64(memory)}); err != nil {
+			return err
+		}
+		debug.SetMemoryLimit(memory)
+	}
+	return nil
+}
+
+// exceededLimit reports whether a worker ended by exceeding its limits: SIGXCPU
+// or SIGKILL after using up cpuTime (Go ignores SIGXCPU, so such workers are
+// killed at the hard limit), or, with a memory limit, the runtime's out of
+// memory failure on stderr. Other signals, such as a SIGKILL from another
+// process, are crashes. Workers killed by the parent are reported before this
+// is consulted.
+func exceededLimit(state *os.ProcessState, stderr string, cpuTime time.Duration, memory int64) bool {
+	if state == nil {
+		return false
+	}
+	if memory > 0 && outOfMemory(stderr) {
+		return true
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return false
+	}
+	cpuUsed := cpuTime > 0 && state.UserTime()+state.SystemTime() >= cpuTime
+	switch status.Signal() {
+	case syscall.SIGXCPU, syscall.SIGKILL:
+		return cpuUsed
+	}
+	return false
+}
+
+// outOfMemory reports whether a Go program's stderr shows that it failed to
+// allocate memory, such as "fatal error: runtime: out of memory"
+func outOfMemory(stderr string) bool {
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(line, "fatal error: ") && strings.Contains(line, "out of memory") {
+			return true
+		}
+	}
+	return false
+}
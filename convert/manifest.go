@@ -0,0 +1,404 @@
+package convert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tenebris-tech/x2md/docx2md"
+	"github.com/tenebris-tech/x2md/imageutil"
+	"github.com/tenebris-tech/x2md/pdf2md"
+	"github.com/tenebris-tech/x2md/xlsx2md"
+)
+
+// modulePath is the import path of the x2md module
+const modulePath = "github.com/tenebris-tech/x2md"
+
+// Version identifies the x2md release. It is recorded in incremental
+// manifests so that upgrading x2md reconverts every file. It is read from
+// the build information of the binary: the module version x2md was required
+// at, or the VCS revision of a build from a checkout. Release builds may set
+// it with -ldflags "-X github.com/tenebris-tech/x2md/convert.Version=v1.2.3".
+var Version string
+
+func init() {
+	if Version == "" {
+		Version = buildVersion()
+	}
+}
+
+// buildVersion returns the version of x2md recorded in the build information
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	module := &info.Main
+	if module.Path != modulePath {
+		module = nil
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				module = dep
+				if dep.Replace != nil {
+					module = dep.Replace
+				}
+				break
+			}
+		}
+	}
+	if module != nil && module.Version != "" && module.Version != "(devel)" {
+		return module.Version
+	}
+	if module != &info.Main {
+		return "(devel)"
+	}
+
+	// A build from a checkout of x2md: identify it by its revision
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				modified = "-dirty"
+			}
+		}
+	}
+	if revision != "" {
+		return "(devel)-" + revision + modified
+	}
+	return "(devel)"
+}
+
+// ManifestName is the file name of the incremental conversion manifest,
+// written to the output directory (or the converted directory if there is none)
+const ManifestName = ".x2md-manifest.json"
+
+// manifestSchema is the version of the manifest file layout
+const manifestSchema = 1
+
+// Manifest records the source files converted in incremental mode
+type Manifest struct {
+	Schema int `json:"schema"`
+
+	// Files maps the absolute real path of each converted source to its entry
+	Files map[string]*ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes a source file as it was when last converted
+type ManifestEntry struct {
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mtime"`
+	Version   string    `json:"x2md_version"`
+	Options   string    `json:"options"` // Fingerprint of the options that affect output
+	Output    string    `json:"output"`  // Absolute path of the converted file
+	Converted time.Time `json:"converted"`
+}
+
+// LoadManifest reads a manifest file. A missing file yields an empty manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	m := &Manifest{Schema: manifestSchema, Files: make(map[string]*ManifestEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]*ManifestEntry)
+	}
+	return m, nil
+}
+
+// save writes the manifest atomically, so an interrupted batch never leaves a
+// truncated file behind
+func (m *Manifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// owner returns the source recorded as producing output, if any
+func (m *Manifest) owner(output string) string {
+	output = absPath(output)
+	for source, entry := range m.Files {
+		if entry.Output == output {
+			return source
+		}
+	}
+	return ""
+}
+
+// sourceState is the state of a source file compared with its manifest entry
+type sourceState struct {
+	size        int64
+	modTime     time.Time
+	sha256      string
+	fingerprint string
+	entry       *ManifestEntry // Previous entry, or nil for a new file
+	unchanged   bool           // Content, version and options match the entry
+}
+
+// inspectSource compares a source file with its manifest entry. The file is
+// only hashed if its size or modification time differ from the entry.
+func (c *Converter) inspectSource(path string, format Format) (*sourceState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+	var entry *ManifestEntry
+	if e := c.manifest.Files[path]; e != nil {
+		copied := *e
+		entry = &copied
+	}
+	c.mu.Unlock()
+
+	src := &sourceState{
+		size:        info.Size(),
+		modTime:     info.ModTime(),
+		fingerprint: c.optionsFingerprint(format),
+		entry:       entry,
+	}
+
+	current := entry != nil && entry.Version == Version && entry.Options == src.fingerprint
+	if current {
+		if _, err := os.Stat(entry.Output); err != nil {
+			current = false
+		}
+	}
+	if current && entry.Size == src.size && entry.ModTime.Equal(src.modTime) {
+		src.sha256 = entry.SHA256
+		src.unchanged = true
+		return src, nil
+	}
+
+	src.sha256, err = hashFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	src.unchanged = current && entry.SHA256 == src.sha256
+	return src, nil
+}
+
+// incrementalOutputPath determines the output path for a source in incremental
+// mode. Known sources keep their recorded output while their options are
+// unchanged; it is overwritten when the source changes. Other sources get the
+// default output path, or a unique one if a file the manifest does not record
+// for them is already there, so that files it did not create are never
+// overwritten. Callers must hold c.mu.
+func (c *Converter) incrementalOutputPath(path, inputPath string, src *sourceState) (string, bool, string) {
+	if src.unchanged {
+		return src.entry.Output, true, "unchanged since last conversion"
+	}
+	if src.entry != nil && src.entry.Options == src.fingerprint && !c.reservedOutputs[src.entry.Output] {
+		return src.entry.Output, false, ""
+	}
+
+	outputPath := c.defaultOutputPath(path, inputPath)
+	if owner := c.manifest.owner(outputPath); c.reservedOutputs[outputPath] || (owner != inputPath && (owner != "" || c.outputExists(outputPath))) {
+		outputPath = c.findUniquePath(outputPath)
+	}
+	return outputPath, false, ""
+}
+
+// absPath returns the absolute form of path, or path if it cannot be made absolute
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// recordSource updates the manifest entry of a source and saves the manifest
+func (c *Converter) recordSource(path, outputPath string, src *sourceState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.manifest.Files[path] = &ManifestEntry{
+		SHA256:    src.sha256,
+		Size:      src.size,
+		ModTime:   src.modTime,
+		Version:   Version,
+		Options:   src.fingerprint,
+		Output:    absPath(outputPath),
+		Converted: time.Now().UTC(),
+	}
+	return c.manifest.save(c.manifestPath)
+}
+
+// pruneDeleted removes the outputs of manifest entries under root whose source
+// no longer exists, including their image directories
+func (c *Converter) pruneDeleted(root string, result *Result) {
+	realRoot, err := resolvePath(root)
+	if err != nil {
+		c.addFailure(result, root, fmt.Errorf("cannot resolve %s: %w", root, err))
+		return
+	}
+	prefix := realRoot + string(filepath.Separator)
+
+	c.mu.Lock()
+	var sources []string
+	for source := range c.manifest.Files {
+		if strings.HasPrefix(source, prefix) {
+			sources = append(sources, source)
+		}
+	}
+	c.mu.Unlock()
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		if _, err := os.Lstat(source); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		c.mu.Lock()
+		output := c.manifest.Files[source].Output
+		c.mu.Unlock()
+
+		if err := os.Remove(output); err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.addFailure(result, source, fmt.Errorf("removing %s: %w", output, err))
+			continue
+		}
+		if err := os.RemoveAll(imageutil.ImageDir(output)); err != nil {
+			c.addFailure(result, source, fmt.Errorf("removing images of %s: %w", output, err))
+			continue
+		}
+
+		c.mu.Lock()
+		delete(c.manifest.Files, source)
+		c.mu.Unlock()
+		c.addFile(result, FileResult{Input: source, Output: output, Status: StatusRemoved})
+
+		if c.options.OnFileRemoved != nil {
+			c.callbackMu.Lock()
+			c.options.OnFileRemoved(source, output)
+			c.callbackMu.Unlock()
+		}
+	}
+
+	c.mu.Lock()
+	err = c.manifest.save(c.manifestPath)
+	c.mu.Unlock()
+	if err != nil {
+		c.addFailure(result, c.manifestPath, fmt.Errorf("saving manifest: %w", err))
+	}
+}
+
+// optionsFingerprint hashes the options that affect the output of format, so
+// that changing them reconverts the files it handles. Callbacks are ignored;
+// image sinks contribute only their type. Formats other than the built-in ones
+// are identified by name only.
+func (c *Converter) optionsFingerprint(format Format) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "format=%s;output=%s;name=%s;mirror=%t;",
+		format.Name(), c.options.OutputFormat, c.options.OutputName, c.options.PreserveStructure)
+	if c.options.ImageSink != nil {
+		_, _ = fmt.Fprintf(h, "sink=%T;", c.options.ImageSink)
+	}
+
+	switch format.(type) {
+	case pdfFormat:
+		opts := pdf2md.DefaultOptions()
+		for _, opt := range c.options.PDFOptions {
+			opt(opts)
+		}
+		writeFingerprint(h, reflect.ValueOf(opts))
+	case docxFormat:
+		opts := docx2md.DefaultOptions()
+		for _, opt := range c.options.DOCXOptions {
+			opt(opts)
+		}
+		writeFingerprint(h, reflect.ValueOf(opts))
+	case xlsxFormat:
+		opts := xlsx2md.DefaultOptions()
+		for _, opt := range c.options.XLSXOptions {
+			opt(opts)
+		}
+		writeFingerprint(h, reflect.ValueOf(opts))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeFingerprint writes a canonical form of v to h, skipping functions
+func writeFingerprint(h hash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			_, _ = io.WriteString(h, "nil;")
+			return
+		}
+		writeFingerprint(h, v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Kind() == reflect.Func {
+				continue
+			}
+			_, _ = io.WriteString(h, t.Field(i).Name+"=")
+			writeFingerprint(h, v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		_, _ = fmt.Fprintf(h, "[%d]", v.Len())
+		for i := 0; i < v.Len(); i++ {
+			writeFingerprint(h, v.Index(i))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			_, _ = io.WriteString(h, "nil;")
+			return
+		}
+		_, _ = fmt.Fprintf(h, "%s;", v.Elem().Type())
+	case reflect.Bool:
+		_, _ = fmt.Fprintf(h, "%t;", v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, _ = fmt.Fprintf(h, "%d;", v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, _ = fmt.Fprintf(h, "%d;", v.Uint())
+	case reflect.Float32, reflect.Float64:
+		_, _ = fmt.Fprintf(h, "%g;", v.Float())
+	case reflect.String:
+		_, _ = fmt.Fprintf(h, "%q;", v.String())
+	}
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,240 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenebris-tech/x2md/docx2md"
+)
+
+func TestIncrementalConversion(t *testing.T) {
+	srcDir := t.TempDir()
+	outDir := t.TempDir()
+
+	docxPath := filepath.Join(srcDir, "test.docx")
+	if err := os.WriteFile(docxPath, createTestDocx(`<w:p><w:r><w:t>First</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []Option{WithRecursion(true), WithOutputDirectory(outDir), WithIncremental(true)}
+
+	// First run converts and records the file
+	result, err := New(opts...).Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 1 {
+		t.Fatalf("Expected 1 converted, got %d", result.Converted)
+	}
+	manifest, err := LoadManifest(filepath.Join(outDir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 1 {
+		t.Fatalf("Expected 1 manifest entry, got %d", len(manifest.Files))
+	}
+
+	// Second run skips the unchanged file
+	result, err = New(opts...).Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 0 || result.Skipped != 1 {
+		t.Errorf("Expected 0 converted and 1 skipped, got %d and %d", result.Converted, result.Skipped)
+	}
+
+	// Touching the file without changing its content still skips it
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(docxPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	result, err = New(opts...).Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Skipped != 1 {
+		t.Errorf("Expected touched file to be skipped, got %d skipped", result.Skipped)
+	}
+
+	// Changing the content reconverts it to the same output
+	if err := os.WriteFile(docxPath, createTestDocx(`<w:p><w:r><w:t>Second version</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = New(opts...).Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 1 {
+		t.Errorf("Expected 1 converted after change, got %d", result.Converted)
+	}
+	content, err := os.ReadFile(filepath.Join(outDir, "test.docx.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "Second version") {
+		t.Errorf("Expected updated output, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "test_1.docx.md")); err == nil {
+		t.Error("Expected output to be overwritten, not renamed")
+	}
+
+	// Changing an option that affects output reconverts it
+	optsChanged := append(opts, WithDOCXOptions(docx2md.WithPreserveFormatting(false)))
+	result, err = New(optsChanged...).Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 1 {
+		t.Errorf("Expected 1 converted after option change, got %d", result.Converted)
+	}
+}
+
+func TestIncrementalPruneDeleted(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	docxData := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+	for _, name := range []string{"keep.docx", "remove.docx"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), docxData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := []Option{WithRecursion(true), WithIncremental(true)}
+	if _, err := New(opts...).Convert(tmpDir); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(tmpDir, "remove.docx")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without pruning the stale output is left alone
+	result, err := New(opts...).Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Removed != 0 {
+		t.Errorf("Expected 0 removed without pruning, got %d", result.Removed)
+	}
+
+	var removed []string
+	opts = append(opts, WithPruneDeleted(true), WithOnFileRemoved(func(path, outputPath string) {
+		removed = append(removed, filepath.Base(outputPath))
+	}))
+	result, err = New(opts...).Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Removed != 1 || len(removed) != 1 || removed[0] != "remove.docx.md" {
+		t.Errorf("Expected remove.docx.md to be removed, got %d (%v)", result.Removed, removed)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "remove.docx.md")); err == nil {
+		t.Error("Expected output of deleted source to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "keep.docx.md")); err != nil {
+		t.Error("Expected output of remaining source to be kept")
+	}
+
+	manifest, err := LoadManifest(filepath.Join(tmpDir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 1 {
+		t.Errorf("Expected 1 manifest entry after pruning, got %d", len(manifest.Files))
+	}
+}
+
+func TestIncrementalRelativePaths(t *testing.T) {
+	base := t.TempDir()
+	srcDir := filepath.Join(base, "src")
+	if err := os.Mkdir(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "test.docx"), createTestDocx(`<w:p><w:r><w:t>Text</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Relative paths are recorded as absolute ones
+	t.Chdir(base)
+	if _, err := New(WithRecursion(true), WithIncremental(true)).Convert("src"); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	manifest, err := LoadManifest(filepath.Join(srcDir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for source, entry := range manifest.Files {
+		if !filepath.IsAbs(source) || !filepath.IsAbs(entry.Output) {
+			t.Errorf("Expected absolute manifest paths, got %s -> %s", source, entry.Output)
+		}
+	}
+
+	// The same manifest applies from another working directory
+	t.Chdir(srcDir)
+	result, err := New(WithRecursion(true), WithIncremental(true)).Convert(".")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 0 || result.Skipped != 1 {
+		t.Errorf("Expected 0 converted and 1 skipped, got %d and %d", result.Converted, result.Skipped)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	if Version == "" {
+		t.Error("Expected a version from the build information")
+	}
+}
+
+func TestIncrementalManifestErrorReported(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "test.docx"), createTestDocx(`<w:p><w:r><w:t>Text</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A directory in the way of the temporary file makes saving fail
+	if err := os.Mkdir(filepath.Join(srcDir, ManifestName+".tmp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var completeErr error
+	result, err := New(WithRecursion(true), WithIncremental(true),
+		WithOnFileComplete(func(path, outputPath string, err error) { completeErr = err }),
+	).Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Failed != 1 {
+		t.Errorf("Expected the file to fail, got %d failed", result.Failed)
+	}
+	if completeErr == nil || !strings.Contains(completeErr.Error(), "saving manifest") {
+		t.Errorf("Expected OnFileComplete to report the manifest error, got %v", completeErr)
+	}
+}
+
+func TestIncrementalKeepsUnrecordedFiles(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "a.docx"), createTestDocx(`<w:p><w:r><w:t>Converted</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A hand-written file where the output would go
+	existing := filepath.Join(srcDir, "a.md")
+	if err := os.WriteFile(existing, []byte("Hand-written"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New(WithRecursion(true), WithIncremental(true), WithOutputName("{name}.md")).Convert(srcDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Converted != 1 {
+		t.Errorf("Expected 1 converted, got %d", result.Converted)
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "Hand-written" {
+		t.Errorf("Expected a.md to be kept, got %q", content)
+	}
+	if content, err := os.ReadFile(filepath.Join(srcDir, "a-1.md")); err != nil || !strings.Contains(string(content), "Converted") {
+		t.Errorf("Expected the output in a-1.md, got %q, %v", content, err)
+	}
+}
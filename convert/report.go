@@ -0,0 +1,222 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/tenebris-tech/x2md/document"
+)
+
+// Errors reported by conversions, for use with errors.Is. They are the
+// document package's errors, re-exported for convenience.
+var (
+	ErrEncrypted     = document.ErrEncrypted
+	ErrWrongPassword = document.ErrWrongPassword
+	ErrNoTextContent = document.ErrNoTextContent
+	ErrCorrupt       = document.ErrCorrupt
+	ErrUnsupported   = document.ErrUnsupported
+	ErrLimitExceeded = document.ErrLimitExceeded
+)
+
+// ErrorClass is a short, stable name for the kind of a conversion error
+type ErrorClass string
+
+// Error classes
+const (
+	ClassEncrypted     ErrorClass = "encrypted"
+	ClassWrongPassword ErrorClass = "wrong_password"
+	ClassNoTextContent ErrorClass = "no_text_content"
+	ClassCorrupt       ErrorClass = "corrupt"
+	ClassUnsupported   ErrorClass = "unsupported"
+	ClassLimitExceeded ErrorClass = "limit_exceeded"
+	ClassCrashed       ErrorClass = "crashed"
+	ClassTimeout       ErrorClass = "timeout"
+	ClassCanceled      ErrorClass = "canceled"
+	ClassIO            ErrorClass = "io"
+	ClassOther         ErrorClass = "other"
+)
+
+// Classify returns the class of err, or "" if err is nil
+func Classify(err error) ErrorClass {
+	var pathErr *fs.PathError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrLimitExceeded):
+		return ClassLimitExceeded
+	case errors.Is(err, ErrCrashed):
+		return ClassCrashed
+	case errors.Is(err, ErrWrongPassword):
+		return ClassWrongPassword
+	case errors.Is(err, ErrEncrypted):
+		return ClassEncrypted
+	case errors.Is(err, ErrNoTextContent):
+		return ClassNoTextContent
+	case errors.Is(err, ErrCorrupt):
+		return ClassCorrupt
+	case errors.Is(err, ErrUnsupported):
+		return ClassUnsupported
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.As(err, &pathErr):
+		return ClassIO
+	default:
+		return ClassOther
+	}
+}
+
+// FileStatus is the outcome of a file in a batch
+type FileStatus string
+
+// File outcomes
+const (
+	StatusConverted FileStatus = "converted"
+	StatusSkipped   FileStatus = "skipped"
+	StatusFailed    FileStatus = "failed"
+	StatusRemoved   FileStatus = "removed" // Output of a deleted source removed by PruneDeleted
+)
+
+// FileResult records what happened to one file of a batch
+type FileResult struct {
+	Input    string             // Source path
+	Output   string             // Output path, if one was chosen
+	Format   string             // Format name, e.g. "pdf"
+	Status   FileStatus         // Converted, skipped, failed or removed
+	Reason   string             // Why the file was skipped
+	Pages    int                // Page count, for formats that have pages
+	Images   int                // Number of images extracted
+	Duration time.Duration      // Time spent converting
+	Warnings []document.Warning // Problems that did not stop the conversion
+	Err      error              // Why the file failed
+}
+
+// MarshalJSON encodes the result with the duration in milliseconds and the
+// error as its message and class
+func (f FileResult) MarshalJSON() ([]byte, error) {
+	type fileResultJSON struct {
+		Input      string             `json:"input"`
+		Output     string             `json:"output,omitempty"`
+		Format     string             `json:"format,omitempty"`
+		Status     FileStatus         `json:"status"`
+		Reason     string             `json:"reason,omitempty"`
+		Pages      int                `json:"pages,omitempty"`
+		Images     int                `json:"images,omitempty"`
+		DurationMS int64              `json:"duration_ms"`
+		Warnings   []document.Warning `json:"warnings,omitempty"`
+		Error      string             `json:"error,omitempty"`
+		ErrorClass ErrorClass         `json:"error_class,omitempty"`
+	}
+	v := fileResultJSON{
+		Input:      f.Input,
+		Output:     f.Output,
+		Format:     f.Format,
+		Status:     f.Status,
+		Reason:     f.Reason,
+		Pages:      f.Pages,
+		Images:     f.Images,
+		DurationMS: f.Duration.Milliseconds(),
+		Warnings:   f.Warnings,
+		ErrorClass: Classify(f.Err),
+	}
+	if f.Err != nil {
+		v.Error = f.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// WriteJSON writes the result as a JSON report: the totals, the time taken and
+// one record per file
+func (r *Result) WriteJSON(w io.Writer) error {
+	files := r.Files
+	if files == nil {
+		files = []FileResult{}
+	}
+	report := struct {
+		Version    string       `json:"x2md_version"`
+		Started    time.Time    `json:"started"`
+		DurationMS int64        `json:"duration_ms"`
+		Converted  int          `json:"converted"`
+		Skipped    int          `json:"skipped"`
+		Failed     int          `json:"failed"`
+		Removed    int          `json:"removed"`
+		Files      []FileResult `json:"files"`
+	}{
+		Version:    Version,
+		Started:    r.Started,
+		DurationMS: r.Duration.Milliseconds(),
+		Converted:  r.Converted,
+		Skipped:    r.Skipped,
+		Failed:     r.Failed,
+		Removed:    r.Removed,
+		Files:      files,
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// addFile records the outcome of a file in the result and updates its counters
+func (c *Converter) addFile(result *Result, file FileResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch file.Status {
+	case StatusConverted:
+		result.Converted++
+	case StatusSkipped:
+		result.Skipped++
+	case StatusFailed:
+		result.Failed++
+		result.Errors = append(result.Errors, file.Err)
+	case StatusRemoved:
+		result.Removed++
+	}
+	result.Files = append(result.Files, file)
+}
+
+// fileStats collects details of a single conversion for its FileResult.
+// Formats find it in the conversion context.
+type fileStats struct {
+	pages    int
+	images   int
+	warnings *document.Diagnostics
+}
+
+// statsKey is the context key of the fileStats of a conversion
+type statsKey struct{}
+
+// withStats returns ctx carrying stats
+func withStats(ctx context.Context, stats *fileStats) context.Context {
+	return context.WithValue(ctx, statsKey{}, stats)
+}
+
+// statsFrom returns the fileStats carried by ctx, or a throwaway one if there is none
+func statsFrom(ctx context.Context) *fileStats {
+	if stats, ok := ctx.Value(statsKey{}).(*fileStats); ok {
+		return stats
+	}
+	return &fileStats{}
+}
+
+// newStats returns the fileStats for a conversion of path. Its warnings are
+// also passed to the OnFileWarning callback and the logger.
+func (c *Converter) newStats(path string) *fileStats {
+	var onWarning func(document.Warning)
+	if c.options.OnFileWarning != nil {
+		onWarning = func(w document.Warning) {
+			c.callbackMu.Lock()
+			defer c.callbackMu.Unlock()
+			c.options.OnFileWarning(path, w)
+		}
+	}
+	logger := c.options.Logger
+	if logger != nil && path != "" {
+		logger = logger.With("file", path)
+	}
+	return &fileStats{warnings: document.NewDiagnostics(onWarning, logger)}
+}
@@ -0,0 +1,184 @@
+package convert
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/internal/testutil"
+	"github.com/tenebris-tech/x2md/pdf2md"
+)
+
+func TestClassify(t *testing.T) {
+	_, statErr := os.Stat(filepath.Join(t.TempDir(), "missing"))
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{nil, ""},
+		{fmt.Errorf("a.pdf: %w", ErrEncrypted), ClassEncrypted},
+		{document.MarkError(ErrEncrypted, ErrWrongPassword), ClassWrongPassword},
+		{fmt.Errorf("a.pdf: %w", ErrNoTextContent), ClassNoTextContent},
+		{fmt.Errorf("a.pdf: %w", ErrCorrupt), ClassCorrupt},
+		{ErrUnsupportedFormat, ClassUnsupported},
+		{fmt.Errorf("%w: exit status 2", ErrCrashed), ClassCrashed},
+		{fmt.Errorf("timed out: %w", context.DeadlineExceeded), ClassTimeout},
+		{context.Canceled, ClassCanceled},
+		{statErr, ClassIO},
+		{errors.New("boom"), ClassOther},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestNoTextContentPDF(t *testing.T) {
+	data := testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>",
+		"<< /Length 0 >>\nstream\n\nendstream",
+	}, "")
+
+	var buf bytes.Buffer
+	err := New().ConvertToWriter(context.Background(), bytes.NewReader(data), int64(len(data)), &buf, nil)
+	if !errors.Is(err, ErrNoTextContent) {
+		t.Fatalf("Expected ErrNoTextContent, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output, got %q", buf.String())
+	}
+}
+
+func TestBatchReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string][]byte{
+		"good.docx":    createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`),
+		"broken.pdf":   []byte("%PDF-1.4\nnot really a PDF"),
+		"done.docx":    createTestDocx(`<w:p><w:r><w:t>Done</w:t></w:r></w:p>`),
+		"done.docx.md": []byte("existing"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := New(WithRecursion(true)).Convert(tmpDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(result.Files) != result.Converted+result.Skipped+result.Failed {
+		t.Fatalf("Expected one record per counted file, got %d for %+v", len(result.Files), result)
+	}
+
+	byName := make(map[string]FileResult)
+	for _, f := range result.Files {
+		byName[filepath.Base(f.Input)] = f
+	}
+	if f := byName["good.docx"]; f.Status != StatusConverted || f.Format != "docx" || f.Output == "" {
+		t.Errorf("Unexpected record for good.docx: %+v", f)
+	}
+	if f := byName["done.docx"]; f.Status != StatusSkipped || f.Reason == "" {
+		t.Errorf("Unexpected record for done.docx: %+v", f)
+	}
+	f := byName["broken.pdf"]
+	if f.Status != StatusFailed || !errors.Is(f.Err, ErrCorrupt) {
+		t.Errorf("Unexpected record for broken.pdf: %+v", f)
+	}
+
+	var buf bytes.Buffer
+	if err := result.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var report struct {
+		Converted int `json:"converted"`
+		Failed    int `json:"failed"`
+		Files     []struct {
+			Input      string `json:"input"`
+			Status     string `json:"status"`
+			ErrorClass string `json:"error_class"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid report JSON: %v", err)
+	}
+	if report.Converted != 1 || report.Failed != 1 || len(report.Files) != 3 {
+		t.Errorf("Unexpected report totals: %s", buf.String())
+	}
+	for _, rf := range report.Files {
+		if filepath.Base(rf.Input) == "broken.pdf" && rf.ErrorClass != string(ClassCorrupt) {
+			t.Errorf("Expected error class %q for broken.pdf, got %q", ClassCorrupt, rf.ErrorClass)
+		}
+	}
+}
+
+func TestFileWarnings(t *testing.T) {
+	// A page with text and an image in a filter the parser cannot decode
+	data := testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R >> >> >>",
+		testutil.Stream("", "BT /F1 12 Tf 72 720 Td (Hello) Tj ET\n/Im1 Do"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		testutil.Stream("/Type /XObject /Subtype /Image /Width 8 /Height 8 /BitsPerComponent 1 "+
+			"/ColorSpace /DeviceGray /Filter /CCITTFaxDecode", "abcd"),
+	}, "")
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "scan.pdf")
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the lone line of text, which would otherwise be taken for a header
+	var reported []string
+	c := New(WithPDFOptions(pdf2md.WithStrip()), WithOnFileWarning(func(path string, w document.Warning) {
+		reported = append(reported, path+": "+w.Code)
+	}))
+	result, err := c.Convert(input)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Status != StatusConverted {
+		t.Fatalf("Expected one converted file, got %+v", result.Files)
+	}
+
+	warnings := result.Files[0].Warnings
+	if len(warnings) != 1 || warnings[0].Code != document.WarnImageSkipped || warnings[0].Page != 1 {
+		t.Fatalf("Expected an image_skipped warning on page 1, got %v", warnings)
+	}
+	if len(reported) != 1 || reported[0] != input+": "+document.WarnImageSkipped {
+		t.Errorf("Expected the warning to reach OnFileWarning, got %v", reported)
+	}
+}
+
+func TestDecompressionLimit(t *testing.T) {
+	// A content stream that inflates to 1 MiB
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write(make([]byte, 1<<20))
+	_ = zw.Close()
+	data := testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>",
+		testutil.Stream("/Filter /FlateDecode", compressed.String()),
+	}, "")
+
+	var buf bytes.Buffer
+	c := New(WithLimits(document.Limits{MaxStreamBytes: 64 << 10}))
+	err := c.ConvertToWriter(context.Background(), bytes.NewReader(data), int64(len(data)), &buf, nil)
+	if !errors.Is(err, ErrLimitExceeded) || Classify(err) != ClassLimitExceeded {
+		t.Fatalf("Expected ErrLimitExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Package document defines the format-neutral model that every converter
+// produces: Document → Sections → Blocks → Inlines, plus tables, images,
+// notes and metadata. Markdown and JSON output are renderers over it, so
+// downstream code can post-process any source format uniformly.
+package document
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// BlockType identifies the kind of a Block
+type BlockType string
+
+// Block types
+const (
+	BlockHeading   BlockType = "heading"
+	BlockParagraph BlockType = "paragraph"
+	BlockListItem  BlockType = "list_item"
+	BlockTable     BlockType = "table"
+	BlockImage     BlockType = "image"
+	BlockCode      BlockType = "code"
+	BlockTOC       BlockType = "toc"
+)
+
+// Document is the root of a converted document
+type Document struct {
+	// Format is the source format, e.g. "pdf", "docx" or "xlsx"
+	Format string `json:"format"`
+
+	// Metadata holds document properties such as title and author
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	// Sections hold the content in reading order: one per page for PDF,
+	// one per sheet for XLSX, and the document body (plus any headers and
+	// footers) for DOCX
+	Sections []*Section `json:"sections"`
+
+	// Images describes the images referenced by image blocks
+	Images []*Image `json:"images,omitempty"`
+
+	// Notes holds footnotes and endnotes referenced as [^id] in block text
+	Notes []*Note `json:"notes,omitempty"`
+
+	// Warnings lists problems met during conversion that may have affected the content
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Metadata holds document properties. Dates are kept as written in the source.
+type Metadata struct {
+	Title    string `json:"title,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Keywords string `json:"keywords,omitempty"`
+	Creator  string `json:"creator,omitempty"` // Application that created the document
+	Producer string `json:"producer,omitempty"`
+	Created  string `json:"created,omitempty"`
+	Modified string `json:"modified,omitempty"`
+	Pages    int    `json:"pages,omitempty"` // Page count (PDF only)
+
+	// PageRange lists the pages converted, e.g. "1-5,10", when only some
+	// were selected (PDF only)
+	PageRange string `json:"page_range,omitempty"`
+}
+
+// Section is a page, sheet or other top-level division of a document
+type Section struct {
+	Page   int      `json:"page,omitempty"`   // 1-based page number (PDF only)
+	Name   string   `json:"name,omitempty"`   // Sheet name, "header"/"footer" for DOCX, or "outline"/"form" for a PDF's TOC and form fields
+	Title  string   `json:"title,omitempty"`  // Heading rendered before the section's blocks
+	Anchor string   `json:"anchor,omitempty"` // ID that internal links point to as "#anchor"
+	Blocks []*Block `json:"blocks"`
+}
+
+// Block is a single structural element
+type Block struct {
+	Type BlockType `json:"type"`
+
+	// Level is the heading level (1-6) or the 0-based list nesting level
+	Level int `json:"level,omitempty"`
+
+	// Marker is the bullet or number of a list item, e.g. "-" or "2."
+	Marker string `json:"marker,omitempty"`
+
+	// Text is the plain text of headings, paragraphs, list items, code and TOC blocks
+	Text string `json:"text,omitempty"`
+
+	// Inlines holds the formatted content of headings, paragraphs and list items.
+	// Text is their plain-text rendering.
+	Inlines []*Inline `json:"inlines,omitempty"`
+
+	// Title names a table, e.g. the sheet range of an XLSX table
+	Title string `json:"title,omitempty"`
+
+	// Rows holds the cells of a table
+	Rows []*Row `json:"rows,omitempty"`
+
+	// Image is the ID of the image shown by an image block
+	Image string `json:"image,omitempty"`
+}
+
+// InlineType identifies the kind of an Inline
+type InlineType string
+
+// Inline types
+const (
+	InlineText    InlineType = "text"
+	InlineLink    InlineType = "link"
+	InlineNoteRef InlineType = "note_ref"
+)
+
+// Inline is a run of text with uniform formatting
+type Inline struct {
+	Type   InlineType `json:"type"`
+	Text   string     `json:"text,omitempty"`
+	Bold   bool       `json:"bold,omitempty"`
+	Italic bool       `json:"italic,omitempty"`
+	Target string     `json:"target,omitempty"` // Link URL or note ID
+}
+
+// PlainText returns the text of inlines without formatting.
+// Note references are written as [^id].
+func PlainText(inlines []*Inline) string {
+	var b strings.Builder
+	for _, in := range inlines {
+		if in.Type == InlineNoteRef {
+			b.WriteString("[^" + in.Target + "]")
+		} else {
+			b.WriteString(in.Text)
+		}
+	}
+	return b.String()
+}
+
+// Row is a table row
+type Row struct {
+	Header bool     `json:"header,omitempty"`
+	Cells  []string `json:"cells"`
+}
+
+// Image describes an extracted image
+type Image struct {
+	ID     string `json:"id"`
+	Src    string `json:"src,omitempty"` // Link to the stored image, set once it is written
+	Alt    string `json:"alt,omitempty"`
+	Format string `json:"format,omitempty"`
+	Page   int    `json:"page,omitempty"` // 1-based page number (PDF only)
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// Note is a footnote or endnote
+type Note struct {
+	ID      string `json:"id"`
+	Text    string `json:"text"`
+	Endnote bool   `json:"endnote,omitempty"`
+}
+
+// Walk calls fn for every block in every section, in reading order
+func (d *Document) Walk(fn func(section *Section, block *Block)) {
+	for _, s := range d.Sections {
+		for _, b := range s.Blocks {
+			fn(s, b)
+		}
+	}
+}
+
+// AddSection appends a new section and returns it
+func (d *Document) AddSection(page int, name string) *Section {
+	s := &Section{Page: page, Name: name, Blocks: []*Block{}}
+	d.Sections = append(d.Sections, s)
+	return s
+}
+
+// SetImageLinks sets the Src of each image from links, keyed by image ID
+func (d *Document) SetImageLinks(links map[string]string) {
+	for _, img := range d.Images {
+		if link, ok := links[img.ID]; ok {
+			img.Src = link
+		}
+	}
+}
+
+// WriteJSON writes the document to w as indented JSON
+func (d *Document) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(d)
+}
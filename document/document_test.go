@@ -0,0 +1,165 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	doc := &Document{Format: "pdf"}
+	section := doc.AddSection(1, "")
+	section.Blocks = append(section.Blocks,
+		&Block{Type: BlockHeading, Level: 1, Text: "Title"},
+		&Block{Type: BlockTable, Rows: []*Row{{Header: true, Cells: []string{"a", "b"}}}},
+		&Block{Type: BlockImage, Image: "image_001"},
+	)
+	doc.Images = []*Image{{ID: "image_001", Format: "png", Page: 1}}
+	doc.AddSection(2, "")
+
+	doc.SetImageLinks(map[string]string{"image_001": "out_images/image_001.png"})
+
+	var buf bytes.Buffer
+	if err := doc.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var decoded Document
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(decoded.Sections) != 2 || decoded.Sections[0].Page != 1 {
+		t.Fatalf("Unexpected sections: %s", buf.String())
+	}
+	if decoded.Sections[1].Blocks == nil {
+		t.Error("Expected empty section to encode blocks as []")
+	}
+	blocks := decoded.Sections[0].Blocks
+	if len(blocks) != 3 || blocks[0].Type != BlockHeading || blocks[1].Rows[0].Cells[1] != "b" {
+		t.Errorf("Unexpected blocks: %s", buf.String())
+	}
+	if decoded.Images[0].Src != "out_images/image_001.png" {
+		t.Errorf("Expected image src to be set, got %q", decoded.Images[0].Src)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	doc := &Document{Format: "pdf"}
+	first := doc.AddSection(1, "")
+	first.Blocks = append(first.Blocks,
+		&Block{Type: BlockHeading, Level: 2, Text: "Title"},
+		&Block{Type: BlockParagraph, Inlines: []*Inline{
+			{Type: InlineText, Text: "Some "},
+			{Type: InlineText, Text: "bold", Bold: true},
+			{Type: InlineText, Text: " and a "},
+			{Type: InlineLink, Text: "link", Target: "https://example.com"},
+			{Type: InlineNoteRef, Target: "1"},
+		}},
+		&Block{Type: BlockListItem, Marker: "-", Text: "One"},
+		&Block{Type: BlockListItem, Marker: "-", Level: 1, Text: "Nested"},
+		&Block{Type: BlockTable, Rows: []*Row{{Header: true, Cells: []string{"a", "b"}}, {Cells: []string{"1", "x|y"}}}},
+	)
+	second := doc.AddSection(2, "")
+	second.Blocks = append(second.Blocks,
+		&Block{Type: BlockTable, Rows: []*Row{{Header: true, Cells: []string{"A", "B"}}, {Cells: []string{"2", "z"}}}},
+		&Block{Type: BlockImage, Image: "image_001"},
+	)
+	doc.Notes = []*Note{{ID: "1", Text: "A note"}}
+
+	want := "## Title\n\n" +
+		"Some **bold** and a [link](https://example.com)[^1]\n\n" +
+		"- One\n  - Nested\n\n" +
+		"| a | b |\n| --- | --- |\n| 1 | x\\|y |\n| 2 | z |\n\n" +
+		"![image_001]\n\n" +
+		"## Footnotes\n\n[^1]: A note\n"
+	if got := doc.Markdown(&MarkdownOptions{MergeTables: true}); got != want {
+		t.Errorf("Markdown mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	plain := doc.Markdown(&MarkdownOptions{PlainText: true, SectionSeparator: "---"})
+	if strings.Contains(plain, "**") {
+		t.Errorf("Expected no bold markers in plain text, got:\n%s", plain)
+	}
+	if !strings.Contains(plain, "| 1 | x\\|y |\n\n---\n\n| A | B |") {
+		t.Errorf("Expected unmerged tables split by the separator, got:\n%s", plain)
+	}
+}
+
+func TestMarkdownLink(t *testing.T) {
+	tests := []struct {
+		text, target, want string
+	}{
+		{"site", "https://example.com/a b", "[site](https://example.com/a%20b)"},
+		{"wiki", "https://en.wikipedia.org/wiki/Go_(language)", "[wiki](https://en.wikipedia.org/wiki/Go_%28language%29)"},
+		{"[1] note]", "https://example.com", `[\[1\] note\]](https://example.com)`},
+		{"two\nlines", "MAILTO:me@example.com", "[two lines](MAILTO:me@example.com)"},
+		{"page 3", "#page-3", "[page 3](#page-3)"},
+		{"run", "javascript:alert(1)", "run"},
+		{"file", "file:///etc/passwd", "file"},
+		{"[x]", "", `\[x\]`},
+	}
+	for _, tt := range tests {
+		if got := MarkdownLink(tt.text, tt.target); got != tt.want {
+			t.Errorf("MarkdownLink(%q, %q) = %q, want %q", tt.text, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownImageAlt(t *testing.T) {
+	doc := &Document{Format: "pdf"}
+	section := doc.AddSection(1, "")
+	section.Blocks = append(section.Blocks, &Block{Type: BlockImage, Image: "image_001"})
+	doc.Images = []*Image{{ID: "image_001", Src: "images/image_001.png", Alt: "Chart [Q1]\nrevenue"}}
+
+	want := `![Chart \[Q1\] revenue](images/image_001.png)`
+	if got := doc.Markdown(nil); !strings.Contains(got, want) {
+		t.Errorf("Expected escaped alt text %q, got:\n%s", want, got)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	var seen []Warning
+	diag := NewDiagnostics(func(w Warning) { seen = append(seen, w) }, nil)
+	diag.Warnf(WarnImageSkipped, 2, "image %s: %s", "Im1", "unsupported filter")
+	diag.Warnf(WarnImageSkipped, 2, "image %s: %s", "Im1", "unsupported filter")
+	diag.Warnf(WarnFontNoUnicode, 0, "font F1 has no ToUnicode map")
+
+	if got := diag.Warnings(); len(got) != 2 || len(seen) != 2 {
+		t.Fatalf("Expected 2 warnings after dedupe, got %v (callback saw %d)", got, len(seen))
+	}
+	if got := diag.Warnings()[0].String(); got != "page 2: image Im1: unsupported filter" {
+		t.Errorf("Unexpected warning string %q", got)
+	}
+
+	var none *Diagnostics
+	none.Warnf(WarnPageFailed, 1, "ignored")
+	if none.Warnings() != nil {
+		t.Error("Expected a nil collector to discard warnings")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	budget := NewBudget(Limits{MaxStreamBytes: 10, MaxTotalBytes: 15})
+	if data, err := budget.ReadAll("a", strings.NewReader("0123456789")); err != nil || len(data) != 10 {
+		t.Fatalf("Expected a stream at the limit to be read, got %d bytes, %v", len(data), err)
+	}
+	if _, err := budget.ReadAll("b", strings.NewReader("0123456789a")); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected the stream limit to be exceeded, got %v", err)
+	}
+	if budget.Err() == nil {
+		t.Error("Expected the budget to remember the limit error")
+	}
+
+	budget = NewBudget(Limits{MaxStreamBytes: 10, MaxTotalBytes: 15})
+	_, _ = budget.ReadAll("a", strings.NewReader("0123456789"))
+	if _, err := budget.ReadAll("b", strings.NewReader("0123456")); !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), "total") {
+		t.Errorf("Expected the total limit to be exceeded, got %v", err)
+	}
+
+	var unlimited *Budget
+	if data, err := unlimited.ReadAll("a", strings.NewReader("0123456789a")); err != nil || len(data) != 11 {
+		t.Errorf("Expected a nil budget to read everything, got %d bytes, %v", len(data), err)
+	}
+}
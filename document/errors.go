@@ -0,0 +1,52 @@
+package document
+
+import "errors"
+
+// Errors returned by the converters, classified so callers can tell failures
+// apart with errors.Is. Converters wrap them with MarkError to keep their
+// own, more specific messages.
+var (
+	// ErrEncrypted reports a document that cannot be read without a password
+	ErrEncrypted = errors.New("document is encrypted")
+
+	// ErrWrongPassword reports an encrypted document whose password was given
+	// but is wrong. Such errors also match ErrEncrypted.
+	ErrWrongPassword = errors.New("wrong password")
+
+	// ErrNoTextContent reports a document that parsed but yielded no text,
+	// typically a scan without a text layer
+	ErrNoTextContent = errors.New("no text content")
+
+	// ErrCorrupt reports a document that is damaged or not a valid file of its format
+	ErrCorrupt = errors.New("document is corrupt")
+
+	// ErrUnsupported reports a document format or feature that cannot be converted
+	ErrUnsupported = errors.New("unsupported document")
+
+	// ErrLimitExceeded reports a document that needs more work than its Limits
+	// allow, such as a zip bomb
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+// MarkError returns err marked with kind, one of the Err values above, so that
+// errors.Is(err, kind) holds. The message of err is kept unchanged.
+func MarkError(kind, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &markedError{kind: kind, err: err}
+}
+
+// markedError is an error marked with a kind by MarkError
+type markedError struct {
+	kind error
+	err  error
+}
+
+func (e *markedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *markedError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
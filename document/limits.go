@@ -0,0 +1,114 @@
+package document
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Limits bounds the work a converter does for one document, to protect it
+// from hostile input such as zip bombs and huge page trees. A zero field
+// means no limit. Documents that exceed a limit fail with ErrLimitExceeded.
+type Limits struct {
+	MaxInputBytes  int64 // Largest document accepted
+	MaxStreamBytes int64 // Largest decompressed PDF stream or archive part
+	MaxTotalBytes  int64 // Most bytes decompressed for one document
+	MaxPages       int   // Most PDF pages
+	MaxSheets      int   // Most XLSX sheets
+	MaxRows        int   // Highest XLSX row number
+	MaxCells       int   // Most XLSX cells in a workbook, merged areas included
+	MaxImages      int   // Most images extracted from one document
+	MaxXMLDepth    int   // Deepest element nesting in DOCX and XLSX parts
+}
+
+// DefaultLimits returns the limits the converters use unless told otherwise.
+// They stop decompression bombs without getting in the way of large but
+// genuine documents.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxStreamBytes: 256 << 20,
+		MaxTotalBytes:  1 << 30,
+		MaxCells:       5_000_000,
+		MaxXMLDepth:    1000,
+	}
+}
+
+// CheckLimit returns an ErrLimitExceeded error naming what if n is over max,
+// or nil if it is not or max is zero
+func CheckLimit[T ~int | ~int64](what string, n, max T) error {
+	if max > 0 && n > max {
+		return fmt.Errorf("%w: %s exceeds %d", ErrLimitExceeded, what, max)
+	}
+	return nil
+}
+
+// Budget tracks the bytes decompressed for one document against the
+// MaxStreamBytes and MaxTotalBytes limits. It is safe for concurrent use.
+// A nil *Budget imposes no limits.
+type Budget struct {
+	limits Limits
+	mu     sync.Mutex
+	used   int64
+	err    error
+}
+
+// NewBudget returns a budget for limits
+func NewBudget(limits Limits) *Budget {
+	return &Budget{limits: limits}
+}
+
+// Limits returns the limits of the budget
+func (b *Budget) Limits() Limits {
+	if b == nil {
+		return Limits{}
+	}
+	return b.limits
+}
+
+// ReadAll reads r until EOF like io.ReadAll, but fails with ErrLimitExceeded
+// once it has read more than MaxStreamBytes or the rest of MaxTotalBytes.
+// what names the stream in the error.
+func (b *Budget) ReadAll(what string, r io.Reader) ([]byte, error) {
+	if b == nil {
+		return io.ReadAll(r)
+	}
+
+	b.mu.Lock()
+	streamMax, totalLeft := b.limits.MaxStreamBytes, b.limits.MaxTotalBytes-b.used
+	b.mu.Unlock()
+	limit := streamMax
+	if b.limits.MaxTotalBytes > 0 && (limit <= 0 || totalLeft < limit) {
+		limit = max(totalLeft, 0)
+	}
+	if limit <= 0 && b.limits.MaxTotalBytes <= 0 {
+		return io.ReadAll(r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used += int64(len(data))
+	if int64(len(data)) > limit {
+		limitErr := CheckLimit("decompressed size of "+what, int64(len(data)), streamMax)
+		if limitErr == nil {
+			limitErr = CheckLimit("total decompressed size", b.used, b.limits.MaxTotalBytes)
+		}
+		if b.err == nil {
+			b.err = limitErr
+		}
+		return nil, limitErr
+	}
+	return data, err
+}
+
+// Err returns the first limit error reported by ReadAll, if any. Converters
+// check it so that a document is rejected even where a stream that failed to
+// decompress would otherwise be skipped.
+func (b *Budget) Err() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
@@ -0,0 +1,339 @@
+package document
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MarkdownOptions controls how a Document is rendered as Markdown
+type MarkdownOptions struct {
+	// SectionSeparator is written between sections (e.g. "---\n" between pages)
+	SectionSeparator string
+
+	// PlainText omits bold and italic markers
+	PlainText bool
+
+	// MergeTables joins a table that directly follows another table with the
+	// same number of columns, even across a section boundary, and drops its
+	// header row if it repeats the first table's header. This reassembles
+	// tables split across PDF pages.
+	MergeTables bool
+}
+
+// Markdown renders the document as Markdown. Images with a Src are linked;
+// the others are written as ![id] placeholders. Notes are listed at the end
+// under a Footnotes heading.
+func (d *Document) Markdown(opts *MarkdownOptions) string {
+	if opts == nil {
+		opts = &MarkdownOptions{}
+	}
+
+	r := &markdownRenderer{
+		opts:   opts,
+		images: make(map[string]*Image),
+	}
+	for _, img := range d.Images {
+		r.images[img.ID] = img
+	}
+
+	for i, section := range d.Sections {
+		if i > 0 && opts.SectionSeparator != "" {
+			r.separate(opts.SectionSeparator)
+		}
+		if section.Anchor != "" {
+			r.block(`<a id="` + section.Anchor + `"></a>` + "\n")
+		}
+		if section.Title != "" {
+			r.block("## " + section.Title + "\n")
+		}
+		for _, block := range section.Blocks {
+			r.render(block)
+		}
+	}
+
+	if len(d.Notes) > 0 {
+		r.block("## Footnotes\n")
+		for _, note := range d.Notes {
+			r.block("[^" + note.ID + "]: " + note.Text + "\n")
+		}
+	}
+
+	return r.out.String()
+}
+
+// WriteMarkdown writes the document to w as Markdown (see Markdown)
+func (d *Document) WriteMarkdown(w io.Writer, opts *MarkdownOptions) error {
+	_, err := io.WriteString(w, d.Markdown(opts))
+	return err
+}
+
+// markdownRenderer accumulates Markdown output. Separators between blocks are
+// held back until the next block is written so that merged tables can drop them.
+type markdownRenderer struct {
+	opts    *MarkdownOptions
+	images  map[string]*Image
+	out     strings.Builder
+	pending string
+
+	prev      *Block // Last block written
+	tableCols int    // Column count of the table being written, for merging
+	header    string // Header row of the table being written, for merging
+}
+
+// block writes a block of content, preceded by a blank line if anything came before
+func (r *markdownRenderer) block(s string) {
+	r.write("\n", s)
+	r.prev = nil
+}
+
+// write flushes the pending separator (or gap, if none is pending) and writes s
+func (r *markdownRenderer) write(gap, s string) {
+	if r.out.Len() > 0 {
+		if r.pending != "" {
+			r.out.WriteString(r.pending)
+		} else {
+			r.out.WriteString(gap)
+		}
+	}
+	r.pending = ""
+	r.out.WriteString(s)
+}
+
+// separate queues a section separator surrounded by blank lines
+func (r *markdownRenderer) separate(sep string) {
+	if !strings.HasSuffix(sep, "\n") {
+		sep += "\n"
+	}
+	if strings.TrimSpace(sep) != "" {
+		sep = "\n" + sep + "\n"
+	}
+	r.pending += sep
+}
+
+// render writes a single block
+func (r *markdownRenderer) render(b *Block) {
+	switch b.Type {
+	case BlockHeading:
+		level := b.Level
+		if level < 1 {
+			level = 1
+		} else if level > 6 {
+			level = 6
+		}
+		r.block(strings.Repeat("#", level) + " " + b.Text + "\n")
+	case BlockListItem:
+		var line strings.Builder
+		line.WriteString(strings.Repeat("  ", b.Level))
+		if b.Marker != "" {
+			line.WriteString(b.Marker + " ")
+		}
+		line.WriteString(r.inlines(b))
+		line.WriteString("\n")
+		gap := "\n"
+		if r.prev != nil && r.prev.Type == BlockListItem {
+			gap = ""
+		}
+		r.write(gap, line.String())
+	case BlockTable:
+		r.table(b)
+		return
+	case BlockImage:
+		r.block(r.image(b.Image) + "\n")
+	case BlockCode:
+		r.block("```\n" + b.Text + "\n```\n")
+	case BlockTOC:
+		r.block(strings.TrimRight(b.Text, "\n") + "\n")
+	default:
+		text := r.inlines(b)
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		r.block(text + "\n")
+	}
+	r.prev = b
+}
+
+// table writes a table block, continuing the previous table when merging
+func (r *markdownRenderer) table(b *Block) {
+	if len(b.Rows) == 0 {
+		return
+	}
+	cols := len(b.Rows[0].Cells)
+
+	var out strings.Builder
+	rows := b.Rows
+	merge := r.opts.MergeTables && r.prev != nil && r.prev.Type == BlockTable &&
+		b.Title == "" && cols == r.tableCols
+	if merge {
+		// Continue the previous table, dropping a repeated header
+		if rows[0].Header && normalizeRow(rows[0].Cells) == r.header {
+			rows = rows[1:]
+		}
+	} else {
+		r.tableCols = cols
+		r.header = ""
+		if b.Title != "" {
+			out.WriteString("### " + b.Title + "\n")
+		}
+	}
+
+	headerWritten := merge
+	for _, row := range rows {
+		out.WriteString("|")
+		for _, cell := range row.Cells {
+			out.WriteString(" " + escapeCell(cell) + " |")
+		}
+		out.WriteString("\n")
+		if row.Header && !headerWritten {
+			out.WriteString("|")
+			for range row.Cells {
+				out.WriteString(" --- |")
+			}
+			out.WriteString("\n")
+			r.header = normalizeRow(row.Cells)
+			headerWritten = true
+		}
+	}
+
+	if merge {
+		r.pending = ""
+		r.out.WriteString(out.String())
+	} else {
+		r.write("\n", out.String())
+	}
+	r.prev = b
+}
+
+// inlines renders the inline content of a block, falling back to its Text
+func (r *markdownRenderer) inlines(b *Block) string {
+	if len(b.Inlines) == 0 {
+		return b.Text
+	}
+
+	var out strings.Builder
+	for _, in := range b.Inlines {
+		switch in.Type {
+		case InlineNoteRef:
+			out.WriteString("[^" + in.Target + "]")
+			continue
+		case InlineLink:
+			out.WriteString(r.format(in, MarkdownLink(in.Text, in.Target)))
+		default:
+			out.WriteString(r.format(in, in.Text))
+		}
+	}
+	return out.String()
+}
+
+// format wraps text in the bold/italic markers of in, keeping surrounding
+// whitespace outside the markers
+func (r *markdownRenderer) format(in *Inline, text string) string {
+	if r.opts.PlainText || (!in.Bold && !in.Italic) {
+		return text
+	}
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+
+	start, end := "_", "_"
+	switch {
+	case in.Bold && in.Italic:
+		start, end = "**_", "_**"
+	case in.Bold:
+		start, end = "**", "**"
+	}
+
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + start + trimmed + end + trail
+}
+
+// image renders an image link, or an ![id] placeholder if it has no Src
+func (r *markdownRenderer) image(id string) string {
+	img := r.images[id]
+	if img == nil || img.Src == "" {
+		return "![" + id + "]"
+	}
+	alt := img.Alt
+	if alt == "" {
+		alt = "image"
+	}
+	return "![" + EscapeLinkText(alt) + "](" + img.Src + ")"
+}
+
+// linkSchemes are the URL schemes rendered as links
+var linkSchemes = []string{"http:", "https:", "mailto:"}
+
+// IsAllowedLink reports whether target may be rendered as a link: an http,
+// https or mailto URL, or an #anchor within the document. Other targets,
+// such as javascript: URLs, are rendered as their text only.
+func IsAllowedLink(target string) bool {
+	if strings.HasPrefix(target, "#") {
+		return len(target) > 1
+	}
+	lower := strings.ToLower(strings.TrimSpace(target))
+	for _, scheme := range linkSchemes {
+		if strings.HasPrefix(lower, scheme) && len(lower) > len(scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkdownLink renders a link with escaped text and a percent-encoded
+// target, or only the escaped text if the target is not allowed
+func MarkdownLink(text, target string) string {
+	if !IsAllowedLink(target) {
+		return EscapeLinkText(text)
+	}
+	return "[" + EscapeLinkText(text) + "](" + escapeTarget(strings.TrimSpace(target)) + ")"
+}
+
+// EscapeLinkText makes text safe inside the brackets of a link or an image's
+// alt text, escaping brackets and backslashes and joining lines
+func EscapeLinkText(text string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\",
+		"[", "\\[",
+		"]", "\\]",
+		"\r\n", " ",
+		"\r", " ",
+		"\n", " ",
+	).Replace(text)
+}
+
+// escapeTarget percent-encodes the characters of a link target that would
+// end it or break the Markdown around it
+func escapeTarget(target string) string {
+	var out strings.Builder
+	for i := 0; i < len(target); i++ {
+		c := target[i]
+		if c <= ' ' || c == 0x7f || strings.IndexByte("<>()\\\"`", c) >= 0 {
+			_, _ = fmt.Fprintf(&out, "%%%02X", c)
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// escapeCell makes a cell value safe for a Markdown table
+func escapeCell(value string) string {
+	value = strings.TrimSpace(value)
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	value = strings.ReplaceAll(value, "\r", "\n")
+	value = strings.ReplaceAll(value, "\n", "<br>")
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return value
+}
+
+// normalizeRow creates a canonical form of a header row for comparison
+func normalizeRow(cells []string) string {
+	normalized := make([]string, len(cells))
+	for i, cell := range cells {
+		normalized[i] = strings.ToLower(strings.TrimSpace(cell))
+	}
+	return strings.Join(normalized, "|")
+}
@@ -0,0 +1,84 @@
+package document
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Warning codes reported by the built-in converters
+const (
+	WarnPageFailed     = "page_failed"       // A page could not be extracted and was left out
+	WarnPageSize       = "page_size_unknown" // Page dimensions could not be read; defaults were used
+	WarnImageSkipped   = "image_skipped"     // An image could not be read or decoded and was left out
+	WarnImageNotStored = "image_not_stored"  // The image sink failed to store an image
+	WarnPartSkipped    = "part_skipped"      // A part of the document, such as DOCX headers, could not be read
+	WarnFontNoUnicode  = "font_no_tounicode" // A font has no usable ToUnicode map, so its text may be garbled
+	WarnDecodeFallback = "decode_fallback"   // A stream was not fully decoded and its data was used as is
+	WarnDecryptFailed  = "decrypt_failed"    // A stream could not be decrypted and was read as is
+)
+
+// Warning is a problem that did not stop a conversion but may have affected
+// its output, such as an image that could not be decoded
+type Warning struct {
+	Code    string `json:"code"`           // One of the Warn constants
+	Page    int    `json:"page,omitempty"` // 1-based page, if the warning concerns one
+	Message string `json:"message"`
+}
+
+// String returns the message, prefixed with the page if there is one
+func (w Warning) String() string {
+	if w.Page > 0 {
+		return fmt.Sprintf("page %d: %s", w.Page, w.Message)
+	}
+	return w.Message
+}
+
+// Diagnostics collects the warnings of one conversion. Each warning is also
+// passed to the optional callback and logger as it is reported. Repeated
+// identical warnings are recorded once. A nil *Diagnostics discards warnings.
+type Diagnostics struct {
+	onWarning func(Warning)
+	logger    *slog.Logger
+	warnings  []Warning
+	seen      map[Warning]bool
+}
+
+// NewDiagnostics returns a collector that also passes warnings to onWarning
+// and logger, either of which may be nil
+func NewDiagnostics(onWarning func(Warning), logger *slog.Logger) *Diagnostics {
+	return &Diagnostics{onWarning: onWarning, logger: logger, seen: make(map[Warning]bool)}
+}
+
+// Warn records a warning
+func (d *Diagnostics) Warn(w Warning) {
+	if d == nil || d.seen[w] {
+		return
+	}
+	d.seen[w] = true
+	d.warnings = append(d.warnings, w)
+
+	if d.logger != nil {
+		attrs := []any{"code", w.Code}
+		if w.Page > 0 {
+			attrs = append(attrs, "page", w.Page)
+		}
+		d.logger.Warn(w.Message, attrs...)
+	}
+	if d.onWarning != nil {
+		d.onWarning(w)
+	}
+}
+
+// Warnf records a warning with a formatted message. page is 1-based, or 0 if
+// the warning does not concern a page.
+func (d *Diagnostics) Warnf(code string, page int, format string, args ...any) {
+	d.Warn(Warning{Code: code, Page: page, Message: fmt.Sprintf(format, args...)})
+}
+
+// Warnings returns the warnings recorded so far, in the order they were reported
+func (d *Diagnostics) Warnings() []Warning {
+	if d == nil {
+		return nil
+	}
+	return d.warnings
+}
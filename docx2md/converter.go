@@ -2,10 +2,14 @@
 package docx2md
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
 
+	"github.com/tenebris-tech/x2md/document"
 	"github.com/tenebris-tech/x2md/docx2md/docx"
 	"github.com/tenebris-tech/x2md/docx2md/transform"
 	"github.com/tenebris-tech/x2md/imageutil"
@@ -34,9 +38,23 @@ type Options struct {
 	// Compact removes excessive blank lines from the output
 	Compact bool
 
+	// ImageSink stores extracted images when converting to a file.
+	// If nil, images are written to a <name>_images directory beside the output.
+	ImageSink imageutil.Sink
+
 	// Callbacks for conversion progress
 	OnDocumentParsed func()
 	OnStylesParsed   func(styleCount int)
+
+	// OnWarning receives problems that did not stop the conversion, such as
+	// images missing from the archive
+	OnWarning func(w document.Warning)
+
+	// Logger, if set, logs each warning at warn level
+	Logger *slog.Logger
+
+	// Limits bounds the work done for one document (default: document.DefaultLimits)
+	Limits document.Limits
 }
 
 // Option is a functional option for configuring the converter
@@ -48,6 +66,7 @@ func DefaultOptions() *Options {
 		PreserveFormatting: true,
 		PreserveImages:     true,
 		PageSeparator: "\n",
+		Limits:             document.DefaultLimits(),
 	}
 }
 
@@ -86,6 +105,28 @@ func WithOnStylesParsed(callback func(styleCount int)) Option {
 	}
 }
 
+// WithOnWarning sets the callback for conversion warnings
+func WithOnWarning(callback func(w document.Warning)) Option {
+	return func(o *Options) {
+		o.OnWarning = callback
+	}
+}
+
+// WithLogger sets a logger for conversion warnings
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithLimits sets the limits for hostile input, such as the largest
+// decompressed part and the deepest XML nesting
+func WithLimits(limits document.Limits) Option {
+	return func(o *Options) {
+		o.Limits = limits
+	}
+}
+
 // WithCompact removes excessive blank lines from the output.
 func WithCompact(compact bool) Option {
 	return func(o *Options) {
@@ -93,6 +134,14 @@ func WithCompact(compact bool) Option {
 	}
 }
 
+// WithImageSink sets where extracted images are stored by ConvertFileToFile.
+// Use imageutil.MemorySink, imageutil.DataURISink, imageutil.HashSink or a custom Sink.
+func WithImageSink(sink imageutil.Sink) Option {
+	return func(o *Options) {
+		o.ImageSink = sink
+	}
+}
+
 // New creates a new Converter with the given options
 func New(opts ...Option) *Converter {
 	options := DefaultOptions()
@@ -113,6 +162,12 @@ func (c *Converter) ConvertFile(inputPath string) (string, error) {
 
 // ConvertFileToFile converts a DOCX file and writes the result to a file
 func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
+	return c.ConvertFileToFileContext(context.Background(), inputPath, outputPath)
+}
+
+// ConvertFileToFileContext is like ConvertFileToFile but stops when ctx is cancelled.
+// Nothing is written if the conversion is cancelled.
+func (c *Converter) ConvertFileToFileContext(ctx context.Context, inputPath, outputPath string) error {
 	// Read input file
 	data, err := os.ReadFile(inputPath)
 	if err != nil {
@@ -120,7 +175,7 @@ func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
 	}
 
 	// Convert to markdown and get images
-	markdown, images, err := c.ConvertWithImages(data)
+	markdown, images, err := c.ConvertWithImagesContext(ctx, data)
 	if err != nil {
 		return err
 	}
@@ -136,44 +191,64 @@ func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
 	return os.WriteFile(outputPath, []byte(markdown), 0644)
 }
 
-// writeImages writes images to disk and updates markdown with correct paths
+// writeImages stores images in the configured sink (or beside outputPath) and
+// updates markdown with their locations
 func (c *Converter) writeImages(outputPath, markdown string, images []*models.ImageItem) (string, error) {
-	writer, err := imageutil.NewImageWriter(outputPath)
+	sink := c.options.ImageSink
+	if sink == nil {
+		writer, err := imageutil.NewImageWriter(outputPath)
+		if err != nil {
+			return markdown, err
+		}
+		sink = writer
+	}
+	return c.resolveImages(markdown, images, sink), nil
+}
+
+// resolveImages stores images in sink and links them in markdown. Images the
+// sink fails to store keep their placeholder and are reported as warnings.
+func (c *Converter) resolveImages(markdown string, images []*models.ImageItem, sink imageutil.Sink) string {
+	diag := document.NewDiagnostics(c.options.OnWarning, c.options.Logger)
+	links := imageutil.StoreImagesReport(images, sink, func(img *models.ImageItem, err error) {
+		diag.Warnf(document.WarnImageNotStored, 0, "image %s: %v", img.ID, err)
+	})
+	return imageutil.LinkImages(markdown, images, links)
+}
+
+// ConvertReader converts a DOCX read from r to Markdown and returns extracted images.
+// The document is read fully into memory, as DOCX is a zip archive.
+func (c *Converter) ConvertReader(r io.ReaderAt, size int64) (string, []*models.ImageItem, error) {
+	return c.ConvertReaderContext(context.Background(), r, size)
+}
+
+// ConvertReaderContext is like ConvertReader but stops when ctx is cancelled
+func (c *Converter) ConvertReaderContext(ctx context.Context, r io.ReaderAt, size int64) (string, []*models.ImageItem, error) {
+	if err := document.CheckLimit("input size", size, c.options.Limits.MaxInputBytes); err != nil {
+		return "", nil, err
+	}
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
 	if err != nil {
-		return markdown, err
+		return "", nil, fmt.Errorf("reading input: %w", err)
 	}
+	return c.ConvertWithImagesContext(ctx, data)
+}
 
-	// Build map of image IDs to output paths
-	imageMap := make(map[string]string)
+// ConvertToWriter converts a DOCX read from r and writes the Markdown to w.
+// Images are handed to sink and linked using the locations it returns. If sink
+// is nil or image preservation is disabled, images are dropped and their ![id]
+// placeholders are left in the Markdown.
+func (c *Converter) ConvertToWriter(ctx context.Context, r io.ReaderAt, size int64, w io.Writer, sink imageutil.Sink) error {
+	markdown, images, err := c.ConvertReaderContext(ctx, r, size)
+	if err != nil {
+		return err
+	}
 
-	for _, img := range images {
-		relativePath, err := writer.WriteImage(img)
-		if err != nil {
-			// Log warning but continue
-			continue
-		}
-		imageMap[img.ID] = relativePath
-	}
-
-	// Replace image placeholders in markdown
-	// The placeholder format is ![image_001] (from WordTypeImage.ToText)
-	for id, path := range imageMap {
-		// Find the image in the list to get alt text
-		altText := "image"
-		for _, img := range images {
-			if img.ID == id {
-				if img.AltText != "" {
-					altText = img.AltText
-				}
-				break
-			}
-		}
-		placeholder := fmt.Sprintf("![%s]", id)
-		replacement := fmt.Sprintf("![%s](%s)", altText, path)
-		markdown = strings.ReplaceAll(markdown, placeholder, replacement)
+	if sink != nil && c.options.PreserveImages && len(images) > 0 {
+		markdown = c.resolveImages(markdown, images, sink)
 	}
 
-	return markdown, nil
+	_, err = io.WriteString(w, markdown)
+	return err
 }
 
 // Convert converts DOCX data to Markdown
@@ -182,101 +257,201 @@ func (c *Converter) Convert(data []byte) (string, error) {
 	return markdown, err
 }
 
+// ConvertContext is like Convert but stops when ctx is cancelled
+func (c *Converter) ConvertContext(ctx context.Context, data []byte) (string, error) {
+	markdown, _, err := c.ConvertWithImagesContext(ctx, data)
+	return markdown, err
+}
+
 // ConvertWithImages converts DOCX data to Markdown and returns extracted images
 func (c *Converter) ConvertWithImages(data []byte) (string, []*models.ImageItem, error) {
-	// Parse DOCX
-	parser, err := docx.NewParser(data)
+	return c.ConvertWithImagesContext(context.Background(), data)
+}
+
+// ConvertWithImagesContext is like ConvertWithImages but stops when ctx is cancelled.
+// Cancellation is checked between body-level XML elements; the returned error is ctx.Err().
+func (c *Converter) ConvertWithImagesContext(ctx context.Context, data []byte) (string, []*models.ImageItem, error) {
+	doc, images, err := c.ConvertDocumentContext(ctx, data)
 	if err != nil {
-		return "", nil, fmt.Errorf("parsing DOCX: %w", err)
+		return "", nil, err
 	}
 
-	if err := parser.Parse(); err != nil {
-		return "", nil, fmt.Errorf("validating DOCX: %w", err)
-	}
+	// Headers and footers are set apart from the body by a rule
+	markdown := doc.Markdown(&document.MarkdownOptions{
+		SectionSeparator: "---",
+		PlainText:        !c.options.PreserveFormatting,
+	})
 
-	if c.options.OnDocumentParsed != nil {
-		c.options.OnDocumentParsed()
+	// Apply compact formatting if enabled
+	if c.options.Compact {
+		markdown = compactMarkdown(markdown)
 	}
 
-	// Create extractor
-	extractor, err := docx.NewExtractor(parser)
-	if err != nil {
-		return "", nil, fmt.Errorf("creating extractor: %w", err)
-	}
+	return markdown, images, nil
+}
 
-	// Report styles if callback is set
-	if c.options.OnStylesParsed != nil {
-		styles := extractor.GetStyles()
-		c.options.OnStylesParsed(len(styles.Styles))
-	}
+// ConvertDocument converts DOCX data to a structured document and returns extracted images.
+// The body becomes one section; headers and footers, when enabled, get their own sections.
+func (c *Converter) ConvertDocument(data []byte) (*document.Document, []*models.ImageItem, error) {
+	return c.ConvertDocumentContext(context.Background(), data)
+}
 
-	// Extract content to Page format
-	page, images, err := extractor.Extract()
+// ConvertDocumentContext is like ConvertDocument but stops when ctx is cancelled
+func (c *Converter) ConvertDocumentContext(ctx context.Context, data []byte) (*document.Document, []*models.ImageItem, error) {
+	diag := document.NewDiagnostics(c.options.OnWarning, c.options.Logger)
+	extractor, page, images, err := c.extract(ctx, data, diag)
 	if err != nil {
-		return "", nil, fmt.Errorf("extracting content: %w", err)
+		return nil, nil, err
 	}
 
-	// Collect footnotes/endnotes
-	footnotes := extractor.GetCollectedFootnotes()
-
-	// Run transformation pipeline
-	pipelineOpts := &transform.PipelineOptions{
+	pipeline := transform.NewPipeline(&transform.PipelineOptions{
 		PreserveFormatting: c.options.PreserveFormatting,
-	}
-	pipeline := transform.NewPipeline(pipelineOpts)
+	})
 	result := pipeline.Transform(page)
 
-	// Combine output
-	var output strings.Builder
+	doc := &document.Document{
+		Format:   "docx",
+		Metadata: coreMetadata(extractor.GetCoreProperties()),
+	}
 
-	// Add headers if requested
 	if c.options.ExtractHeadersFooters {
 		headers, err := extractor.ExtractHeaders()
-		if err == nil && len(headers) > 0 {
-			output.WriteString("## Document Headers\n\n")
-			for _, h := range headers {
-				output.WriteString(fmt.Sprintf("**%s**: %s\n\n", h.ID, h.Content))
-			}
-			output.WriteString("---\n\n")
+		if err != nil {
+			diag.Warnf(document.WarnPartSkipped, 0, "headers left out: %v", err)
+		} else if len(headers) > 0 {
+			addHeaderFooterSection(doc, "header", headers)
 		}
 	}
 
-	// Main content
-	for _, page := range result.Pages {
-		for _, item := range page.Items {
-			if text, ok := item.(string); ok {
-				output.WriteString(text)
+	body := doc.AddSection(0, "")
+	for _, p := range result.Pages {
+		for _, item := range p.Items {
+			block, ok := item.(*models.LineItemBlock)
+			if !ok {
+				continue
+			}
+			for _, b := range models.BlockToDocument(block) {
+				if b.Type == document.BlockImage && !c.options.PreserveImages {
+					continue
+				}
+				body.Blocks = append(body.Blocks, b)
 			}
 		}
 	}
 
-	// Add footers if requested
 	if c.options.ExtractHeadersFooters {
 		footers, err := extractor.ExtractFooters()
-		if err == nil && len(footers) > 0 {
-			output.WriteString("\n\n---\n\n## Document Footers\n\n")
-			for _, f := range footers {
-				output.WriteString(fmt.Sprintf("**%s**: %s\n\n", f.ID, f.Content))
-			}
+		if err != nil {
+			diag.Warnf(document.WarnPartSkipped, 0, "footers left out: %v", err)
+		} else if len(footers) > 0 {
+			addHeaderFooterSection(doc, "footer", footers)
 		}
 	}
 
-	// Append footnotes section if any
-	if len(footnotes) > 0 {
-		output.WriteString("\n\n## Footnotes\n\n")
-		for _, fn := range footnotes {
-			output.WriteString(fmt.Sprintf("[^%s]: %s\n\n", fn.ID, fn.Content))
+	for _, fn := range extractor.GetCollectedFootnotes() {
+		doc.Notes = append(doc.Notes, &document.Note{ID: fn.ID, Text: fn.Content, Endnote: fn.IsEndnote})
+	}
+	if err := extractor.LimitErr(); err != nil {
+		return nil, nil, err
+	}
+
+	if !c.options.PreserveImages {
+		images = nil
+	}
+	for _, img := range images {
+		doc.Images = append(doc.Images, models.ImageToDocument(img, false))
+	}
+	doc.Warnings = diag.Warnings()
+
+	return doc, images, nil
+}
+
+// addHeaderFooterSection adds a section holding one paragraph per header or
+// footer, each led by its part name in bold
+func addHeaderFooterSection(doc *document.Document, name string, contents []docx.HeaderFooterContent) {
+	section := doc.AddSection(0, name)
+	section.Title = "Document Headers"
+	if name == "footer" {
+		section.Title = "Document Footers"
+	}
+	for _, hf := range contents {
+		inlines := []*document.Inline{
+			{Type: document.InlineText, Text: hf.ID, Bold: true},
+			{Type: document.InlineText, Text: ": " + hf.Content},
 		}
+		section.Blocks = append(section.Blocks, &document.Block{
+			Type:    document.BlockParagraph,
+			Text:    document.PlainText(inlines),
+			Inlines: inlines,
+		})
 	}
+}
 
-	markdown := output.String()
+// coreMetadata converts DOCX core properties to document metadata.
+// It returns nil if no properties are set.
+func coreMetadata(props *docx.CoreProperties) *document.Metadata {
+	meta := &document.Metadata{
+		Title:    props.Title,
+		Author:   props.Creator,
+		Subject:  props.Subject,
+		Keywords: props.Keywords,
+		Created:  props.Created,
+		Modified: props.Modified,
+	}
+	if *meta == (document.Metadata{}) {
+		return nil
+	}
+	return meta
+}
 
-	// Apply compact formatting if enabled
-	if c.options.Compact {
-		markdown = compactMarkdown(markdown)
+// extract parses the DOCX and extracts its body content and images, reporting
+// problems to diag
+func (c *Converter) extract(ctx context.Context, data []byte, diag *document.Diagnostics) (*docx.Extractor, *models.Page, []*models.ImageItem, error) {
+	// Parse DOCX
+	if err := document.CheckLimit("input size", int64(len(data)), c.options.Limits.MaxInputBytes); err != nil {
+		return nil, nil, nil, err
+	}
+	parser, err := docx.NewParser(data)
+	if err != nil {
+		return nil, nil, nil, document.MarkError(document.ErrCorrupt, fmt.Errorf("parsing DOCX: %w", err))
 	}
+	parser.SetLimits(c.options.Limits)
 
-	return markdown, images, nil
+	if err := parser.Parse(); err != nil {
+		return nil, nil, nil, document.MarkError(document.ErrCorrupt, fmt.Errorf("validating DOCX: %w", err))
+	}
+
+	if c.options.OnDocumentParsed != nil {
+		c.options.OnDocumentParsed()
+	}
+
+	// Create extractor
+	extractor, err := docx.NewExtractor(parser)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("creating extractor: %w", err)
+	}
+	extractor.SetContext(ctx)
+	extractor.SetWarningHandler(diag.Warn)
+
+	// Report styles if callback is set
+	if c.options.OnStylesParsed != nil {
+		styles := extractor.GetStyles()
+		c.options.OnStylesParsed(len(styles.Styles))
+	}
+
+	// Extract content to Page format
+	page, images, err := extractor.Extract()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, nil, nil, ctxErr
+		}
+		if limitErr := parser.LimitErr(); limitErr != nil {
+			return nil, nil, nil, limitErr
+		}
+		return nil, nil, nil, fmt.Errorf("extracting content: %w", err)
+	}
+
+	return extractor, page, images, nil
 }
 
 // compactMarkdown reduces excessive blank lines in markdown.
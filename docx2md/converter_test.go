@@ -3,7 +3,13 @@ package docx2md
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
 // createTestDocx creates a minimal valid DOCX for testing
@@ -231,3 +237,121 @@ func TestInvalidDocx(t *testing.T) {
 		t.Error("Expected error for DOCX without document.xml")
 	}
 }
+
+func TestLimits(t *testing.T) {
+	docx := createTestDocx(strings.Repeat("<w:sdt><w:sdtContent>", 50) +
+		"<w:p><w:r><w:t>Deep</w:t></w:r></w:p>" + strings.Repeat("</w:sdtContent></w:sdt>", 50))
+
+	if _, err := New().Convert(docx); err != nil {
+		t.Fatalf("Convert with default limits failed: %v", err)
+	}
+
+	tests := []document.Limits{
+		{MaxInputBytes: 100},
+		{MaxStreamBytes: 100},
+		{MaxXMLDepth: 20},
+	}
+	for _, limits := range tests {
+		_, err := New(WithLimits(limits)).Convert(docx)
+		if !errors.Is(err, document.ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded with %+v, got %v", limits, err)
+		}
+	}
+}
+
+func TestConvertContextCancelled(t *testing.T) {
+	docx := createTestDocx(`<w:p><w:r><w:t>Hello</w:t></w:r></w:p>`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	converter := New()
+	_, err := converter.ConvertContext(ctx, docx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestConvertToWriter(t *testing.T) {
+	docx := createTestDocx(`<w:p><w:r><w:t>Streamed</w:t></w:r></w:p>`)
+
+	var out bytes.Buffer
+	converter := New()
+	err := converter.ConvertToWriter(context.Background(), bytes.NewReader(docx), int64(len(docx)), &out, nil)
+	if err != nil {
+		t.Fatalf("ConvertToWriter failed: %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("Streamed")) {
+		t.Errorf("Expected streamed output to contain 'Streamed', got: %s", out.String())
+	}
+}
+
+func TestConvertDocument(t *testing.T) {
+	data := createTestDocx(`
+    <w:p><w:r><w:t>Intro text</w:t></w:r></w:p>
+    <w:tbl>
+      <w:tr>
+        <w:tc><w:p><w:r><w:t>Header1</w:t></w:r></w:p></w:tc>
+        <w:tc><w:p><w:r><w:t>Header2</w:t></w:r></w:p></w:tc>
+      </w:tr>
+      <w:tr>
+        <w:tc><w:p><w:r><w:t>Cell1</w:t></w:r></w:p></w:tc>
+        <w:tc><w:p><w:r><w:t>Cell2</w:t></w:r></w:p></w:tc>
+      </w:tr>
+    </w:tbl>`)
+
+	doc, _, err := New().ConvertDocument(data)
+	if err != nil {
+		t.Fatalf("ConvertDocument failed: %v", err)
+	}
+	if doc.Format != "docx" || len(doc.Sections) != 1 {
+		t.Fatalf("Expected a single docx section, got %+v", doc)
+	}
+
+	blocks := doc.Sections[0].Blocks
+	if len(blocks) != 2 {
+		t.Fatalf("Expected paragraph and table, got %d blocks", len(blocks))
+	}
+	if blocks[0].Type != document.BlockParagraph || blocks[0].Text != "Intro text" {
+		t.Errorf("Unexpected paragraph: %+v", blocks[0])
+	}
+	if blocks[1].Type != document.BlockTable || len(blocks[1].Rows) != 2 || !blocks[1].Rows[0].Header {
+		t.Errorf("Unexpected table: %+v", blocks[1])
+	}
+	if blocks[1].Rows[1].Cells[1] != "Cell2" {
+		t.Errorf("Expected Cell2, got %v", blocks[1].Rows[1].Cells)
+	}
+}
+
+func TestConvertDocumentMetadata(t *testing.T) {
+	data := createTestDocx(`<w:p><w:r><w:t>Body</w:t></w:r></w:p>`)
+
+	// Rebuild the archive with a core properties part
+	src, _ := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, file := range src.File {
+		rc, _ := file.Open()
+		f, _ := w.Create(file.Name)
+		_, _ = io.Copy(f, rc)
+		_ = rc.Close()
+	}
+	f, _ := w.Create("docProps/core.xml")
+	_, _ = f.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+ xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+  <dc:title>Quarterly Report</dc:title>
+  <dc:creator>Jane Doe</dc:creator>
+  <dcterms:created>2024-01-02T03:04:05Z</dcterms:created>
+</cp:coreProperties>`))
+	_ = w.Close()
+
+	doc, _, err := New().ConvertDocument(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ConvertDocument failed: %v", err)
+	}
+	meta := doc.Metadata
+	if meta == nil || meta.Title != "Quarterly Report" || meta.Author != "Jane Doe" || meta.Created != "2024-01-02T03:04:05Z" {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+}
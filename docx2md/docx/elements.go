@@ -364,3 +364,16 @@ type Footer struct {
 	Paragraphs []Paragraph `xml:"p"`
 	Tables     []Table     `xml:"tbl"`
 }
+
+// CoreProperties holds document properties (docProps/core.xml)
+type CoreProperties struct {
+	XMLName        xml.Name `xml:"coreProperties"`
+	Title          string   `xml:"title"`
+	Subject        string   `xml:"subject"`
+	Creator        string   `xml:"creator"`
+	Keywords       string   `xml:"keywords"`
+	Description    string   `xml:"description"`
+	LastModifiedBy string   `xml:"lastModifiedBy"`
+	Created        string   `xml:"created"`
+	Modified       string   `xml:"modified"`
+}
@@ -2,11 +2,14 @@ package docx
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 
+	"github.com/tenebris-tech/x2md/document"
 	"github.com/tenebris-tech/x2md/pdf2md/models"
 )
 
@@ -29,6 +32,12 @@ type Extractor struct {
 	// Footnote/endnote tracking (IDs in order of appearance)
 	footnoteRefs []string
 	endnoteRefs  []string
+
+	// ctx aborts body parsing when cancelled (nil means never)
+	ctx context.Context
+
+	// onWarning receives recoverable problems (nil to ignore)
+	onWarning func(document.Warning)
 }
 
 // NewExtractor creates a new document extractor
@@ -69,6 +78,30 @@ func NewExtractor(parser *Parser) (*Extractor, error) {
 	}, nil
 }
 
+// SetContext sets a context that aborts extraction when cancelled
+func (e *Extractor) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// SetWarningHandler sets a function that receives problems the extractor
+// works around, such as images missing from the archive
+func (e *Extractor) SetWarningHandler(fn func(document.Warning)) {
+	e.onWarning = fn
+}
+
+// warn reports a recoverable problem to the warning handler
+func (e *Extractor) warn(code, format string, args ...any) {
+	if e.onWarning != nil {
+		e.onWarning(document.Warning{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// LimitErr returns the error of the first part that exceeded the parser's
+// limits, if any
+func (e *Extractor) LimitErr() error {
+	return e.parser.LimitErr()
+}
+
 // Extract converts the DOCX document to Page format
 func (e *Extractor) Extract() (*models.Page, []*models.ImageItem, error) {
 	// Read raw document XML for custom parsing
@@ -76,6 +109,9 @@ func (e *Extractor) Extract() (*models.Page, []*models.ImageItem, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := checkXMLDepth(docData, e.parser.Limits().MaxXMLDepth); err != nil {
+		return nil, nil, err
+	}
 
 	// Parse document body content
 	items, err := e.parseDocumentBody(docData)
@@ -101,6 +137,12 @@ func (e *Extractor) parseDocumentBody(data []byte) ([]interface{}, error) {
 	var depth int
 
 	for {
+		if e.ctx != nil {
+			if err := e.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
 		tok, err := decoder.Token()
 		if err == io.EOF {
 			break
@@ -240,11 +282,8 @@ func (e *Extractor) parseParagraphElement(decoder *xml.Decoder) (*models.LineIte
 					if inHyperlink && hyperlinkID != "" {
 						target := e.relationships.GetTarget(hyperlinkID)
 						if target != "" {
-							word.Type = &models.WordType{
-								Name: "LINK",
-							}
-							// Store the URL in a custom field or handle during rendering
-							word.String = fmt.Sprintf("[%s](%s)", text, target)
+							word.Type = models.WordTypeLink
+							word.Link = target
 							word.Format = nil // Links don't need additional formatting
 						}
 					}
@@ -549,6 +588,11 @@ func (e *Extractor) GetRelationships() *Relationships {
 	return e.relationships
 }
 
+// GetCoreProperties returns the document properties
+func (e *Extractor) GetCoreProperties() *CoreProperties {
+	return e.parser.GetCoreProperties()
+}
+
 // mergeConsecutiveFormattedWords merges adjacent words that have the same formatting
 // This fixes issues where DOCX splits formatted text across multiple runs.
 // Per OOXML spec, runs are concatenated directly - spaces only exist if explicitly
@@ -698,6 +742,7 @@ func (e *Extractor) extractAndRegisterImage(relID, altText string) (*models.Word
 	// Get the target path from relationships
 	target := e.relationships.GetTarget(relID)
 	if target == "" {
+		e.warn(document.WarnImageSkipped, "image %s has no target", relID)
 		return nil, nil
 	}
 
@@ -707,10 +752,17 @@ func (e *Extractor) extractAndRegisterImage(relID, altText string) (*models.Word
 		imagePath = "word/" + target
 	}
 
+	if err := document.CheckLimit("image count", len(e.images)+1, e.parser.Limits().MaxImages); err != nil {
+		return nil, err
+	}
+
 	// Read the image data from the DOCX archive
 	data, err := e.parser.ReadFile(imagePath)
+	if errors.Is(err, document.ErrLimitExceeded) {
+		return nil, err
+	}
 	if err != nil {
-		// Image not found, skip silently
+		e.warn(document.WarnImageSkipped, "image %s: %v", relID, err)
 		return nil, nil
 	}
 
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
 // Parser handles DOCX file parsing
@@ -14,6 +16,7 @@ type Parser struct {
 	data      []byte
 	zipReader *zip.Reader
 	files     map[string]*zip.File
+	budget    *document.Budget // Bounds the decompressed size of parts
 
 	// Cached parsed content
 	document      *Document
@@ -38,6 +41,7 @@ func NewParser(data []byte) (*Parser, error) {
 		data:      data,
 		zipReader: zipReader,
 		files:     make(map[string]*zip.File),
+		budget:    document.NewBudget(document.DefaultLimits()),
 	}
 
 	// Index files by name
@@ -48,6 +52,22 @@ func NewParser(data []byte) (*Parser, error) {
 	return p, nil
 }
 
+// SetLimits replaces the default limits on part sizes and XML nesting
+func (p *Parser) SetLimits(limits document.Limits) {
+	p.budget = document.NewBudget(limits)
+}
+
+// Limits returns the limits in effect
+func (p *Parser) Limits() document.Limits {
+	return p.budget.Limits()
+}
+
+// LimitErr returns the error of the first part that exceeded the limits, if
+// any. Optional parts that exceed them are otherwise skipped like broken ones.
+func (p *Parser) LimitErr() error {
+	return p.budget.Err()
+}
+
 // Parse parses the DOCX structure
 func (p *Parser) Parse() error {
 	// Verify this is a valid DOCX file
@@ -217,19 +237,30 @@ func (p *Parser) readXML(filename string, v interface{}) error {
 	}
 	defer func() { _ = rc.Close() }()
 
-	data, err := io.ReadAll(rc)
+	data, err := p.budget.ReadAll(filename, rc)
 	if err != nil {
 		return fmt.Errorf("reading %s: %w", filename, err)
 	}
 
 	// Use custom decoder that handles Word XML namespaces
-	if err := unmarshalWordXML(data, v); err != nil {
+	if err := unmarshalWordXML(data, v, p.Limits().MaxXMLDepth); err != nil {
 		return fmt.Errorf("parsing %s: %w", filename, err)
 	}
 
 	return nil
 }
 
+// GetCoreProperties returns the document properties from docProps/core.xml.
+// It returns an empty CoreProperties if the part is missing or invalid.
+func (p *Parser) GetCoreProperties() *CoreProperties {
+	props := &CoreProperties{}
+	if err := p.readXML("docProps/core.xml", props); err != nil {
+		// Core properties are optional
+		return &CoreProperties{}
+	}
+	return props
+}
+
 // ReadFile reads a raw file from the ZIP archive
 func (p *Parser) ReadFile(filename string) ([]byte, error) {
 	f, ok := p.files[filename]
@@ -243,7 +274,7 @@ func (p *Parser) ReadFile(filename string) ([]byte, error) {
 	}
 	defer func() { _ = rc.Close() }()
 
-	return io.ReadAll(rc)
+	return p.budget.ReadAll(filename, rc)
 }
 
 // ListFiles returns all file paths in the archive
@@ -255,8 +286,9 @@ func (p *Parser) ListFiles() []string {
 	return files
 }
 
-// unmarshalWordXML handles Word's XML with namespaces
-func unmarshalWordXML(data []byte, v interface{}) error {
+// unmarshalWordXML handles Word's XML with namespaces. Elements nested deeper
+// than maxDepth (0 for no limit) fail with ErrLimitExceeded.
+func unmarshalWordXML(data []byte, v interface{}, maxDepth int) error {
 	// Word XML uses namespaces that need to be handled
 	// The main namespace is "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
 	// We'll strip namespaces for simpler parsing
@@ -266,16 +298,17 @@ func unmarshalWordXML(data []byte, v interface{}) error {
 	decoder.Entity = xml.HTMLEntity
 
 	// Create a custom token reader that strips namespace prefixes
-	return decodeWithNamespaceStripping(decoder, v)
+	return decodeWithNamespaceStripping(decoder, v, maxDepth)
 }
 
 // decodeWithNamespaceStripping decodes XML while handling namespaces
-func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}) error {
+func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}, maxDepth int) error {
 	// We need to handle Word XML namespaces properly
 	// The main document elements are in the "w" namespace
 	// We'll use a custom unmarshal approach
 
 	var tokens []xml.Token
+	depth := 0
 	for {
 		tok, err := decoder.Token()
 		if err == io.EOF {
@@ -290,6 +323,10 @@ func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}) error {
 		// xml.Decoder reuses its internal buffer between Token() calls
 		switch t := tok.(type) {
 		case xml.StartElement:
+			depth++
+			if err := document.CheckLimit("XML nesting depth", depth, maxDepth); err != nil {
+				return err
+			}
 			t.Name.Local = stripNamespacePrefix(t.Name.Local)
 			t.Name.Space = ""
 			for i := range t.Attr {
@@ -298,6 +335,7 @@ func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}) error {
 			}
 			tok = t
 		case xml.EndElement:
+			depth--
 			t.Name.Local = stripNamespacePrefix(t.Name.Local)
 			t.Name.Space = ""
 			tok = t
@@ -336,3 +374,30 @@ func stripNamespacePrefix(name string) string {
 	}
 	return name
 }
+
+// checkXMLDepth returns an ErrLimitExceeded error if elements in data are
+// nested deeper than maxDepth (0 for no limit)
+func checkXMLDepth(data []byte, maxDepth int) error {
+	if maxDepth <= 0 {
+		return nil
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	depth := 0
+	for {
+		tok, err := decoder.RawToken()
+		if err != nil {
+			// Malformed XML is reported by the parse proper
+			return nil
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+			if err := document.CheckLimit("XML nesting depth", depth, maxDepth); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
@@ -34,13 +34,11 @@ func NewPipeline(opts *PipelineOptions) *Pipeline {
 		options: opts,
 		transformations: []Transformation{
 			NewGatherBlocks(),
-			NewToTextBlocks(),
-			NewToMarkdown(),
 		},
 	}
 }
 
-// Transform runs the pipeline on a page
+// Transform runs the pipeline on a page, leaving its items as LineItemBlocks
 func (p *Pipeline) Transform(page *models.Page) *models.ParseResult {
 	result := &models.ParseResult{
 		Pages: []*models.Page{page},
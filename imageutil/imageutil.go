@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/tenebris-tech/x2md/document"
 	"github.com/tenebris-tech/x2md/pdf2md/models"
 )
 
@@ -17,13 +18,18 @@ type ImageWriter struct {
 	counter     int    // Counter for generating unique filenames
 }
 
+// ImageDir returns the directory NewImageWriter uses for a markdown output path:
+// output_images/ beside output.md
+func ImageDir(mdOutputPath string) string {
+	baseName := strings.TrimSuffix(filepath.Base(mdOutputPath), filepath.Ext(mdOutputPath))
+	return filepath.Join(filepath.Dir(mdOutputPath), baseName+"_images")
+}
+
 // NewImageWriter creates a new ImageWriter for the given markdown output path.
 // For output.md, it creates and uses output_images/ directory.
 func NewImageWriter(mdOutputPath string) (*ImageWriter, error) {
 	// Generate directory name from markdown output path
-	baseName := strings.TrimSuffix(filepath.Base(mdOutputPath), filepath.Ext(mdOutputPath))
-	dir := filepath.Dir(mdOutputPath)
-	imageDir := filepath.Join(dir, baseName+"_images")
+	imageDir := ImageDir(mdOutputPath)
 
 	// Create the directory
 	if err := os.MkdirAll(imageDir, 0755); err != nil {
@@ -32,7 +38,7 @@ func NewImageWriter(mdOutputPath string) (*ImageWriter, error) {
 
 	return &ImageWriter{
 		OutputDir:   imageDir,
-		RelativeDir: baseName + "_images",
+		RelativeDir: filepath.Base(imageDir),
 		counter:     0,
 	}, nil
 }
@@ -64,6 +70,98 @@ func (w *ImageWriter) WriteImage(img *models.ImageItem) (string, error) {
 	return img.OutputPath, nil
 }
 
+// Sink receives extracted images and decides where they are stored
+type Sink interface {
+	// Put stores an image and returns the link target to use for it in Markdown
+	Put(img *models.ImageItem) (string, error)
+}
+
+// Put implements Sink by writing the image to disk
+func (w *ImageWriter) Put(img *models.ImageItem) (string, error) {
+	return w.WriteImage(img)
+}
+
+// WriteImages writes images beside the markdown output path (see NewImageWriter)
+// and replaces their ![id] placeholders in markdown with links to the written files.
+func WriteImages(mdOutputPath, markdown string, images []*models.ImageItem) (string, error) {
+	writer, err := NewImageWriter(mdOutputPath)
+	if err != nil {
+		return markdown, err
+	}
+	return ResolveImages(markdown, images, writer)
+}
+
+// ResolveImages hands each image to sink and replaces its ![id] placeholder in
+// markdown with a link to the location returned by the sink.
+// Raw pixel data tagged as PNG is wrapped in a PNG container first.
+// Images the sink fails to store keep their placeholder.
+func ResolveImages(markdown string, images []*models.ImageItem, sink Sink) (string, error) {
+	return LinkImages(markdown, images, StoreImages(images, sink)), nil
+}
+
+// StoreImages hands each image to sink and returns the stored locations keyed by image ID.
+// Raw pixel data tagged as PNG is wrapped in a PNG container first.
+// Images the sink fails to store are left out of the result.
+func StoreImages(images []*models.ImageItem, sink Sink) map[string]string {
+	return StoreImagesReport(images, sink, nil)
+}
+
+// StoreImagesReport is like StoreImages but calls onError, if not nil, for
+// each image the sink fails to store
+func StoreImagesReport(images []*models.ImageItem, sink Sink, onError func(img *models.ImageItem, err error)) map[string]string {
+	// Build map of image IDs to output paths
+	imageMap := make(map[string]string)
+
+	for _, img := range images {
+		// For PNG format images that are raw data, wrap in PNG
+		if img.Format == "png" && len(img.Data) > 0 {
+			// Check if it's already PNG (has PNG magic bytes)
+			if len(img.Data) < 8 || img.Data[0] != 0x89 || img.Data[1] != 0x50 {
+				// Wrap raw data in PNG format
+				pngData, err := CreatePNG(img.Data, img.Width, img.Height, 8, "DeviceRGB")
+				if err == nil {
+					img.Data = pngData
+				}
+			}
+		}
+
+		location, err := sink.Put(img)
+		if err != nil {
+			if onError != nil {
+				onError(img, err)
+			}
+			continue
+		}
+		imageMap[img.ID] = location
+	}
+
+	return imageMap
+}
+
+// LinkImages replaces the ![id] placeholder of each image in links with a
+// Markdown image link to its location
+func LinkImages(markdown string, images []*models.ImageItem, links map[string]string) string {
+	// Replace image placeholders in markdown
+	// The placeholder format is ![image_001] (from WordTypeImage.ToText)
+	for id, path := range links {
+		// Find the image in the list to get alt text
+		altText := "image"
+		for _, img := range images {
+			if img.ID == id {
+				if img.AltText != "" {
+					altText = img.AltText
+				}
+				break
+			}
+		}
+		placeholder := fmt.Sprintf("![%s]", id)
+		replacement := fmt.Sprintf("![%s](%s)", document.EscapeLinkText(altText), path)
+		markdown = strings.ReplaceAll(markdown, placeholder, replacement)
+	}
+
+	return markdown
+}
+
 // GenerateFilename creates a unique filename like "image_001.png"
 func (w *ImageWriter) GenerateFilename(format string) string {
 	w.counter++
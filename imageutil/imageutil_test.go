@@ -0,0 +1,114 @@
+package imageutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+)
+
+// recordingSink stores images in memory and fails for IDs in fail
+type recordingSink struct {
+	stored map[string][]byte
+	fail   map[string]bool
+}
+
+func (s *recordingSink) Put(img *models.ImageItem) (string, error) {
+	if s.fail[img.ID] {
+		return "", fmt.Errorf("cannot store %s", img.ID)
+	}
+	s.stored[img.ID] = img.Data
+	return "https://example.com/" + img.ID, nil
+}
+
+func TestResolveImages(t *testing.T) {
+	sink := &recordingSink{stored: map[string][]byte{}, fail: map[string]bool{"image_002": true}}
+	images := []*models.ImageItem{
+		{ID: "image_001", Format: "jpeg", Data: []byte{0xFF, 0xD8}, AltText: "Logo [big]\nversion"},
+		{ID: "image_002", Format: "jpeg", Data: []byte{0xFF, 0xD8}},
+	}
+
+	markdown, err := ResolveImages("![image_001]\n\n![image_002]\n", images, sink)
+	if err != nil {
+		t.Fatalf("ResolveImages failed: %v", err)
+	}
+
+	if !strings.Contains(markdown, `![Logo \[big\] version](https://example.com/image_001)`) {
+		t.Errorf("Expected stored image to be linked, got: %s", markdown)
+	}
+	if !strings.Contains(markdown, "![image_002]\n") {
+		t.Errorf("Expected failed image to keep its placeholder, got: %s", markdown)
+	}
+	if len(sink.stored) != 1 {
+		t.Errorf("Expected 1 stored image, got %d", len(sink.stored))
+	}
+}
+
+func TestResolveImagesWrapsRawPNG(t *testing.T) {
+	sink := &recordingSink{stored: map[string][]byte{}}
+	raw := make([]byte, 2*2*3) // 2x2 RGB pixels
+	images := []*models.ImageItem{{ID: "image_001", Format: "png", Data: raw, Width: 2, Height: 2}}
+
+	if _, err := ResolveImages("![image_001]", images, sink); err != nil {
+		t.Fatalf("ResolveImages failed: %v", err)
+	}
+	if data := sink.stored["image_001"]; len(data) < 8 || string(data[1:4]) != "PNG" {
+		t.Error("Expected raw pixel data to be wrapped in PNG")
+	}
+}
+
+func TestMemorySink(t *testing.T) {
+	sink := NewMemorySink("mem://")
+	first, _ := sink.Put(&models.ImageItem{ID: "image_001", Format: "png", Data: []byte{1}})
+	second, _ := sink.Put(&models.ImageItem{ID: "page_001", Format: "jpeg", Data: []byte{2}, OutputPath: "page_001.jpg"})
+	third, _ := sink.Put(&models.ImageItem{ID: "page_001", Format: "jpeg", Data: []byte{3}, OutputPath: "page_001.jpg"})
+
+	if first != "mem://image_001.png" || second != "mem://page_001.jpg" {
+		t.Errorf("Unexpected links: %s, %s", first, second)
+	}
+	if third == second {
+		t.Error("Expected duplicate filenames to be made unique")
+	}
+	if len(sink.Filenames()) != 3 || sink.Get("page_001.jpg").Data[0] != 2 {
+		t.Error("Expected all images to be retrievable")
+	}
+}
+
+func TestDataURISink(t *testing.T) {
+	link, err := DataURISink{}.Put(&models.ImageItem{Format: "png", Data: []byte("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "data:image/png;base64,YWJj" {
+		t.Errorf("Unexpected data URI: %s", link)
+	}
+}
+
+func TestHashSink(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewHashSink(dir, "https://cdn.example.com/img/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := sink.Put(&models.ImageItem{Format: "jpeg", Data: []byte("same")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := sink.Put(&models.ImageItem{Format: "jpeg", Data: []byte("same")})
+	c, _ := sink.Put(&models.ImageItem{Format: "jpeg", Data: []byte("different")})
+
+	if a != b || a == c {
+		t.Errorf("Expected identical content to share a link: %s %s %s", a, b, c)
+	}
+	if !strings.HasPrefix(a, "https://cdn.example.com/img/") || !strings.HasSuffix(a, ".jpg") {
+		t.Errorf("Unexpected link: %s", a)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 stored files, got %d", len(entries))
+	}
+}
@@ -0,0 +1,168 @@
+package imageutil
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+)
+
+// MemorySink keeps images in memory instead of writing them to disk.
+// Each image is linked as Prefix followed by a filename that is unique within the sink.
+// It is safe for concurrent use.
+type MemorySink struct {
+	Prefix string // Prepended to filenames in Markdown links (e.g. a base URL)
+
+	mu      sync.Mutex
+	images  map[string]*models.ImageItem
+	order   []string
+	counter int
+}
+
+// NewMemorySink creates a MemorySink that links images as prefix + filename
+func NewMemorySink(prefix string) *MemorySink {
+	return &MemorySink{
+		Prefix: prefix,
+		images: make(map[string]*models.ImageItem),
+	}
+}
+
+// Put stores the image and returns its link
+func (s *MemorySink) Put(img *models.ImageItem) (string, error) {
+	if img == nil || len(img.Data) == 0 {
+		return "", fmt.Errorf("invalid image: nil or empty data")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Keep a preset filename (e.g. page_001.jpg) unless it is already taken
+	filename := filepath.Base(img.OutputPath)
+	if img.OutputPath == "" || s.images[filename] != nil {
+		for {
+			s.counter++
+			filename = fmt.Sprintf("image_%03d%s", s.counter, formatToExtension(img.Format))
+			if s.images[filename] == nil {
+				break
+			}
+		}
+	}
+
+	s.images[filename] = img
+	s.order = append(s.order, filename)
+	img.OutputPath = s.Prefix + filename
+	return img.OutputPath, nil
+}
+
+// Filenames returns the stored filenames in the order they were added
+func (s *MemorySink) Filenames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.order...)
+}
+
+// Get returns the image stored under filename, or nil
+func (s *MemorySink) Get(filename string) *models.ImageItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.images[filename]
+}
+
+// DataURISink inlines images into the Markdown as base64 data: URIs,
+// producing a single self-contained file
+type DataURISink struct{}
+
+// Put returns the image encoded as a data: URI
+func (DataURISink) Put(img *models.ImageItem) (string, error) {
+	if img == nil || len(img.Data) == 0 {
+		return "", fmt.Errorf("invalid image: nil or empty data")
+	}
+	return "data:" + MIMEType(img.Format) + ";base64," + base64.StdEncoding.EncodeToString(img.Data), nil
+}
+
+// HashSink writes images to a directory named by the SHA-256 of their content,
+// so identical images across documents are stored once. Links are LinkPrefix
+// followed by the filename, which lets the directory be served from a blob store
+// or CDN. It is safe for concurrent use.
+type HashSink struct {
+	Dir        string // Directory to write images to
+	LinkPrefix string // Prepended to filenames in Markdown links
+}
+
+// NewHashSink creates a HashSink writing to dir, creating it if needed
+func NewHashSink(dir, linkPrefix string) (*HashSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create image directory %s: %w", dir, err)
+	}
+	return &HashSink{Dir: dir, LinkPrefix: linkPrefix}, nil
+}
+
+// Put writes the image if its content is not already stored and returns its link
+func (s *HashSink) Put(img *models.ImageItem) (string, error) {
+	if img == nil || len(img.Data) == 0 {
+		return "", fmt.Errorf("invalid image: nil or empty data")
+	}
+
+	sum := sha256.Sum256(img.Data)
+	filename := hex.EncodeToString(sum[:]) + formatToExtension(img.Format)
+	fullPath := filepath.Join(s.Dir, filename)
+
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		// Write to a temporary file and rename so concurrent writers never expose partial files
+		tmp, err := os.CreateTemp(s.Dir, filename+".tmp*")
+		if err != nil {
+			return "", fmt.Errorf("failed to write image %s: %w", fullPath, err)
+		}
+		_, writeErr := tmp.Write(img.Data)
+		closeErr := tmp.Close()
+		if writeErr == nil {
+			writeErr = closeErr
+		}
+		if writeErr == nil {
+			writeErr = os.Rename(tmp.Name(), fullPath)
+		}
+		if writeErr != nil {
+			_ = os.Remove(tmp.Name())
+			return "", fmt.Errorf("failed to write image %s: %w", fullPath, writeErr)
+		}
+	}
+
+	link := filename
+	if s.LinkPrefix != "" {
+		link = strings.TrimSuffix(s.LinkPrefix, "/") + "/" + filename
+	}
+	img.OutputPath = link
+	return link, nil
+}
+
+// MIMEType converts an image format name to a MIME type
+func MIMEType(format string) string {
+	switch strings.ToLower(format) {
+	case "jpeg", "jpg":
+		return "image/jpeg"
+	case "png":
+		return "image/png"
+	case "gif":
+		return "image/gif"
+	case "bmp":
+		return "image/bmp"
+	case "tiff", "tif":
+		return "image/tiff"
+	case "webp":
+		return "image/webp"
+	case "jp2", "jpeg2000":
+		return "image/jp2"
+	case "emf":
+		return "image/emf"
+	case "wmf":
+		return "image/wmf"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,100 @@
+// Package testutil builds minimal PDF and DOCX documents for tests.
+package testutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PDF writes numbered object bodies as a PDF, with object 1 as the catalog
+// and trailer added to the trailer dictionary
+func PDF(objects []string, trailer string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return buf.Bytes()
+}
+
+// Stream returns the body of a stream object holding data, with entries
+// added to its dictionary before /Length
+func Stream(entries, data string) string {
+	if entries != "" {
+		entries += " "
+	}
+	return fmt.Sprintf("<< %s/Length %d >>\nstream\n%s\nendstream", entries, len(data), data)
+}
+
+// TextPDF builds a PDF with one line of text per page
+func TextPDF(pageTexts ...string) []byte {
+	n := len(pageTexts)
+	kids := make([]string, n)
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", ""}
+	for i, text := range pageTexts {
+		page, contents := len(objects)+1, len(objects)+2
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R /Resources << /Font << /F1 %d 0 R >> >> >>", contents, 3*n+3),
+			Stream("", fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n)
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	return PDF(objects, "")
+}
+
+// Docx builds a minimal DOCX whose document body is content
+func Docx(content string) []byte {
+	return DocxWith(content, nil)
+}
+
+// DocxWith builds a DOCX like Docx with additional parts by name, such as
+// images and word/_rels/document.xml.rels
+func DocxWith(content string, parts map[string][]byte) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+
+	files := map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="xml" ContentType="application/xml"/>
+  <Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`,
+		"_rels/.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`,
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:body>` + content + `</w:body>
+</w:document>`,
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml"} {
+		f, _ := w.Create(name)
+		_, _ = f.Write([]byte(files[name]))
+	}
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, _ := w.Create(name)
+		_, _ = f.Write(parts[name])
+	}
+
+	_ = w.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,266 @@
+// Package mcp implements a Model Context Protocol server that lets LLM agents
+// read local documents through x2md. It speaks JSON-RPC 2.0 over a
+// newline-delimited stream, normally the process's stdin and stdout.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/tenebris-tech/x2md/convert"
+)
+
+// supportedVersions lists the protocol versions the server accepts, newest first
+var supportedVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server answers MCP requests with the x2md tools
+type Server struct {
+	options *Options
+	tools   []*tool
+}
+
+// Options holds configuration for the server
+type Options struct {
+	// Roots limits the documents tools may read to these directories and
+	// their subdirectories. If empty, any readable path is allowed.
+	Roots []string
+
+	// ConverterOptions are applied to every conversion before the tool's options
+	ConverterOptions []convert.Option
+}
+
+// Option is a functional option for configuring the server
+type Option func(*Options)
+
+// WithRoots limits the documents tools may read to the given directories
+func WithRoots(dirs ...string) Option {
+	return func(o *Options) {
+		o.Roots = append(o.Roots, dirs...)
+	}
+}
+
+// WithConverterOptions sets options applied to every conversion
+func WithConverterOptions(opts ...convert.Option) Option {
+	return func(o *Options) {
+		o.ConverterOptions = opts
+	}
+}
+
+// New creates a new Server with the given options
+func New(opts ...Option) *Server {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	s := &Server{options: options}
+	s.tools = s.newTools()
+	return s
+}
+
+// message is a JSON-RPC request, notification or response
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// Serve reads requests from r and writes responses to w, one JSON message per
+// line, until r is exhausted or ctx is cancelled. Requests are handled in order.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	encoder := json.NewEncoder(w)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := s.handle(ctx, line); resp != nil {
+				if err := encoder.Encode(resp); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle processes one message and returns the response, or nil for notifications
+func (s *Server) handle(ctx context.Context, line []byte) *message {
+	var req message
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &message{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error: " + err.Error()}}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID == nil {
+			return nil // Stray response or malformed notification
+		}
+		return &message{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{codeInvalidRequest, "invalid request"}}
+	}
+
+	result, err := s.dispatch(ctx, req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	var rerr *rpcError
+	switch {
+	case errors.As(err, &rerr):
+		resp.Error = rerr
+	case err != nil:
+		resp.Error = &rpcError{codeInvalidParams, err.Error()}
+	case result == nil:
+		resp.Result = map[string]any{}
+	default:
+		resp.Result = result
+	}
+	return resp
+}
+
+// dispatch runs a method and returns its result
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, err
+			}
+		}
+		version := supportedVersions[0]
+		for _, v := range supportedVersions {
+			if v == p.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "x2md", "version": convert.Version},
+			"instructions":    "Converts local PDF, DOCX and XLSX documents to Markdown for reading.",
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		list := make([]map[string]any, len(s.tools))
+		for i, t := range s.tools {
+			list[i] = map[string]any{
+				"name":        t.name,
+				"description": t.description,
+				"inputSchema": t.schema,
+			}
+		}
+		return map[string]any{"tools": list}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return s.callTool(ctx, p.Name, p.Arguments)
+	}
+
+	if strings.HasPrefix(method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{codeMethodNotFound, "method not found: " + method}
+}
+
+// callTool runs a tool. Tool failures are reported in the result, as the
+// protocol requires, so the agent can see and react to them.
+func (s *Server) callTool(ctx context.Context, name string, args json.RawMessage) (any, error) {
+	for _, t := range s.tools {
+		if t.name != name {
+			continue
+		}
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		content, err := t.run(ctx, args)
+		if err != nil {
+			return map[string]any{
+				"content": []map[string]any{textContent(err.Error())},
+				"isError": true,
+			}, nil
+		}
+		return map[string]any{"content": content}, nil
+	}
+	return nil, &rpcError{codeInvalidParams, "unknown tool: " + name}
+}
+
+// resolvePath makes path absolute and checks that it lies within the roots
+func (s *Server) resolvePath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if len(s.options.Roots) == 0 {
+		return abs, nil
+	}
+
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	for _, root := range s.options.Roots {
+		realRoot, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if r, err := filepath.EvalSymlinks(realRoot); err == nil {
+			realRoot = r
+		}
+		if rel, err := filepath.Rel(realRoot, real); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return real, nil
+		}
+	}
+	return "", fmt.Errorf("%s is outside the allowed directories", path)
+}
+
+// textContent returns a text content item
+func textContent(text string) map[string]any {
+	return map[string]any{"type": "text", "text": text}
+}
@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/internal/testutil"
+)
+
+// response is a decoded JSON-RPC response
+type response struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// toolResult is a decoded tools/call result
+type toolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// exchange sends requests to s and returns its responses
+func exchange(t *testing.T, s *Server, requests ...string) []response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var responses []response
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r response
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		responses = append(responses, r)
+	}
+	return responses
+}
+
+// callTool builds a tools/call request
+func callTool(id int, name string, args map[string]any) string {
+	data, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	return string(data)
+}
+
+func TestProtocol(t *testing.T) {
+	responses := exchange(t, New(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"unknown"}`,
+		`not json`,
+	)
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses (none for the notification), got %d", len(responses))
+	}
+
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(responses[0].Result, &init)
+	if init.ProtocolVersion != "2024-11-05" {
+		t.Errorf("Expected negotiated version 2024-11-05, got %q", init.ProtocolVersion)
+	}
+
+	var list struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	_ = json.Unmarshal(responses[1].Result, &list)
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "convert_document,get_pages,list_sheets,document_info" {
+		t.Errorf("Unexpected tools: %s", got)
+	}
+
+	if responses[2].Error == nil || responses[2].Error.Code != codeMethodNotFound {
+		t.Errorf("Expected method not found error, got %+v", responses[2])
+	}
+	if responses[3].Error == nil || responses[3].Error.Code != codeParseError {
+		t.Errorf("Expected parse error, got %+v", responses[3])
+	}
+}
+
+func TestTools(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.docx")
+	if err := os.WriteFile(path, testutil.Docx(`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Hello world</w:t></w:r></w:p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := exchange(t, New(WithRoots(dir)),
+		callTool(1, "convert_document", map[string]any{"path": path}),
+		callTool(2, "convert_document", map[string]any{"path": path, "preserve_formatting": false}),
+		callTool(3, "document_info", map[string]any{"path": path}),
+		callTool(4, "list_sheets", map[string]any{"path": path}),
+		callTool(5, "convert_document", map[string]any{"path": filepath.Join(dir, "..", "outside.docx")}),
+	)
+
+	results := make([]toolResult, len(responses))
+	for i, r := range responses {
+		if err := json.Unmarshal(r.Result, &results[i]); err != nil {
+			t.Fatalf("Invalid result %d: %v", i, err)
+		}
+	}
+
+	if results[0].IsError || !strings.Contains(results[0].Content[0].Text, "**Hello world**") {
+		t.Errorf("Unexpected convert_document result: %+v", results[0])
+	}
+	if strings.Contains(results[1].Content[0].Text, "**") {
+		t.Errorf("Expected plain text, got %q", results[1].Content[0].Text)
+	}
+	if !strings.Contains(results[2].Content[0].Text, `"format": "docx"`) {
+		t.Errorf("Unexpected document_info result: %s", results[2].Content[0].Text)
+	}
+	if !results[3].IsError {
+		t.Error("Expected list_sheets to fail for a DOCX")
+	}
+	if !results[4].IsError || !strings.Contains(results[4].Content[0].Text, "outside") {
+		t.Errorf("Expected path outside the roots to be refused, got %+v", results[4])
+	}
+}
+
+func TestDocumentInfoImages(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "image.docx")
+	data := testutil.DocxWith(`<w:p><w:r><w:drawing><wp:docPr xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" name="Logo"/>`+
+		`<a:blip xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:embed="rId5"/>`+
+		`</w:drawing></w:r></w:p>`,
+		map[string][]byte{
+			"word/_rels/document.xml.rels": []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1.png"/>
+</Relationships>`),
+			"word/media/image1.png": img.Bytes(),
+		})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := exchange(t, New(WithRoots(dir)), callTool(1, "document_info", map[string]any{"path": path}))
+	var result toolResult
+	if err := json.Unmarshal(responses[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || !strings.Contains(result.Content[0].Text, `"images": 1`) {
+		t.Errorf("Expected one image, got %+v", result)
+	}
+}
@@ -0,0 +1,309 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tenebris-tech/x2md/convert"
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/imageutil"
+	"github.com/tenebris-tech/x2md/server"
+	"github.com/tenebris-tech/x2md/xlsx2md/xlsx"
+)
+
+// tool is a callable MCP tool
+type tool struct {
+	name        string
+	description string
+	schema      map[string]any
+	run         func(ctx context.Context, args json.RawMessage) ([]map[string]any, error)
+}
+
+// toolArgs are the arguments shared by the tools. Conversion options, pages
+// included, use the names of the HTTP server's options.
+type toolArgs struct {
+	Path string `json:"path"`
+	server.Request
+}
+
+// optionProperties describes the conversion options in tool input schemas
+var optionProperties = map[string]any{
+	"images":              boolProperty("Return extracted images as image content (default: false)"),
+	"preserve_formatting": boolProperty("Keep bold and italic formatting (default: true)"),
+	"strip": map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string", "enum": []string{"headers_footers", "page_numbers", "toc", "footnotes", "blank_pages"}},
+		"description": "PDF content to remove (default: headers_footers, blank_pages)",
+	},
+	"detect_lists":        boolProperty("Detect lists in PDFs (default: true)"),
+	"detect_headings":     boolProperty("Detect headings in PDFs (default: true)"),
+	"scan_mode":           boolProperty("Extract page images from scanned PDFs (default: true)"),
+	"show_formulas":       boolProperty("Show XLSX formulas alongside values (default: true)"),
+	"include_sheet_names": boolProperty("Title each XLSX sheet with its name (default: true)"),
+	"include_hidden":      boolProperty("Include hidden XLSX rows and columns (default: true)"),
+}
+
+// newTools returns the tools offered by the server
+func (s *Server) newTools() []*tool {
+	pathProperty := map[string]any{"type": "string", "description": "Path of a local PDF, DOCX or XLSX file"}
+
+	convertProps := map[string]any{
+		"path":   pathProperty,
+		"format": map[string]any{"type": "string", "enum": []string{"pdf", "docx", "xlsx"}, "description": "Document format; detected from the content if omitted"},
+		"output": map[string]any{"type": "string", "enum": []string{"markdown", "json"}, "description": "Markdown (default) or the structured document as JSON"},
+	}
+	pagesProps := map[string]any{
+		"path":  pathProperty,
+		"pages": map[string]any{"type": "string", "description": `Page range, e.g. "1-3,7" or "10-"`},
+	}
+	for name, prop := range optionProperties {
+		convertProps[name] = prop
+		pagesProps[name] = prop
+	}
+
+	return []*tool{
+		{
+			name:        "convert_document",
+			description: "Convert a local PDF, DOCX or XLSX document to Markdown (or structured JSON) for reading.",
+			schema:      objectSchema(convertProps, "path"),
+			run:         s.convertDocument,
+		},
+		{
+			name:        "get_pages",
+			description: "Convert selected pages of a local PDF to Markdown, for reading long documents in parts.",
+			schema:      objectSchema(pagesProps, "path", "pages"),
+			run:         s.getPages,
+		},
+		{
+			name:        "list_sheets",
+			description: "List the sheets of a local XLSX workbook with their used row and column counts.",
+			schema:      objectSchema(map[string]any{"path": pathProperty}, "path"),
+			run:         s.listSheets,
+		},
+		{
+			name:        "document_info",
+			description: "Describe a local document: format, size, metadata such as title and author, and page, sheet and image counts.",
+			schema:      objectSchema(map[string]any{"path": pathProperty}, "path"),
+			run:         s.documentInfo,
+		},
+	}
+}
+
+// parseArgs decodes tool arguments and resolves the document path
+func (s *Server) parseArgs(raw json.RawMessage) (*toolArgs, string, error) {
+	var args toolArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	path, err := s.resolvePath(args.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	return &args, path, nil
+}
+
+// converter builds a converter for the tool arguments. Images are left out
+// unless requested, since they are costly for agents to read.
+func (s *Server) converter(args *toolArgs) (*convert.Converter, error) {
+	if args.Images == nil {
+		images := false
+		args.Images = &images
+	}
+	opts, err := args.ConverterOptions()
+	if err != nil {
+		return nil, err
+	}
+	return convert.New(append(append([]convert.Option(nil), s.options.ConverterOptions...), opts...)...), nil
+}
+
+// convertDocument implements the convert_document tool
+func (s *Server) convertDocument(ctx context.Context, raw json.RawMessage) ([]map[string]any, error) {
+	args, path, err := s.parseArgs(raw)
+	if err != nil {
+		return nil, err
+	}
+	if args.Output == server.OutputZip {
+		return nil, fmt.Errorf("output must be markdown or json")
+	}
+	c, err := s.converter(args)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := imageutil.NewMemorySink("")
+	var buf bytes.Buffer
+	reader := bytes.NewReader(data)
+	if args.Format != "" {
+		err = c.ConvertFormatToWriter(ctx, args.Format, reader, int64(len(data)), &buf, sink)
+	} else {
+		err = c.ConvertToWriter(ctx, reader, int64(len(data)), &buf, sink)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content := []map[string]any{textContent(buf.String())}
+	return append(content, imageContent(sink)...), nil
+}
+
+// getPages implements the get_pages tool. Only the selected pages are extracted.
+func (s *Server) getPages(ctx context.Context, raw json.RawMessage) ([]map[string]any, error) {
+	args, path, err := s.parseArgs(raw)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Pages) == "" {
+		return nil, fmt.Errorf("pages is required")
+	}
+	c, err := s.converter(args)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, images, err := c.ConvertFileToDocument(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Format != "pdf" {
+		return nil, fmt.Errorf("get_pages supports PDF documents only; use convert_document for %s", doc.Format)
+	}
+
+	sink := imageutil.NewMemorySink("")
+	doc.SetImageLinks(imageutil.StoreImages(images, sink))
+	markdown := doc.Markdown(&document.MarkdownOptions{
+		SectionSeparator: "---",
+		PlainText:        args.PreserveFormatting != nil && !*args.PreserveFormatting,
+	})
+	content := []map[string]any{textContent(markdown)}
+	return append(content, imageContent(sink)...), nil
+}
+
+// listSheets implements the list_sheets tool
+func (s *Server) listSheets(ctx context.Context, raw json.RawMessage) ([]map[string]any, error) {
+	_, path, err := s.parseArgs(raw)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if convert.DetectFormat(data) != ".xlsx" {
+		return nil, fmt.Errorf("%s is not an XLSX workbook", path)
+	}
+
+	workbook, err := xlsx.ParseContext(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	type sheetInfo struct {
+		Name string `json:"name"`
+		Rows int    `json:"rows"`
+		Cols int    `json:"cols"`
+	}
+	sheets := make([]sheetInfo, 0, len(workbook.Sheets))
+	for _, sheet := range workbook.Sheets {
+		cols := 0
+		if sheet.MaxCol > 0 {
+			cols = sheet.MaxCol - sheet.MinCol + 1
+		}
+		sheets = append(sheets, sheetInfo{Name: sheet.Name, Rows: sheet.MaxRow, Cols: cols})
+	}
+	return jsonContent(map[string]any{"sheets": sheets})
+}
+
+// documentInfo implements the document_info tool
+func (s *Server) documentInfo(ctx context.Context, raw json.RawMessage) ([]map[string]any, error) {
+	args, path, err := s.parseArgs(raw)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// Images are extracted so that they are counted
+	images := true
+	args.Images = &images
+	c, err := s.converter(args)
+	if err != nil {
+		return nil, err
+	}
+	doc, extracted, err := c.ConvertFileToDocument(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := map[string]any{
+		"path":     path,
+		"format":   doc.Format,
+		"size":     stat.Size(),
+		"metadata": doc.Metadata,
+		"images":   len(extracted),
+		"notes":    len(doc.Notes),
+	}
+	switch doc.Format {
+	case "pdf":
+		if doc.Metadata != nil {
+			info["pages"] = doc.Metadata.Pages
+		}
+	case "xlsx":
+		var names []string
+		for _, section := range doc.Sections {
+			names = append(names, section.Name)
+		}
+		info["sheets"] = names
+	}
+	return jsonContent(info)
+}
+
+// imageContent returns the images in sink as image content items, in the
+// order they were stored. Markdown links refer to them by filename.
+func imageContent(sink *imageutil.MemorySink) []map[string]any {
+	var content []map[string]any
+	for _, name := range sink.Filenames() {
+		img := sink.Get(name)
+		content = append(content, map[string]any{
+			"type":     "image",
+			"data":     base64.StdEncoding.EncodeToString(img.Data),
+			"mimeType": imageutil.MIMEType(img.Format),
+		})
+	}
+	return content
+}
+
+// jsonContent returns v as indented JSON text content
+func jsonContent(v any) ([]map[string]any, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]any{textContent(string(data))}, nil
+}
+
+// objectSchema returns a JSON schema for an object with the given properties
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	sort.Strings(required)
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// boolProperty returns a JSON schema for a boolean option
+func boolProperty(description string) map[string]any {
+	return map[string]any{"type": "boolean", "description": description}
+}
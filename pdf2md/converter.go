@@ -2,10 +2,16 @@
 package pdf2md
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"sort"
 	"strings"
 
+	"github.com/tenebris-tech/x2md/document"
 	"github.com/tenebris-tech/x2md/imageutil"
 	"github.com/tenebris-tech/x2md/pdf2md/models"
 	"github.com/tenebris-tech/x2md/pdf2md/pdf"
@@ -68,11 +74,45 @@ type Options struct {
 	// PageSeparator is the separator between pages
 	PageSeparator string
 
+	// Password opens encrypted documents. It is tried as the user password
+	// and then as the owner password.
+	Password string
+
+	// Pages restricts conversion to a page range such as "1-5,10,20-"
+	// (default: all pages). See ParsePageRange.
+	Pages string
+
+	// OutlineTOC adds a table of contents built from the document outline
+	// (bookmarks) before the first page
+	OutlineTOC bool
+
+	// FormFields adds the fields of interactive forms and their values in a
+	// table after the last page
+	FormFields bool
+
+	// StructureTree converts tagged PDFs by their structure tree, taking
+	// headings, lists and tables from the tags instead of the page layout
+	StructureTree bool
+
+	// ImageSink stores extracted images when converting to a file.
+	// If nil, images are written to a <name>_images directory beside the output.
+	ImageSink imageutil.Sink
+
 	// Callbacks for conversion progress
 	OnPageParsed         func(pageNum, totalPages int)
 	OnFontParsed         func(fontName string)
 	OnConversionComplete func()
 	OnPageSkipped        func(pageNum int, reason string)
+
+	// OnWarning receives problems that did not stop the conversion, such as
+	// undecodable images or fonts without ToUnicode maps
+	OnWarning func(w document.Warning)
+
+	// Logger, if set, logs each warning at warn level
+	Logger *slog.Logger
+
+	// Limits bounds the work done for one document (default: document.DefaultLimits)
+	Limits document.Limits
 }
 
 // ShouldStrip checks if a given StripOption is enabled
@@ -98,7 +138,9 @@ func DefaultOptions() *Options {
 		PreserveFormatting: true,
 		ExtractImages:      true,
 		ScanMode:           true, // Auto-detect scanned pages by default
+		FormFields:         true,
 		PageSeparator:      "\n",
+		Limits:             document.DefaultLimits(),
 	}
 }
 
@@ -146,6 +188,68 @@ func WithOnFontParsed(callback func(fontName string)) Option {
 	}
 }
 
+// WithOnWarning sets the callback for conversion warnings
+func WithOnWarning(callback func(w document.Warning)) Option {
+	return func(o *Options) {
+		o.OnWarning = callback
+	}
+}
+
+// WithLogger sets a logger for conversion warnings
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithLimits sets the limits for hostile input, such as the largest
+// decompressed stream and the most pages
+func WithLimits(limits document.Limits) Option {
+	return func(o *Options) {
+		o.Limits = limits
+	}
+}
+
+// WithPassword sets the password for encrypted documents. Documents it does
+// not open fail with document.ErrWrongPassword.
+func WithPassword(password string) Option {
+	return func(o *Options) {
+		o.Password = password
+	}
+}
+
+// WithPages restricts conversion to a page range such as "1-5,10,20-".
+// Only the selected pages are extracted, including their images.
+func WithPages(spec string) Option {
+	return func(o *Options) {
+		o.Pages = spec
+	}
+}
+
+// WithOutlineTOC sets whether to add a table of contents built from the
+// document outline. Documents without an outline get none.
+func WithOutlineTOC(enabled bool) Option {
+	return func(o *Options) {
+		o.OutlineTOC = enabled
+	}
+}
+
+// WithFormFields sets whether to add the fields of interactive (AcroForm or
+// XFA) forms and their values, whose answers are not part of the page text
+func WithFormFields(enabled bool) Option {
+	return func(o *Options) {
+		o.FormFields = enabled
+	}
+}
+
+// WithStructureTree sets whether to convert tagged PDFs by their structure
+// tree. Untagged documents are converted from the page layout as usual.
+func WithStructureTree(enabled bool) Option {
+	return func(o *Options) {
+		o.StructureTree = enabled
+	}
+}
+
 // WithExtractImages sets whether to extract images
 func WithExtractImages(extract bool) Option {
 	return func(o *Options) {
@@ -170,6 +274,14 @@ func WithCompact(compact bool) Option {
 	}
 }
 
+// WithImageSink sets where extracted images are stored by ConvertFileToFile.
+// Use imageutil.MemorySink, imageutil.DataURISink, imageutil.HashSink or a custom Sink.
+func WithImageSink(sink imageutil.Sink) Option {
+	return func(o *Options) {
+		o.ImageSink = sink
+	}
+}
+
 // New creates a new Converter with the given options
 func New(opts ...Option) *Converter {
 	options := DefaultOptions()
@@ -194,6 +306,12 @@ func (c *Converter) ConvertFile(inputPath string) (string, error) {
 
 // ConvertFileToFile converts a PDF file and writes the result to a file
 func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
+	return c.ConvertFileToFileContext(context.Background(), inputPath, outputPath)
+}
+
+// ConvertFileToFileContext is like ConvertFileToFile but stops when ctx is cancelled.
+// Nothing is written if the conversion is cancelled.
+func (c *Converter) ConvertFileToFileContext(ctx context.Context, inputPath, outputPath string) error {
 	// Read input file
 	data, err := os.ReadFile(inputPath)
 	if err != nil {
@@ -201,7 +319,7 @@ func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
 	}
 
 	// Convert to markdown and get images
-	markdown, images, err := c.ConvertWithImages(data)
+	markdown, images, err := c.ConvertWithImagesContext(ctx, data)
 	if err != nil {
 		return err
 	}
@@ -217,56 +335,64 @@ func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
 	return os.WriteFile(outputPath, []byte(markdown), 0644)
 }
 
-// writeImages writes images to disk and updates markdown with correct paths
+// writeImages stores images in the configured sink (or beside outputPath) and
+// updates markdown with their locations
 func (c *Converter) writeImages(outputPath, markdown string, images []*models.ImageItem) (string, error) {
-	writer, err := imageutil.NewImageWriter(outputPath)
-	if err != nil {
-		return markdown, err
+	sink := c.options.ImageSink
+	if sink == nil {
+		writer, err := imageutil.NewImageWriter(outputPath)
+		if err != nil {
+			return markdown, err
+		}
+		sink = writer
 	}
+	return c.resolveImages(markdown, images, sink), nil
+}
 
-	// Build map of image IDs to output paths
-	imageMap := make(map[string]string)
+// resolveImages stores images in sink and links them in markdown. Images the
+// sink fails to store keep their placeholder and are reported as warnings.
+func (c *Converter) resolveImages(markdown string, images []*models.ImageItem, sink imageutil.Sink) string {
+	diag := document.NewDiagnostics(c.options.OnWarning, c.options.Logger)
+	links := imageutil.StoreImagesReport(images, sink, func(img *models.ImageItem, err error) {
+		diag.Warnf(document.WarnImageNotStored, img.PageIndex+1, "image %s: %v", img.ID, err)
+	})
+	return imageutil.LinkImages(markdown, images, links)
+}
 
-	for _, img := range images {
-		// For PNG format images that are raw data, wrap in PNG
-		if img.Format == "png" && len(img.Data) > 0 {
-			// Check if it's already PNG (has PNG magic bytes)
-			if len(img.Data) < 8 || img.Data[0] != 0x89 || img.Data[1] != 0x50 {
-				// Wrap raw data in PNG format
-				pngData, err := imageutil.CreatePNG(img.Data, img.Width, img.Height, 8, "DeviceRGB")
-				if err == nil {
-					img.Data = pngData
-				}
-			}
-		}
+// ConvertReader converts a PDF read from r to Markdown and returns extracted images.
+// The document is read fully into memory, as PDF parsing needs random access.
+func (c *Converter) ConvertReader(r io.ReaderAt, size int64) (string, []*models.ImageItem, error) {
+	return c.ConvertReaderContext(context.Background(), r, size)
+}
 
-		relativePath, err := writer.WriteImage(img)
-		if err != nil {
-			// Log warning but continue
-			continue
-		}
-		imageMap[img.ID] = relativePath
+// ConvertReaderContext is like ConvertReader but stops when ctx is cancelled
+func (c *Converter) ConvertReaderContext(ctx context.Context, r io.ReaderAt, size int64) (string, []*models.ImageItem, error) {
+	if err := document.CheckLimit("input size", size, c.options.Limits.MaxInputBytes); err != nil {
+		return "", nil, err
 	}
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return "", nil, fmt.Errorf("reading input: %w", err)
+	}
+	return c.ConvertWithImagesContext(ctx, data)
+}
 
-	// Replace image placeholders in markdown
-	// The placeholder format is ![image_001] (from WordTypeImage.ToText)
-	for id, path := range imageMap {
-		// Find the image in the list to get alt text
-		altText := "image"
-		for _, img := range images {
-			if img.ID == id {
-				if img.AltText != "" {
-					altText = img.AltText
-				}
-				break
-			}
-		}
-		placeholder := fmt.Sprintf("![%s]", id)
-		replacement := fmt.Sprintf("![%s](%s)", altText, path)
-		markdown = strings.ReplaceAll(markdown, placeholder, replacement)
+// ConvertToWriter converts a PDF read from r and writes the Markdown to w.
+// Images are handed to sink and linked using the locations it returns. If sink
+// is nil or image extraction is disabled, images are dropped and their ![id]
+// placeholders are left in the Markdown.
+func (c *Converter) ConvertToWriter(ctx context.Context, r io.ReaderAt, size int64, w io.Writer, sink imageutil.Sink) error {
+	markdown, images, err := c.ConvertReaderContext(ctx, r, size)
+	if err != nil {
+		return err
 	}
 
-	return markdown, nil
+	if sink != nil && c.options.ExtractImages && len(images) > 0 {
+		markdown = c.resolveImages(markdown, images, sink)
+	}
+
+	_, err = io.WriteString(w, markdown)
+	return err
 }
 
 // Convert converts PDF data to Markdown
@@ -275,39 +401,238 @@ func (c *Converter) Convert(data []byte) (string, error) {
 	return markdown, err
 }
 
+// ConvertContext is like Convert but stops when ctx is cancelled
+func (c *Converter) ConvertContext(ctx context.Context, data []byte) (string, error) {
+	markdown, _, err := c.ConvertWithImagesContext(ctx, data)
+	return markdown, err
+}
+
 // ConvertWithImages converts PDF data to Markdown and returns extracted images
 func (c *Converter) ConvertWithImages(data []byte) (string, []*models.ImageItem, error) {
-	// Parse PDF
+	return c.ConvertWithImagesContext(context.Background(), data)
+}
+
+// ConvertWithImagesContext is like ConvertWithImages but stops when ctx is cancelled.
+// Cancellation is checked between pages and while tokenizing content streams;
+// the returned error is ctx.Err().
+//
+// Documents that need a password fail with document.ErrEncrypted, and
+// documents that yield neither text nor images with document.ErrNoTextContent.
+func (c *Converter) ConvertWithImagesContext(ctx context.Context, data []byte) (string, []*models.ImageItem, error) {
+	doc, images, err := c.ConvertDocumentContext(ctx, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Check for empty output
+	markdown := doc.Markdown(&document.MarkdownOptions{
+		SectionSeparator: c.options.PageSeparator,
+		PlainText:        !c.options.PreserveFormatting,
+		MergeTables:      true,
+	})
+	if strings.TrimSpace(markdown) == "" && len(images) == 0 {
+		return "", nil, fmt.Errorf("%w could be extracted from this PDF (%d pages); it may be a scan without a text layer (OCR required) or use unsupported fonts",
+			document.ErrNoTextContent, doc.Metadata.Pages)
+	}
+
+	// Apply compact formatting if enabled
+	if c.options.Compact {
+		markdown = compactMarkdown(markdown)
+	}
+
+	return markdown, images, nil
+}
+
+// ConvertDocument converts PDF data to a structured document and returns extracted images.
+// Each page becomes a section; images are placed at the end of the page they appear on.
+func (c *Converter) ConvertDocument(data []byte) (*document.Document, []*models.ImageItem, error) {
+	return c.ConvertDocumentContext(context.Background(), data)
+}
+
+// ConvertDocumentContext is like ConvertDocument but stops when ctx is cancelled
+func (c *Converter) ConvertDocumentContext(ctx context.Context, data []byte) (*document.Document, []*models.ImageItem, error) {
+	ex, err := c.extract(ctx, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipelineOpts := c.pipelineOptions()
+	pipelineOpts.SamplePages = ex.samplePages
+	pipelineOpts.Outline = ex.outline
+	pipelineOpts.Structure = ex.structure
+	pipeline := transform.NewPipeline(ex.fonts, pipelineOpts)
+	result := pipeline.Transform(ex.pages)
+
+	doc := &document.Document{
+		Format:   "pdf",
+		Metadata: infoMetadata(ex.info, ex.pageCount),
+		Warnings: ex.diag.Warnings(),
+	}
+	doc.Metadata.PageRange = ex.pageRange
+	sections := make(map[int]*document.Section)
+	for _, page := range result.Pages {
+		section := doc.AddSection(page.Index+1, "")
+		if ex.linkedPages[page.Index] {
+			section.Anchor = pageAnchor(page.Index)
+		}
+		sections[page.Index] = section
+		for _, item := range page.Items {
+			if block, ok := item.(*models.LineItemBlock); ok {
+				section.Blocks = append(section.Blocks, models.BlockToDocument(block)...)
+			}
+		}
+	}
+
+	images := append(ex.scannedImages, ex.images...)
+	for _, img := range images {
+		section := sections[img.PageIndex]
+		if section == nil {
+			section = doc.AddSection(img.PageIndex+1, "")
+			sections[img.PageIndex] = section
+		}
+		section.Blocks = append(section.Blocks, &document.Block{Type: document.BlockImage, Image: img.ID})
+		doc.Images = append(doc.Images, models.ImageToDocument(img, true))
+	}
+	if c.options.OutlineTOC {
+		c.addOutlineTOC(doc, ex)
+	}
+	sort.SliceStable(doc.Sections, func(i, j int) bool {
+		return doc.Sections[i].Page < doc.Sections[j].Page
+	})
+	if c.options.FormFields {
+		addFormFields(doc, ex)
+	}
+
+	if c.options.OnConversionComplete != nil {
+		c.options.OnConversionComplete()
+	}
+
+	return doc, images, nil
+}
+
+// Errors returned by extract for encrypted documents without and with a password
+var (
+	errEncrypted     = document.MarkError(document.ErrEncrypted, errors.New("PDF document is encrypted and requires a password"))
+	errWrongPassword = document.MarkError(document.ErrEncrypted, document.MarkError(document.ErrWrongPassword,
+		errors.New("PDF document is encrypted and the password is incorrect")))
+)
+
+// extraction holds the content extracted from a PDF, ready for the transformation pipeline
+type extraction struct {
+	pageCount     int
+	pageRange     string // Canonical range of the selected pages, if any
+	pages         []*models.Page
+	outline       []pdf.OutlineItem
+	structure     []*pdf.StructElement
+	linkedPages   map[int]bool // Destination pages of internal links
+	formFields    []pdf.FormField
+	samplePages   []*models.Page      // Unselected pages for the global statistics
+	images        []*models.ImageItem // Images embedded in text pages
+	scannedImages []*models.ImageItem // Page images for scanned pages
+	fonts         map[string]*pdf.Font
+	info          map[string]string // Document information dictionary
+	diag          *document.Diagnostics
+}
+
+// extract parses the PDF and extracts the text items and images of each page
+func (c *Converter) extract(ctx context.Context, data []byte) (*extraction, error) {
+	// Collect warnings, attributing those from the parser to the page being extracted
+	diag := document.NewDiagnostics(c.options.OnWarning, c.options.Logger)
+	currentPage := 0
+	limits := c.options.Limits
+	if err := document.CheckLimit("input size", int64(len(data)), limits.MaxInputBytes); err != nil {
+		return nil, err
+	}
 	parser := pdf.NewParser(data)
+	parser.SetLimits(limits)
+	parser.SetPassword(c.options.Password)
+	parser.SetWarningHandler(func(w document.Warning) {
+		if currentPage < 0 {
+			return // Sampling an unselected page
+		}
+		if w.Page == 0 {
+			w.Page = currentPage
+		}
+		diag.Warn(w)
+	})
+
+	// Parse PDF
 	if err := parser.Parse(); err != nil {
-		return "", nil, fmt.Errorf("parsing PDF: %w", err)
+		if limitErr := parser.LimitErr(); limitErr != nil {
+			return nil, limitErr
+		}
+		return nil, document.MarkError(document.ErrCorrupt, fmt.Errorf("parsing PDF: %w", err))
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Check for encryption
 	if parser.IsEncrypted() {
-		msg := "# Conversion Failed\n\n" +
-			"This PDF document is encrypted and requires a password to access its contents.\n\n" +
-			"The document could not be converted to Markdown.\n"
-		return msg, nil, nil
+		if c.options.Password != "" {
+			return nil, errWrongPassword
+		}
+		return nil, errEncrypted
 	}
 
 	// Get page count
 	pageCount, err := parser.GetPageCount()
 	if err != nil {
-		return "", nil, fmt.Errorf("getting page count: %w", err)
+		return nil, document.MarkError(document.ErrCorrupt, fmt.Errorf("getting page count: %w", err))
+	}
+
+	// Select pages
+	var selected map[int]bool
+	pageRange := ""
+	selectedCount := pageCount
+	if c.options.Pages != "" {
+		if selected, err = ParsePageRange(c.options.Pages, pageCount); err != nil {
+			return nil, err
+		}
+		pageRange = formatPageRange(selected)
+		selectedCount = len(selected)
+	}
+	if err := document.CheckLimit("page count", selectedCount, limits.MaxPages); err != nil {
+		return nil, err
+	}
+
+	// Streams that exceed the limits are skipped like broken ones, so check
+	// the limits between pages to fail the document instead
+	var allImages, scannedPageImages []*models.ImageItem
+	checkLimits := func() error {
+		if err := parser.LimitErr(); err != nil {
+			return err
+		}
+		return document.CheckLimit("image count", len(allImages)+len(scannedPageImages), limits.MaxImages)
 	}
 
 	// Extract text from each page
 	extractor := pdf.NewTextExtractor(parser)
+	extractor.SetContext(ctx)
 	var pages []*models.Page
-	var allImages []*models.ImageItem
-	var scannedPageImages []*models.ImageItem // Page images for scanned pages
+	linkedPages := make(map[int]bool)
+	imageMCIDs := make(map[*models.ImageItem]int) // Marked content painting each image
 	imageCounter := 0
 
 	for i := 0; i < pageCount; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if selected != nil && !selected[i+1] {
+			continue
+		}
+		if err := checkLimits(); err != nil {
+			return nil, err
+		}
+		currentPage = i + 1
+
 		textItems, err := extractor.ExtractPage(i)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
 			// Skip pages that fail to extract, notify via callback
+			diag.Warnf(document.WarnPageFailed, i+1, "page left out: %v", err)
 			if c.options.OnPageSkipped != nil {
 				c.options.OnPageSkipped(i+1, err.Error())
 			}
@@ -315,13 +640,19 @@ func (c *Converter) ConvertWithImages(data []byte) (string, []*models.ImageItem,
 		}
 
 		// Get page dimensions
-		pageWidth, pageHeight, _ := extractor.GetPageDimensions(i)
+		pageWidth, pageHeight, err := extractor.GetPageDimensions(i)
+		if err != nil {
+			diag.Warnf(document.WarnPageSize, i+1, "reading page size: %v", err)
+		}
 
 		// Get page images
 		var pageImages []*pdf.ImageData
 		var imageNames []string
 		if c.options.ExtractImages || c.options.ScanMode {
-			pageImages, imageNames, _ = parser.GetAllPageImages(i)
+			pageImages, imageNames, err = parser.GetAllPageImages(i)
+			if err != nil {
+				diag.Warnf(document.WarnImageSkipped, i+1, "reading page images: %v", err)
+			}
 		}
 
 		// Check if this page is a scan (ScanMode enabled)
@@ -367,16 +698,15 @@ func (c *Converter) ConvertWithImages(data []byte) (string, []*models.ImageItem,
 		}
 
 		// Convert pdf.TextItem to models.TextItem
-		var items []interface{}
-		for _, ti := range textItems {
-			items = append(items, &models.TextItem{
-				X:      ti.X,
-				Y:      ti.Y,
-				Width:  ti.Width,
-				Height: ti.Height,
-				Text:   ti.Text,
-				Font:   ti.Font,
-			})
+		items := textItemsToModels(textItems)
+		if links, err := parser.GetPageLinks(i); err == nil && pageHeight > 0 {
+			links = renderableLinks(links, selected)
+			items = linkItems(items, links, pageHeight)
+			for _, link := range links {
+				if link.URI == "" {
+					linkedPages[link.Page] = true
+				}
+			}
 		}
 
 		// Extract images from this page if enabled (non-scanned pages)
@@ -398,6 +728,14 @@ func (c *Converter) ConvertWithImages(data []byte) (string, []*models.ImageItem,
 					Width:      imgData.Width,
 					Height:     imgData.Height,
 				}
+				if mark, ok := extractor.ImageMarks()[imgName]; ok {
+					if mark.Alt != "" {
+						img.AltText = mark.Alt
+					}
+					if mark.MCID >= 0 {
+						imageMCIDs[img] = mark.MCID
+					}
+				}
 				allImages = append(allImages, img)
 			}
 		}
@@ -414,6 +752,46 @@ func (c *Converter) ConvertWithImages(data []byte) (string, []*models.ImageItem,
 		}
 	}
 
+	// Sample unselected pages so the statistics reflect the whole document.
+	// Their problems are not the selection's, so their warnings are dropped.
+	var sampled []*models.Page
+	if selected != nil {
+		currentPage = -1
+		for _, i := range samplePages(pageCount, selected, statsSamplePages-selectedCount) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if textItems, err := extractor.ExtractPage(i); err == nil {
+				sampled = append(sampled, &models.Page{Index: i, Items: textItemsToModels(textItems)})
+			}
+		}
+	}
+
+	currentPage = 0
+	var formFields []pdf.FormField
+	if c.options.FormFields {
+		formFields = parser.GetFormFields()
+	}
+
+	// Use the structure tree if it tags any of the text, and give images
+	// the alternate descriptions of the figures they belong to
+	var structure []*pdf.StructElement
+	if c.options.StructureTree {
+		if structure = parser.GetStructTree(); hasTaggedText(structure, pages) {
+			alts := figureAlts(structure, nil)
+			for img, mcid := range imageMCIDs {
+				if alt := alts[contentRef{page: img.PageIndex, mcid: mcid}]; alt != "" {
+					img.AltText = alt
+				}
+			}
+		} else {
+			structure = nil
+		}
+	}
+	if err := checkLimits(); err != nil {
+		return nil, err
+	}
+
 	// Get fonts for formatting detection
 	fonts := extractor.GetFonts()
 	if c.options.OnFontParsed != nil {
@@ -422,102 +800,110 @@ func (c *Converter) ConvertWithImages(data []byte) (string, []*models.ImageItem,
 		}
 	}
 
-	// Run transformation pipeline
-	pipelineOpts := &transform.PipelineOptions{
-		StripHeadersFooters: c.options.ShouldStrip(HeadersFooters),
-		StripPageNumbers:    c.options.ShouldStrip(PageNumbers),
-		StripTOC:            c.options.ShouldStrip(TOC),
-		StripFootnotes:      c.options.ShouldStrip(Footnotes),
-		StripBlankPages:     c.options.ShouldStrip(BlankPages),
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	pipeline := transform.NewPipeline(fonts, pipelineOpts)
-	result := pipeline.Transform(pages)
 
-	// Combine page outputs
-	var output strings.Builder
+	return &extraction{
+		pageCount:     pageCount,
+		pageRange:     pageRange,
+		pages:         pages,
+		outline:       parser.GetOutline(),
+		linkedPages:   linkedPages,
+		formFields:    formFields,
+		structure:     structure,
+		samplePages:   sampled,
+		images:        allImages,
+		scannedImages: scannedPageImages,
+		fonts:         fonts,
+		info:          parser.GetInfo(),
+		diag:          diag,
+	}, nil
 
-	// Handle scanned vs non-scanned pages
-	if len(scannedPageImages) > 0 && len(scannedPageImages) == pageCount {
-		// All pages are scanned - just output image references
-		for i, img := range scannedPageImages {
-			output.WriteString(fmt.Sprintf("![%s]\n\n", img.ID))
-			if i < len(scannedPageImages)-1 {
-				output.WriteString(c.options.PageSeparator)
-			}
-		}
-	} else if len(scannedPageImages) > 0 {
-		// Mixed document - some scanned, some text
-		// Output scanned page images in order, interleaved with text content
-		scannedPageIdx := 0
-		for pageIdx := 0; pageIdx < pageCount; pageIdx++ {
-			// Check if this page was scanned
-			if scannedPageIdx < len(scannedPageImages) &&
-				scannedPageImages[scannedPageIdx].PageIndex == pageIdx {
-				// This is a scanned page
-				img := scannedPageImages[scannedPageIdx]
-				output.WriteString(fmt.Sprintf("![%s]\n\n", img.ID))
-				scannedPageIdx++
-			}
-			// Text content from pipeline will be at the end
-		}
-		// Add pipeline text output (for non-scanned pages)
-		for _, page := range result.Pages {
-			for _, item := range page.Items {
-				if text, ok := item.(string); ok {
-					output.WriteString(text)
-				}
-			}
-		}
-	} else {
-		// No scanned pages - normal text output
-		for i, page := range result.Pages {
-			for _, item := range page.Items {
-				if text, ok := item.(string); ok {
-					output.WriteString(text)
-				}
-			}
-			if i < len(result.Pages)-1 {
-				output.WriteString(c.options.PageSeparator)
-			}
-		}
-	}
+}
 
-	// Combine all images (scanned pages + regular images)
-	allImages = append(scannedPageImages, allImages...)
+// addOutlineTOC adds a section listing the outline entries that point to
+// the converted pages, nested by depth. It adds nothing if the pages have no
+// content, so that documents without text are still reported as such.
+func (c *Converter) addOutlineTOC(doc *document.Document, ex *extraction) {
+	hasContent := false
+	for _, section := range doc.Sections {
+		hasContent = hasContent || len(section.Blocks) > 0
+	}
+	if !hasContent {
+		return
+	}
 
-	// Add image references at the end if there are regular images
-	// (scanned page images are already referenced inline)
-	if len(allImages) > len(scannedPageImages) {
-		output.WriteString("\n\n## Images\n\n")
-		for _, img := range allImages[len(scannedPageImages):] {
-			// Write placeholder that will be replaced with actual path
-			output.WriteString(fmt.Sprintf("![%s]\n\n", img.ID))
+	converted := make(map[int]bool, len(ex.pages))
+	for _, page := range ex.pages {
+		converted[page.Index] = true
+	}
+	var entries []pdf.OutlineItem
+	minLevel := 0
+	for _, entry := range ex.outline {
+		if entry.Title == "" || (ex.pageRange != "" && !converted[entry.Page]) {
+			continue
 		}
+		entries = append(entries, entry)
+		if minLevel == 0 || entry.Level < minLevel {
+			minLevel = entry.Level
+		}
+	}
+	if len(entries) == 0 {
+		return
 	}
 
-	if c.options.OnConversionComplete != nil {
-		c.options.OnConversionComplete()
+	var toc strings.Builder
+	for _, entry := range entries {
+		toc.WriteString(strings.Repeat("  ", entry.Level-minLevel) + "- " + entry.Title + "\n")
 	}
+	section := doc.AddSection(0, "outline")
+	section.Title = "Contents"
+	section.Blocks = append(section.Blocks, &document.Block{Type: document.BlockTOC, Text: toc.String()})
+}
 
-	// Check for empty output
-	markdown := output.String()
-	if strings.TrimSpace(markdown) == "" && len(allImages) == 0 {
-		msg := "# Conversion Failed\n\n" +
-			"No text content could be extracted from this PDF document.\n\n" +
-			"Possible reasons:\n" +
-			"- The PDF contains only scanned images without a text layer (OCR required)\n" +
-			"- The PDF uses an unsupported text encoding or font structure\n" +
-			"- The PDF content streams could not be parsed\n\n" +
-			fmt.Sprintf("Document info: %d pages\n", pageCount)
-		return msg, nil, nil
+// textItemsToModels converts extracted text items to pipeline items
+func textItemsToModels(textItems []pdf.TextItem) []interface{} {
+	var items []interface{}
+	for _, ti := range textItems {
+		items = append(items, &models.TextItem{
+			X:        ti.X,
+			Y:        ti.Y,
+			Width:    ti.Width,
+			Height:   ti.Height,
+			Text:     ti.Text,
+			Font:     ti.Font,
+			MCID:     ti.MCID,
+			Artifact: ti.Artifact,
+		})
 	}
+	return items
+}
 
-	// Apply compact formatting if enabled
-	if c.options.Compact {
-		markdown = compactMarkdown(markdown)
+// infoMetadata converts the document information dictionary to document metadata
+func infoMetadata(info map[string]string, pageCount int) *document.Metadata {
+	return &document.Metadata{
+		Title:    info["Title"],
+		Author:   info["Author"],
+		Subject:  info["Subject"],
+		Keywords: info["Keywords"],
+		Creator:  info["Creator"],
+		Producer: info["Producer"],
+		Created:  info["CreationDate"],
+		Modified: info["ModDate"],
+		Pages:    pageCount,
 	}
+}
 
-	return markdown, allImages, nil
+// pipelineOptions returns the transformation pipeline options for the converter options
+func (c *Converter) pipelineOptions() *transform.PipelineOptions {
+	return &transform.PipelineOptions{
+		StripHeadersFooters: c.options.ShouldStrip(HeadersFooters),
+		StripPageNumbers:    c.options.ShouldStrip(PageNumbers),
+		StripTOC:            c.options.ShouldStrip(TOC),
+		StripFootnotes:      c.options.ShouldStrip(Footnotes),
+		StripBlankPages:     c.options.ShouldStrip(BlankPages),
+	}
 }
 
 // isScannedPage determines if a page is likely a scanned image.
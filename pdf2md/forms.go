@@ -0,0 +1,60 @@
+package pdf2md
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
+)
+
+// addFormFields adds a section after the last page with a table of the form
+// fields on the converted pages, ordered by page and then top to bottom and
+// left to right. Fields whose page is unknown, such as those of XFA forms,
+// come last, and are left out when only some pages are converted.
+func addFormFields(doc *document.Document, ex *extraction) {
+	converted := make(map[int]bool, len(ex.pages))
+	for _, page := range ex.pages {
+		converted[page.Index] = true
+	}
+	var fields []pdf.FormField
+	for _, field := range ex.formFields {
+		if converted[field.Page] || (field.Page < 0 && ex.pageRange == "") {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		switch {
+		case a.Page != b.Page:
+			return a.Page >= 0 && (b.Page < 0 || a.Page < b.Page)
+		case a.Rect[3] != b.Rect[3]:
+			return a.Rect[3] > b.Rect[3] // PDF coordinates grow upwards
+		default:
+			return a.Rect[0] < b.Rect[0]
+		}
+	})
+
+	table := &document.Block{Type: document.BlockTable, Rows: []*document.Row{
+		{Header: true, Cells: []string{"Field", "Type", "Value", "Page"}},
+	}}
+	for _, field := range fields {
+		name := field.Label
+		if name == "" {
+			name = field.Name
+		}
+		page := ""
+		if field.Page >= 0 {
+			page = strconv.Itoa(field.Page + 1)
+		}
+		table.Rows = append(table.Rows, &document.Row{Cells: []string{name, field.Type, field.Value, page}})
+	}
+
+	section := doc.AddSection(0, "form")
+	section.Title = "Form Fields"
+	section.Blocks = append(section.Blocks, table)
+}
@@ -0,0 +1,99 @@
+package pdf2md
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/internal/testutil"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
+)
+
+// buildFormPDF builds a two-page PDF with the given AcroForm dictionary
+// followed by extra objects numbered from 8
+func buildFormPDF(acroForm string, extra ...string) []byte {
+	text := "BT /F1 12 Tf 72 740 Td (Application form) Tj ET"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm " + acroForm + " >>",
+		"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 7 0 R >> >> /Annots [8 0 R 9 0 R] >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(text), text),
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 6 0 R /Resources << /Font << /F1 7 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(text), text),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	return testutil.PDF(append(objects, extra...), "")
+}
+
+func TestFormFields(t *testing.T) {
+	data := buildFormPDF("<< /Fields [10 0 R 9 0 R 11 0 R 14 0 R 15 0 R 16 0 R] >>",
+		// 8: the widget of the name field, whose parent holds its type and value
+		"<< /Type /Annot /Subtype /Widget /Parent 12 0 R /Rect [100 700 300 720] >>",
+		// 9: a checkbox that is its own widget, found through the page's annotations
+		"<< /Type /Annot /Subtype /Widget /T (married) /FT /Btn /V /Yes /Rect [100 650 112 662] >>",
+		// 10: a field holding the applicant's fields
+		"<< /T (applicant) /Kids [12 0 R] >>",
+		// 11: a radio button group with a widget on page 2
+		"<< /T (payment) /FT /Btn /Ff 32768 /V /Card /Kids [13 0 R] >>",
+		"<< /T (name) /TU (Applicant name) /FT /Tx /V (Jane Doe) /Parent 10 0 R /Kids [8 0 R] >>",
+		"<< /Type /Annot /Subtype /Widget /Parent 11 0 R /P 5 0 R /Rect [100 700 112 712] /AS /Card >>",
+		// 14: a choice with export and display values
+		"<< /T (color) /FT /Ch /V (r) /Opt [[(r) (Red)] [(g) (Green)]] /P 5 0 R /Rect [100 600 200 620] >>",
+		// 15: an unsigned signature field and 16: a push button
+		"<< /T (signature) /FT /Sig /P 5 0 R /Rect [100 500 300 540] >>",
+		"<< /T (submit) /FT /Btn /Ff 65536 /P 5 0 R /Rect [100 400 200 420] >>",
+	)
+
+	parser := pdf.NewParser(data)
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var got []string
+	for _, f := range parser.GetFormFields() {
+		got = append(got, fmt.Sprintf("%s|%s|%s|%s|%d", f.Name, f.Label, f.Type, f.Value, f.Page))
+	}
+	want := "applicant.name|Applicant name|text|Jane Doe|0," +
+		"married||checkbox|Yes|0," +
+		"payment||radio|Card|1," +
+		"color||choice|Red|1," +
+		"signature||signature||1"
+	if strings.Join(got, ",") != want {
+		t.Errorf("GetFormFields() = %v, want %s", got, want)
+	}
+
+	markdown, err := New(WithStrip()).Convert(data)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !strings.Contains(markdown, "## Form Fields\n\n| Field | Type | Value | Page |\n| --- | --- | --- | --- |\n"+
+		"| Applicant name | text | Jane Doe | 1 |\n| married | checkbox | Yes | 1 |\n") {
+		t.Errorf("Expected a form fields table, got:\n%s", markdown)
+	}
+
+	markdown, err = New(WithStrip(), WithFormFields(false)).Convert(data)
+	if err != nil || strings.Contains(markdown, "Form Fields") {
+		t.Errorf("Expected no form fields table, got %q (%v)", markdown, err)
+	}
+}
+
+func TestXFAFormFields(t *testing.T) {
+	xdp := `<xdp:xdp xmlns:xdp="http://ns.adobe.com/xdp/"><xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/">` +
+		`<xfa:data><form1><name>Jane Doe</name><address><city>Paris</city></address></form1></xfa:data></xfa:datasets></xdp:xdp>`
+	data := buildFormPDF("<< /Fields [] /XFA [(datasets) 10 0 R] >>",
+		"<< /Type /Annot /Subtype /Text /Rect [0 0 1 1] >>",
+		"<< /Type /Annot /Subtype /Text /Rect [0 0 1 1] >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(xdp), xdp),
+	)
+
+	parser := pdf.NewParser(data)
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var got []string
+	for _, f := range parser.GetFormFields() {
+		got = append(got, fmt.Sprintf("%s=%s@%d", f.Name, f.Value, f.Page))
+	}
+	if want := "name=Jane Doe@-1,address.city=Paris@-1"; strings.Join(got, ",") != want {
+		t.Errorf("GetFormFields() = %v, want %s", got, want)
+	}
+}
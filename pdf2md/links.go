@@ -0,0 +1,130 @@
+package pdf2md
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
+)
+
+// pageAnchor returns the anchor that internal links to a page point to
+func pageAnchor(pageIndex int) string {
+	return fmt.Sprintf("page-%d", pageIndex+1)
+}
+
+// linkTarget returns the Markdown target of a link: its URI, or the anchor
+// of its destination page
+func linkTarget(link pdf.Link) string {
+	if link.URI != "" {
+		return link.URI
+	}
+	return "#" + pageAnchor(link.Page)
+}
+
+// renderableLinks returns the links that can be rendered: those to an http,
+// https or mailto URL, and those to a page being converted. selected holds
+// the 1-based numbers of the converted pages, or is nil if all are.
+func renderableLinks(links []pdf.Link, selected map[int]bool) []pdf.Link {
+	var result []pdf.Link
+	for _, link := range links {
+		switch {
+		case link.URI != "":
+			if !document.IsAllowedLink(link.URI) || strings.HasPrefix(link.URI, "#") {
+				continue
+			}
+		case link.Page < 0 || (selected != nil && !selected[link.Page+1]):
+			continue
+		}
+		result = append(result, link)
+	}
+	return result
+}
+
+// linkItems sets the Link of the text items covered by the page's link
+// annotations. pageHeight turns the annotation rectangles into the items'
+// top-down coordinates.
+func linkItems(items []interface{}, links []pdf.Link, pageHeight float64) []interface{} {
+	if len(links) == 0 {
+		return items
+	}
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if textItem, ok := item.(*models.TextItem); ok {
+			result = append(result, linkItem(textItem, links, pageHeight)...)
+		} else {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// linkItem links item to the first of links that covers it. An item only
+// partly covered is split at the word boundaries nearest the edges of the
+// link, assuming evenly wide characters, and its other parts are matched
+// against the remaining links.
+func linkItem(item *models.TextItem, links []pdf.Link, pageHeight float64) []interface{} {
+	// The middle of the glyphs, above the baseline
+	middle := item.Y - item.Height/3
+	for i, link := range links {
+		if middle < pageHeight-link.Rect[3] || middle > pageHeight-link.Rect[1] {
+			continue
+		}
+		x1, x2 := max(item.X, link.Rect[0]), min(item.X+item.Width, link.Rect[2])
+		if x2 <= x1 && !(item.Width <= 0 && item.X >= link.Rect[0] && item.X <= link.Rect[2]) {
+			continue
+		}
+
+		runes := []rune(item.Text)
+		n := len(runes)
+		start, end := 0, n
+		if item.Width > 0 {
+			start = wordBoundary(runes, int(math.Round((x1-item.X)/item.Width*float64(n))))
+			end = wordBoundary(runes, int(math.Round((x2-item.X)/item.Width*float64(n))))
+		}
+		if start >= end || strings.TrimSpace(string(runes[start:end])) == "" {
+			continue
+		}
+
+		var result []interface{}
+		for j, part := range [][2]int{{0, start}, {start, end}, {end, n}} {
+			if part[0] == part[1] {
+				continue
+			}
+			piece := *item
+			piece.Text = string(runes[part[0]:part[1]])
+			piece.X = item.X + item.Width*float64(part[0])/float64(n)
+			piece.Width = item.Width * float64(part[1]-part[0]) / float64(n)
+			if j == 1 {
+				piece.Link = linkTarget(link)
+				result = append(result, &piece)
+			} else {
+				result = append(result, linkItem(&piece, links[i+1:], pageHeight)...)
+			}
+		}
+		return result
+	}
+	return []interface{}{item}
+}
+
+// wordBoundary returns the boundary between a word and whitespace, or the
+// end of the text, nearest to the rune index i
+func wordBoundary(runes []rune, i int) int {
+	n := len(runes)
+	i = min(max(i, 0), n)
+	isBoundary := func(k int) bool {
+		return k == 0 || k == n || unicode.IsSpace(runes[k-1]) != unicode.IsSpace(runes[k])
+	}
+	for d := 0; d <= n; d++ {
+		if i-d >= 0 && isBoundary(i-d) {
+			return i - d
+		}
+		if i+d <= n && isBoundary(i+d) {
+			return i + d
+		}
+	}
+	return i
+}
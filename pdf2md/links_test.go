@@ -0,0 +1,78 @@
+package pdf2md
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/internal/testutil"
+)
+
+// buildLinkedPDF builds a two-page PDF with a web link and a link to the
+// second page on the first
+func buildLinkedPDF() []byte {
+	// Page 1 has a URI link over "click here", which starts 7 characters of
+	// 6 points into the line, and a GoTo link over the whole second line
+	page1 := "BT /F1 12 Tf 72 720 Td (Please click here for details.) Tj 0 -30 Td (Go to the next page) Tj ET"
+	page2 := "BT /F1 12 Tf 72 720 Td (The second page.) Tj ET"
+	font := "/Resources << /Font << /F1 7 0 R >> >>"
+	return testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R " + font +
+			" /Annots [8 0 R << /Type /Annot /Subtype /Link /Rect [70 686 300 702] /Dest [5 0 R /Fit] >>] >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page1), page1),
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 6 0 R " + font + " >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page2), page2),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Annot /Subtype /Link /Rect [113 718 175 732] /A << /S /URI /URI (https://example.com/) >> >>",
+	}, "")
+}
+
+func TestLinkAnnotations(t *testing.T) {
+	markdown, err := New(WithStrip()).Convert(buildLinkedPDF())
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	for _, want := range []string{
+		"Please [click here](https://example.com/) for details.",
+		"[Go to the next page](#page-2)",
+		`<a id="page-2"></a>`,
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in output:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, `<a id="page-1">`) {
+		t.Errorf("Expected no anchor for a page without incoming links:\n%s", markdown)
+	}
+}
+
+func TestLinksLeftOut(t *testing.T) {
+	// A link to a page outside the selection has no anchor to point to
+	markdown, err := New(WithStrip(), WithPages("1")).Convert(buildLinkedPDF())
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if strings.Contains(markdown, "#page-2") || !strings.Contains(markdown, "Go to the next page") {
+		t.Errorf("Expected the link to an unselected page as plain text:\n%s", markdown)
+	}
+
+	// Links to other schemes than http, https and mailto are not rendered
+	content := "BT /F1 12 Tf 72 720 Td (Run the script now) Tj ET"
+	data := testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> " +
+			"/Annots [<< /Type /Annot /Subtype /Link /Rect [70 716 300 732] /A << /S /URI /URI (javascript:alert\\(1\\)) >> >>] >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}, "")
+	markdown, err = New(WithStrip()).Convert(data)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if strings.Contains(markdown, "javascript") || !strings.Contains(markdown, "Run the script now") {
+		t.Errorf("Expected the javascript link as plain text:\n%s", markdown)
+	}
+}
@@ -0,0 +1,42 @@
+package pdf2md
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/internal/testutil"
+)
+
+func TestMarkedContentReplacements(t *testing.T) {
+	// The ligature glyph X stands for "fi", and a discretionary hyphen has an
+	// empty actual text given in a named property list
+	content := "BT /F1 12 Tf 72 720 Td (The ) Tj /Span << /ActualText (fi) >> BDC (X) Tj EMC (nal caf) Tj " +
+		"/Span << /ActualText <FEFF00E9> >> BDC [(e)] TJ EMC ( menu) Tj ET\n" +
+		"BT /F1 12 Tf 72 706 Td (It is encyclo) Tj /Span /Hyphen BDC (-) Tj EMC (pedic.) Tj ET\n" +
+		"/Figure << /Alt (Sales chart) >> BDC q 100 0 0 100 72 400 cm /Im1 Do Q EMC"
+	image := "\xff\xd8\xff\xd9"
+	data := testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R >> /Properties << /Hyphen << /ActualText () >> >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /BitsPerComponent 8 "+
+			"/ColorSpace /DeviceGray /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream", len(image), image),
+	}, "")
+
+	markdown, images, err := New(WithStrip()).ConvertWithImages(data)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	for _, want := range []string{"The final café menu", "It is encyclopedic."} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in output:\n%s", want, markdown)
+		}
+	}
+	if len(images) != 1 || images[0].AltText != "Sales chart" {
+		t.Errorf("Expected an image with the figure's alternate description, got %v", images)
+	}
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"strings"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
 // BlockType represents a markdown block type
@@ -211,7 +213,9 @@ func LinesToText(lineItems []*LineItem, disableInlineFormats bool) string {
 				text.WriteString(openFormat.StartSymbol)
 			}
 
-			if word.Type != nil && (!disableInlineFormats || word.Type.PlainTextFormat) {
+			if word.Link != "" && !disableInlineFormats {
+				text.WriteString(document.MarkdownLink(word.String, word.Link))
+			} else if word.Type != nil && (!disableInlineFormats || word.Type.PlainTextFormat) {
 				text.WriteString(word.Type.ToText(word.String))
 			} else {
 				text.WriteString(word.String)
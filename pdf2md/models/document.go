@@ -0,0 +1,241 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tenebris-tech/x2md/document"
+)
+
+// listMarkerPattern matches the bullet or number at the start of a list item
+var listMarkerPattern = regexp.MustCompile(`^(?:[-*•]|\d+[.)]|[a-zA-Z][.)]|[ivxlcdmIVXLCDM]+[.)])(?:\s+|$)`)
+
+// BlockToDocument converts a block to structured document blocks.
+// Lists yield one block per item; images embedded in lines yield image blocks
+// following the text they appear in.
+func BlockToDocument(block *LineItemBlock) []*document.Block {
+	if len(block.Items) == 0 {
+		return nil
+	}
+
+	if block.Items[0].IsTableRow {
+		if table := linesToTable(block.Items); table != nil {
+			return []*document.Block{table}
+		}
+		return nil
+	}
+
+	switch {
+	case IsHeadline(block.Type):
+		inlines, images := linesToInlines(block.Items, " ")
+		return appendBlock(nil, &document.Block{
+			Type:    document.BlockHeading,
+			Level:   block.Type.HeadlineLevel,
+			Inlines: inlines,
+		}, images)
+	case block.Type == BlockTypeList:
+		var blocks []*document.Block
+		for _, line := range block.Items {
+			inlines, images := linesToInlines([]*LineItem{line}, " ")
+			marker, inlines := splitListMarker(inlines)
+			blocks = appendBlock(blocks, &document.Block{
+				Type:    document.BlockListItem,
+				Level:   line.ListLevel,
+				Marker:  marker,
+				Inlines: inlines,
+			}, images)
+		}
+		return blocks
+	case block.Type == BlockTypeCode:
+		inlines, images := linesToInlines(block.Items, "\n")
+		return appendBlock(nil, &document.Block{Type: document.BlockCode, Text: document.PlainText(inlines)}, images)
+	case block.Type == BlockTypeTOC:
+		inlines, images := linesToInlines(block.Items, "\n")
+		return appendBlock(nil, &document.Block{Type: document.BlockTOC, Text: document.PlainText(inlines)}, images)
+	default:
+		inlines, images := linesToInlines(block.Items, " ")
+		return appendBlock(nil, &document.Block{Type: document.BlockParagraph, Inlines: inlines}, images)
+	}
+}
+
+// ImageToDocument describes an extracted image for a structured document
+func ImageToDocument(img *ImageItem, pages bool) *document.Image {
+	di := &document.Image{
+		ID:     img.ID,
+		Alt:    img.AltText,
+		Format: img.Format,
+		Width:  img.Width,
+		Height: img.Height,
+	}
+	if pages {
+		di.Page = img.PageIndex + 1
+	}
+	return di
+}
+
+// appendBlock appends block unless it has no text, followed by an image block per image ID.
+// The block's Text is set from its inlines if it has any.
+func appendBlock(blocks []*document.Block, block *document.Block, images []string) []*document.Block {
+	if len(block.Inlines) > 0 {
+		block.Text = document.PlainText(block.Inlines)
+	}
+	if strings.TrimSpace(block.Text) != "" || block.Marker != "" {
+		blocks = append(blocks, block)
+	}
+	for _, id := range images {
+		blocks = append(blocks, &document.Block{Type: document.BlockImage, Image: id})
+	}
+	return blocks
+}
+
+// linesToTable converts table rows to a table block, skipping empty rows
+func linesToTable(lines []*LineItem) *document.Block {
+	table := &document.Block{Type: document.BlockTable}
+	for _, line := range lines {
+		hasContent := false
+		cells := make([]string, len(line.TableColumns))
+		for i, col := range line.TableColumns {
+			cells[i] = strings.TrimSpace(col)
+			if cells[i] != "" {
+				hasContent = true
+			}
+		}
+		if !hasContent {
+			continue
+		}
+		table.Rows = append(table.Rows, &document.Row{Header: line.IsTableHeader, Cells: cells})
+	}
+	if len(table.Rows) == 0 {
+		return nil
+	}
+	return table
+}
+
+// linesToInlines converts the words of lines to inline runs, using sep between
+// lines. Consecutive words with the same formatting share a run, as do
+// consecutive words of a link, and words hyphenated across a line break are
+// rejoined. Image words are left out and their IDs returned separately.
+func linesToInlines(lines []*LineItem, sep string) ([]*document.Inline, []string) {
+	var inlines []*document.Inline
+	var images []string
+
+	// last returns the current text run, if any
+	last := func() *document.Inline {
+		if len(inlines) == 0 || inlines[len(inlines)-1].Type != document.InlineText {
+			return nil
+		}
+		return inlines[len(inlines)-1]
+	}
+
+	for _, line := range lines {
+		first := true
+		for _, word := range line.Words {
+			if word.Type == WordTypeImage {
+				images = append(images, word.String)
+				continue
+			}
+
+			// Work out the whitespace before this word
+			space := ""
+			switch {
+			case len(inlines) == 0:
+			case first && sep == " ":
+				if run := last(); run != nil && strings.HasSuffix(run.Text, "-") && !strings.HasSuffix(run.Text, " -") && len(run.Text) > 1 {
+					// Rejoin a word broken by a hyphen at the end of the previous line
+					run.Text = strings.TrimSuffix(run.Text, "-")
+				} else {
+					space = " "
+				}
+			case first:
+				space = sep
+			case (word.Type == nil || !word.Type.AttachWithoutWhitespace) && !isPunctuation(word.String):
+				space = " "
+			}
+			first = false
+
+			bold := word.Format == WordFormatBold || word.Format == WordFormatBoldOblique
+			italic := word.Format == WordFormatOblique || word.Format == WordFormatBoldOblique
+
+			switch {
+			case word.Type == WordTypeFootnoteLink:
+				appendText(&inlines, space, false, false)
+				inlines = append(inlines, &document.Inline{Type: document.InlineNoteRef, Target: word.String})
+			case word.Type != nil && word.Type.Name == WordTypeLink.Name:
+				target := word.Link
+				if target == "" {
+					target = word.String
+				}
+				if n := len(inlines); n > 0 && inlines[n-1].Type == document.InlineLink && inlines[n-1].Target == target &&
+					inlines[n-1].Bold == bold && inlines[n-1].Italic == italic {
+					// Continue a link whose text spans several words
+					inlines[n-1].Text += space + word.String
+					continue
+				}
+				appendText(&inlines, space, false, false)
+				inlines = append(inlines, &document.Inline{
+					Type:   document.InlineLink,
+					Text:   word.String,
+					Bold:   bold,
+					Italic: italic,
+					Target: target,
+				})
+			default:
+				appendText(&inlines, space+word.String, bold, italic)
+			}
+		}
+	}
+
+	return inlines, images
+}
+
+// appendText appends text to the last run if it has the same formatting,
+// otherwise starts a new run. Leading whitespace joins the last run if it is
+// unformatted, so that formatted runs hold only their own text.
+func appendText(inlines *[]*document.Inline, text string, bold, italic bool) {
+	if text == "" {
+		return
+	}
+	if n := len(*inlines); n > 0 && (*inlines)[n-1].Type == document.InlineText {
+		run := (*inlines)[n-1]
+		if run.Bold == bold && run.Italic == italic {
+			run.Text += text
+			return
+		}
+		if !run.Bold && !run.Italic {
+			trimmed := strings.TrimLeft(text, " ")
+			run.Text += text[:len(text)-len(trimmed)]
+			if text = trimmed; text == "" {
+				return
+			}
+		}
+	}
+	*inlines = append(*inlines, &document.Inline{Type: document.InlineText, Text: text, Bold: bold, Italic: italic})
+}
+
+// splitListMarker removes the bullet or number from the start of a list item
+// and returns it separately
+func splitListMarker(inlines []*document.Inline) (string, []*document.Inline) {
+	if len(inlines) == 0 || inlines[0].Type != document.InlineText {
+		return "", inlines
+	}
+
+	match := listMarkerPattern.FindString(inlines[0].Text)
+	if match == "" {
+		return "", inlines
+	}
+
+	rest := inlines[0].Text[len(match):]
+	if rest == "" {
+		inlines = inlines[1:]
+	} else {
+		first := *inlines[0]
+		first.Text = rest
+		inlines = append([]*document.Inline{&first}, inlines[1:]...)
+	}
+	if len(inlines) > 0 && inlines[0].Type == document.InlineText {
+		trimmed := *inlines[0]
+		trimmed.Text = strings.TrimLeft(trimmed.Text, " \t")
+		inlines[0] = &trimmed
+	}
+	return strings.TrimSpace(match), inlines
+}
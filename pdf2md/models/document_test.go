@@ -0,0 +1,106 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
+)
+
+func words(strs ...string) []*Word {
+	result := make([]*Word, len(strs))
+	for i, s := range strs {
+		result[i] = &Word{String: s}
+	}
+	return result
+}
+
+func TestBlockToDocumentHeading(t *testing.T) {
+	block := &LineItemBlock{
+		Type:  BlockTypeH2,
+		Items: []*LineItem{{Words: words("Getting", "Started"), Type: BlockTypeH2}},
+	}
+
+	blocks := BlockToDocument(block)
+	if len(blocks) != 1 {
+		t.Fatalf("Expected 1 block, got %d", len(blocks))
+	}
+	if blocks[0].Type != document.BlockHeading || blocks[0].Level != 2 || blocks[0].Text != "Getting Started" {
+		t.Errorf("Unexpected heading block: %+v", blocks[0])
+	}
+}
+
+func TestBlockToDocumentList(t *testing.T) {
+	block := &LineItemBlock{
+		Type: BlockTypeList,
+		Items: []*LineItem{
+			{Words: words("-", "First"), Type: BlockTypeList},
+			{Words: words("-", "Nested"), Type: BlockTypeList, ListLevel: 1},
+			{Words: words("2.", "Second"), Type: BlockTypeList},
+		},
+	}
+
+	blocks := BlockToDocument(block)
+	if len(blocks) != 3 {
+		t.Fatalf("Expected 3 list items, got %d", len(blocks))
+	}
+	want := []struct {
+		marker string
+		text   string
+		level  int
+	}{{"-", "First", 0}, {"-", "Nested", 1}, {"2.", "Second", 0}}
+	for i, w := range want {
+		b := blocks[i]
+		if b.Type != document.BlockListItem || b.Marker != w.marker || b.Text != w.text || b.Level != w.level {
+			t.Errorf("Item %d = %+v, want marker %q text %q level %d", i, b, w.marker, w.text, w.level)
+		}
+	}
+}
+
+func TestBlockToDocumentTable(t *testing.T) {
+	block := &LineItemBlock{
+		Items: []*LineItem{
+			{IsTableRow: true, IsTableHeader: true, TableColumns: []string{"Name ", " Age"}},
+			{IsTableRow: true, TableColumns: []string{" ", ""}},
+			{IsTableRow: true, TableColumns: []string{"Alice", "30"}},
+		},
+	}
+
+	blocks := BlockToDocument(block)
+	if len(blocks) != 1 || blocks[0].Type != document.BlockTable {
+		t.Fatalf("Expected a single table block, got %+v", blocks)
+	}
+	rows := blocks[0].Rows
+	if len(rows) != 2 {
+		t.Fatalf("Expected empty row to be skipped, got %d rows", len(rows))
+	}
+	if !rows[0].Header || rows[0].Cells[0] != "Name" || rows[0].Cells[1] != "Age" {
+		t.Errorf("Unexpected header row: %+v", rows[0])
+	}
+	if rows[1].Header || rows[1].Cells[0] != "Alice" {
+		t.Errorf("Unexpected data row: %+v", rows[1])
+	}
+}
+
+func TestBlockToDocumentParagraph(t *testing.T) {
+	block := &LineItemBlock{
+		Items: []*LineItem{
+			{Words: []*Word{{String: "Some"}, {String: "bold", Format: WordFormatBold}, {String: "docu-"}}},
+			{Words: []*Word{{String: "ment"}, {String: "1", Type: WordTypeFootnoteLink}, {String: "image_001", Type: WordTypeImage}}},
+		},
+	}
+
+	blocks := BlockToDocument(block)
+	if len(blocks) != 2 {
+		t.Fatalf("Expected paragraph and image blocks, got %d", len(blocks))
+	}
+	if blocks[0].Type != document.BlockParagraph || blocks[0].Text != "Some bold document[^1]" {
+		t.Errorf("Unexpected paragraph: %+v", blocks[0])
+	}
+	inlines := blocks[0].Inlines
+	if len(inlines) != 4 || !inlines[1].Bold || inlines[1].Text != "bold" || inlines[3].Type != document.InlineNoteRef {
+		t.Errorf("Unexpected inlines: %+v", inlines)
+	}
+	if blocks[1].Type != document.BlockImage || blocks[1].Image != "image_001" {
+		t.Errorf("Unexpected image block: %+v", blocks[1])
+	}
+}
@@ -13,6 +13,9 @@ type TextItem struct {
 	Height        float64
 	Text          string
 	Font          string
+	Link          string // Target of a link annotation covering the text
+	MCID          int    // Marked-content ID of the enclosing tagged content, or -1 if none
+	Artifact      bool   // Whether the text is an artifact, such as a running header
 	LineFormat    *WordFormat
 	UnopenedFormat *WordFormat
 	UnclosedFormat *WordFormat
@@ -32,6 +35,7 @@ type Word struct {
 	String string
 	Type   *WordType
 	Format *WordFormat
+	Link   string // Target of a LINK word, if different from String
 }
 
 // LineItem represents a line of text
@@ -186,12 +190,6 @@ var (
 	DetectedAnnotation = &Annotation{Category: "Detected", Color: "green"}
 )
 
-// TextBlock represents a text block for final output
-type TextBlock struct {
-	Category string
-	Text     string
-}
-
 // ImageItem represents an extracted image from a document
 type ImageItem struct {
 	ID         string  // Unique identifier (e.g., "image_001")
@@ -1,6 +1,10 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/tenebris-tech/x2md/document"
+)
 
 // WordType represents a special word type
 type WordType struct {
@@ -23,7 +27,7 @@ var (
 	WordTypeLink = &WordType{
 		Name: "LINK",
 		toTextFunc: func(s string) string {
-			return fmt.Sprintf("[%s](%s)", s, s)
+			return document.MarkdownLink(s, s)
 		},
 	}
 	WordTypeFootnoteLink = &WordType{
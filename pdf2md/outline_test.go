@@ -0,0 +1,131 @@
+package pdf2md
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/internal/testutil"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
+)
+
+// buildOutlinedPDF builds a two-page PDF whose outline has one top-level
+// entry with two children, using an explicit destination, a named destination
+// and a GoTo action
+func buildOutlinedPDF() []byte {
+	stream := func(lines ...string) string {
+		s := "BT /F1 12 Tf 72 720 Td"
+		for i, line := range lines {
+			if i > 0 {
+				s += " 0 -20 Td"
+			}
+			s += fmt.Sprintf(" (%s) Tj", line)
+		}
+		s += " ET"
+		return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(s), s)
+	}
+	page := func(contents int) string {
+		return fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R /Resources << /Font << /F1 12 0 R >> >> >>", contents)
+	}
+	return testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R /Outlines 7 0 R /Names << /Dests 11 0 R >> >>",
+		"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >>",
+		page(4),
+		stream("Introduction", "The opening words of the document."),
+		page(6),
+		stream("Details", "The middle of the document.", "Summary", "The closing words of the document."),
+		"<< /Type /Outlines /First 8 0 R /Last 8 0 R /Count 3 >>",
+		"<< /Title (Introduction) /Parent 7 0 R /First 9 0 R /Last 10 0 R /Count 2 /Dest [3 0 R /XYZ 0 792 0] >>",
+		"<< /Title (Details) /Parent 8 0 R /Next 10 0 R /Dest (sec-details) >>",
+		"<< /Title <FEFF00530075006D006D006100720079> /Parent 8 0 R /Prev 9 0 R /A << /S /GoTo /D [5 0 R /Fit] >> >>",
+		"<< /Names [(sec-details) [5 0 R /Fit]] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}, "")
+}
+
+func TestGetOutline(t *testing.T) {
+	parser := pdf.NewParser(buildOutlinedPDF())
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []pdf.OutlineItem{
+		{Title: "Introduction", Level: 1, Page: 0},
+		{Title: "Details", Level: 2, Page: 1},
+		{Title: "Summary", Level: 2, Page: 1},
+	}
+	got := parser.GetOutline()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("GetOutline() = %v, want %v", got, want)
+	}
+}
+
+func TestOutlineHeadings(t *testing.T) {
+	doc, _, err := New(WithStrip(), WithOutlineTOC(true)).ConvertDocument(buildOutlinedPDF())
+	if err != nil {
+		t.Fatalf("ConvertDocument failed: %v", err)
+	}
+
+	var headings []string
+	for _, section := range doc.Sections {
+		for _, block := range section.Blocks {
+			if block.Type == document.BlockHeading {
+				headings = append(headings, fmt.Sprintf("%d:%s", block.Level, block.Text))
+			}
+		}
+	}
+	if got := strings.Join(headings, ","); got != "1:Introduction,2:Details,2:Summary" {
+		t.Errorf("Expected headings at the outline depth, got %s", got)
+	}
+
+	toc := doc.Sections[0]
+	if toc.Name != "outline" || len(toc.Blocks) != 1 || toc.Blocks[0].Type != document.BlockTOC {
+		t.Fatalf("Expected an outline TOC section first, got %+v", toc)
+	}
+	if want := "- Introduction\n  - Details\n  - Summary\n"; toc.Blocks[0].Text != want {
+		t.Errorf("Expected TOC %q, got %q", want, toc.Blocks[0].Text)
+	}
+
+	// A page selection lists only the entries pointing to the selected pages
+	doc, _, err = New(WithStrip(), WithOutlineTOC(true), WithPages("2")).ConvertDocument(buildOutlinedPDF())
+	if err != nil {
+		t.Fatalf("ConvertDocument failed: %v", err)
+	}
+	if want := "- Details\n- Summary\n"; doc.Sections[0].Blocks[0].Text != want {
+		t.Errorf("Expected TOC %q, got %q", want, doc.Sections[0].Blocks[0].Text)
+	}
+}
+
+func TestPartialOutlineHeadings(t *testing.T) {
+	// The outline only points to the first page; both pages have a larger
+	// line that is not one of its entries
+	page1 := "BT /F1 12 Tf 72 720 Td (Introduction) Tj 0 -20 Td (The opening words of the document.) Tj " +
+		"/F1 20 Tf 0 -40 Td (Aside) Tj /F1 12 Tf 0 -30 Td (More words of the opening page.) Tj ET"
+	page2 := "BT /F1 20 Tf 72 720 Td (Appendix) Tj /F1 12 Tf 0 -30 Td (The closing words of the document.) Tj " +
+		"0 -20 Td (More words of the closing page.) Tj ET"
+	data := testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R /Outlines 7 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 9 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page1), page1),
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 6 0 R /Resources << /Font << /F1 9 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page2), page2),
+		"<< /Type /Outlines /First 8 0 R /Last 8 0 R /Count 1 >>",
+		"<< /Title (Introduction) /Parent 7 0 R /Dest [3 0 R /XYZ 0 792 0] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}, "")
+
+	markdown, err := New(WithStrip()).Convert(data)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !strings.Contains(markdown, "# Introduction\n") || !strings.Contains(markdown, "Appendix") {
+		t.Errorf("Expected the outline heading and the text of both pages:\n%s", markdown)
+	}
+	for _, unwanted := range []string{"# Aside", "# Appendix"} {
+		if strings.Contains(markdown, unwanted) {
+			t.Errorf("Expected no heading from text heights in an outlined document, got %q:\n%s", unwanted, markdown)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package pdf2md
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// statsSamplePages is the number of pages the global statistics are computed
+// from when fewer pages are selected. Unselected pages are sampled to make up
+// the difference, so that the body text size and fonts are those of the whole
+// document rather than of, say, a lone title page.
+const statsSamplePages = 10
+
+// ParsePageRange parses a page range such as "1-3,7,10-" into a set of pages
+// between 1 and total. An open range runs to the last page.
+func ParsePageRange(spec string, total int) (map[int]bool, error) {
+	pages := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid page range %q", spec)
+		}
+		end := start
+		if isRange {
+			end = total
+			if last = strings.TrimSpace(last); last != "" {
+				if end, err = strconv.Atoi(last); err != nil || end < start {
+					return nil, fmt.Errorf("invalid page range %q", spec)
+				}
+			}
+		}
+		for p := start; p <= end && p <= total; p++ {
+			pages[p] = true
+		}
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("page range %q selects no pages (document has %d)", spec, total)
+	}
+	return pages, nil
+}
+
+// formatPageRange returns the canonical form of a set of pages, e.g. "1-5,10"
+func formatPageRange(pages map[int]bool) string {
+	sorted := make([]int, 0, len(pages))
+	for p := range pages {
+		sorted = append(sorted, p)
+	}
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// samplePages returns up to n 0-based indexes of unselected pages, spread
+// evenly over the document
+func samplePages(pageCount int, selected map[int]bool, n int) []int {
+	var unselected []int
+	for i := 0; i < pageCount; i++ {
+		if !selected[i+1] {
+			unselected = append(unselected, i)
+		}
+	}
+	if n <= 0 || len(unselected) == 0 {
+		return nil
+	}
+	if len(unselected) <= n {
+		return unselected
+	}
+	sample := make([]int, n)
+	for k := range sample {
+		sample[k] = unselected[k*len(unselected)/n]
+	}
+	return sample
+}
@@ -0,0 +1,70 @@
+package pdf2md
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/internal/testutil"
+)
+
+func TestParsePageRange(t *testing.T) {
+	tests := []struct {
+		spec  string
+		total int
+		want  []int
+	}{
+		{"1-3,7", 10, []int{1, 2, 3, 7}},
+		{"9-", 10, []int{9, 10}},
+		{"5", 3, nil},
+		{"3-1", 10, nil},
+		{"x", 10, nil},
+	}
+
+	for _, tt := range tests {
+		pages, err := ParsePageRange(tt.spec, tt.total)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("ParsePageRange(%q) expected error", tt.spec)
+			}
+			continue
+		}
+		if err != nil || len(pages) != len(tt.want) {
+			t.Errorf("ParsePageRange(%q) = %v, %v", tt.spec, pages, err)
+			continue
+		}
+		for _, p := range tt.want {
+			if !pages[p] {
+				t.Errorf("ParsePageRange(%q) missing page %d", tt.spec, p)
+			}
+		}
+		if got := formatPageRange(pages); tt.spec == "1-3,7" && got != tt.spec {
+			t.Errorf("formatPageRange = %q, want %q", got, tt.spec)
+		}
+	}
+}
+
+func TestWithPages(t *testing.T) {
+	data := testutil.TextPDF("Alpha", "Bravo", "Charlie", "Delta")
+
+	doc, _, err := New(WithStrip(), WithPages("2-3")).ConvertDocument(data)
+	if err != nil {
+		t.Fatalf("ConvertDocument failed: %v", err)
+	}
+	if doc.Metadata.Pages != 4 || doc.Metadata.PageRange != "2-3" {
+		t.Errorf("Expected 4 pages with range 2-3, got %+v", doc.Metadata)
+	}
+	var text []string
+	for _, section := range doc.Sections {
+		for _, block := range section.Blocks {
+			text = append(text, fmt.Sprintf("%d:%s", section.Page, block.Text))
+		}
+	}
+	if got := strings.Join(text, ","); got != "2:Bravo,3:Charlie" {
+		t.Errorf("Expected pages 2 and 3 only, got %s", got)
+	}
+
+	if _, _, err := New(WithPages("7-")).ConvertDocument(data); err == nil {
+		t.Error("Expected an error for a range past the last page")
+	}
+}
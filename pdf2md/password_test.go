@@ -0,0 +1,212 @@
+package pdf2md
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
+	"github.com/tenebris-tech/x2md/internal/testutil"
+)
+
+// pdfPadding pads passwords in the standard security handler
+var pdfPadding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// rc4Rounds encrypts data in place with key, then 19 more times with the key
+// XORed with the round number, as revision 3 does
+func rc4Rounds(key, data []byte) {
+	for i := 0; i < 20; i++ {
+		iterKey := make([]byte, len(key))
+		for j := range key {
+			iterKey[j] = key[j] ^ byte(i)
+		}
+		c, _ := rc4.NewCipher(iterKey)
+		c.XORKeyStream(data, data)
+	}
+}
+
+// buildEncryptedPDF builds a one-page PDF encrypted with 128-bit RC4
+// (revision 3) for the given user and owner passwords
+func buildEncryptedPDF(text, user, owner string) []byte {
+	pad := func(pw string) []byte {
+		return append([]byte(pw), pdfPadding...)[:32]
+	}
+	id := []byte("0123456789abcdef")
+	const p = -4
+
+	// O: the padded user password encrypted with a key from the owner password
+	ownerKey := md5.Sum(pad(owner))
+	for i := 0; i < 50; i++ {
+		ownerKey = md5.Sum(ownerKey[:])
+	}
+	o := pad(user)
+	rc4Rounds(ownerKey[:], o)
+
+	// The file key, from the user password
+	h := md5.New()
+	h.Write(pad(user))
+	h.Write(o)
+	h.Write([]byte{byte(p & 0xff), byte(p >> 8 & 0xff), byte(p >> 16 & 0xff), byte(p >> 24 & 0xff)})
+	h.Write(id)
+	fileKey := h.Sum(nil)
+	for i := 0; i < 50; i++ {
+		sum := md5.Sum(fileKey)
+		fileKey = sum[:]
+	}
+
+	// U: the padding hashed with the ID, encrypted with the file key
+	u := md5.Sum(append(append([]byte(nil), pdfPadding...), id...))
+	uValue := append(u[:], make([]byte, 16)...)
+	rc4Rounds(fileKey, uValue[:16])
+
+	// Encrypt the content stream (object 4) with its object key
+	stream := []byte(fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text))
+	objKey := md5.Sum(append(append([]byte(nil), fileKey...), 4, 0, 0, 0, 0))
+	c, _ := rc4.NewCipher(objKey[:])
+	c.XORKeyStream(stream, stream)
+
+	return testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Filter /Standard /V 2 /R 3 /Length 128 /P %d /O <%x> /U <%x> >>", p, o, uValue),
+	}, fmt.Sprintf("/Encrypt 6 0 R /ID [<%x> <%x>]", id, id))
+}
+
+// hashR6 computes the password hash of revision 6 (ISO 32000-2, algorithm 2.B):
+// rounds of AES-128 encryption and a SHA-2 hash picked by the encrypted bytes,
+// until at least 64 rounds have run and the last byte allows stopping
+func hashR6(password, salt, userKey []byte) []byte {
+	sum := sha256.Sum256(append(append(append([]byte(nil), password...), salt...), userKey...))
+	k := sum[:]
+	var e []byte
+	for i := 0; i < 64 || int(e[len(e)-1]) > i-32; i++ {
+		var k1 []byte
+		for j := 0; j < 64; j++ {
+			k1 = append(append(append(k1, password...), k...), userKey...)
+		}
+		block, _ := aes.NewCipher(k[:16])
+		e = make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+
+		// The first 16 bytes as a big-endian number, modulo 3
+		mod := 0
+		for _, c := range e[:16] {
+			mod = (mod*256 + int(c)) % 3
+		}
+		switch mod {
+		case 0:
+			h := sha256.Sum256(e)
+			k = h[:]
+		case 1:
+			h := sha512.Sum384(e)
+			k = h[:]
+		default:
+			h := sha512.Sum512(e)
+			k = h[:]
+		}
+	}
+	return k[:32]
+}
+
+// aesCBC encrypts data, a multiple of the block size, with AES in CBC mode
+func aesCBC(key, iv, data []byte) []byte {
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, data)
+	return out
+}
+
+// buildAES256PDF builds a one-page PDF encrypted with AES-256 (revision 6)
+// for the given user and owner passwords
+func buildAES256PDF(text, user, owner string) []byte {
+	fileKey := []byte("0123456789abcdef0123456789abcdef")
+	zeroIV := make([]byte, 16)
+	const p = -4
+
+	// U and UE: the user password's hash with its validation and key salts,
+	// and the file key encrypted with the hash of the key salt
+	uSalts := []byte("uvalsaltukeysalt")
+	u := append(hashR6([]byte(user), uSalts[:8], nil), uSalts...)
+	ue := aesCBC(hashR6([]byte(user), uSalts[8:], nil), zeroIV, fileKey)
+
+	// O and OE: the same for the owner password, hashed with U
+	oSalts := []byte("ovalsaltokeysalt")
+	o := append(hashR6([]byte(owner), oSalts[:8], u), oSalts...)
+	oe := aesCBC(hashR6([]byte(owner), oSalts[8:], u), zeroIV, fileKey)
+
+	// Perms: the permissions encrypted with the file key
+	perms := []byte{byte(p & 0xff), byte(p >> 8 & 0xff), byte(p >> 16 & 0xff), byte(p >> 24 & 0xff),
+		0xff, 0xff, 0xff, 0xff, 'T', 'a', 'd', 'b', 0, 0, 0, 0}
+	block, _ := aes.NewCipher(fileKey)
+	block.Encrypt(perms, perms)
+
+	// Encrypt the content stream with the file key, a random IV first and
+	// the padding of PKCS #7
+	stream := []byte(fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text))
+	padding := 16 - len(stream)%16
+	for i := 0; i < padding; i++ {
+		stream = append(stream, byte(padding))
+	}
+	iv := []byte("streamivstreamiv")
+	stream = append(iv, aesCBC(fileKey, iv, stream)...)
+
+	return testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Filter /Standard /V 5 /R 6 /Length 256 /P %d /O <%x> /U <%x> /OE <%x> /UE <%x> /Perms <%x> "+
+			"/CF << /StdCF << /CFM /AESV3 /AuthEvent /DocOpen /Length 32 >> >> /StmF /StdCF /StrF /StdCF >>",
+			p, o, u, oe, ue, perms),
+	}, "/Encrypt 6 0 R /ID [<30313233> <30313233>]")
+}
+
+func TestWithPassword(t *testing.T) {
+	data := buildEncryptedPDF("Secret", "user", "owner")
+
+	for _, password := range []string{"user", "owner"} {
+		markdown, err := New(WithStrip(), WithPassword(password)).Convert(data)
+		if err != nil || !strings.Contains(markdown, "Secret") {
+			t.Errorf("Password %q: expected the decrypted text, got %q, %v", password, markdown, err)
+		}
+	}
+
+	_, err := New(WithPassword("wrong")).Convert(data)
+	if !errors.Is(err, document.ErrWrongPassword) || !errors.Is(err, document.ErrEncrypted) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
+	}
+	_, err = New().Convert(data)
+	if !errors.Is(err, document.ErrEncrypted) || errors.Is(err, document.ErrWrongPassword) {
+		t.Errorf("Expected ErrEncrypted without a password, got %v", err)
+	}
+}
+
+func TestWithPasswordAES256(t *testing.T) {
+	data := buildAES256PDF("Secret", "user", "owner")
+
+	for _, password := range []string{"user", "owner"} {
+		markdown, err := New(WithStrip(), WithPassword(password)).Convert(data)
+		if err != nil || !strings.Contains(markdown, "Secret") {
+			t.Errorf("Password %q: expected the decrypted text, got %q, %v", password, markdown, err)
+		}
+	}
+
+	_, err := New(WithPassword("wrong")).Convert(data)
+	if !errors.Is(err, document.ErrWrongPassword) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// maxNameTreeDepth is the maximum depth of a name tree lookup
+const maxNameTreeDepth = 32
+
+// destResolver resolves destinations, as used by outlines and links, to page indexes
+type destResolver struct {
+	parser  *Parser
+	catalog *Object
+	pages   map[int]int // Page object number to page index
+}
+
+// destResolver returns the parser's destination resolver, creating it on first use
+func (p *Parser) destResolver() *destResolver {
+	if p.dests == nil {
+		p.dests = &destResolver{parser: p, catalog: p.catalog(), pages: p.pageIndexes()}
+		if p.dests.catalog == nil {
+			p.dests.catalog = &Object{Dict: map[string]interface{}{}}
+		}
+	}
+	return p.dests
+}
+
+// actionPage returns the page index of the destination of an outline item or
+// annotation, given directly in /Dest or by a GoTo action, or -1 if it has
+// none or it cannot be resolved
+func (r *destResolver) actionPage(obj *Object) int {
+	if dest, ok := obj.Dict["Dest"]; ok {
+		return r.page(dest, obj, 0)
+	}
+	action, holder := r.parser.resolve(obj.Dict["A"], obj)
+	if a, ok := action.(map[string]interface{}); ok && a["S"] == "/GoTo" {
+		return r.page(a["D"], holder, 0)
+	}
+	return -1
+}
+
+// page returns the page index of an explicit destination array, or of the
+// destination a name or string refers to, or -1 if it cannot be resolved
+func (r *destResolver) page(dest interface{}, holder *Object, depth int) int {
+	// A name leads to a dictionary, whose /D holds the array
+	if depth > 2 {
+		return -1
+	}
+	dest, holder = r.parser.resolve(dest, holder)
+	switch d := dest.(type) {
+	case []interface{}:
+		if len(d) == 0 {
+			return -1
+		}
+		switch page := d[0].(type) {
+		case *Reference:
+			if index, ok := r.pages[page.ObjectNum]; ok {
+				return index
+			}
+		case float64:
+			// Page numbers are only valid for remote destinations, but some
+			// writers use them for local ones too
+			if page >= 0 && int(page) < len(r.pages) {
+				return int(page)
+			}
+		}
+	case map[string]interface{}:
+		// Named destinations may be dictionaries holding the array in /D
+		return r.page(d["D"], holder, depth+1)
+	case string:
+		if target, targetHolder := r.namedDest(d, holder); target != nil {
+			return r.page(target, targetHolder, depth+1)
+		}
+	}
+	return -1
+}
+
+// namedDest looks up a destination name in the catalog's /Dests dictionary
+// (PDF 1.1) and in the /Dests name tree of its /Names dictionary
+func (r *destResolver) namedDest(name string, holder *Object) (interface{}, *Object) {
+	p := r.parser
+	if strings.HasPrefix(name, "/") {
+		dests, destsHolder := p.resolve(r.catalog.Dict["Dests"], r.catalog)
+		if dict, ok := dests.(map[string]interface{}); ok {
+			if target, ok := dict[name[1:]]; ok {
+				return target, destsHolder
+			}
+		}
+		name = name[1:]
+	} else if s, ok := p.objectString(name, holder); ok {
+		name = s
+	}
+
+	names, namesHolder := p.resolve(r.catalog.Dict["Names"], r.catalog)
+	if dict, ok := names.(map[string]interface{}); ok {
+		return r.lookupNameTree(dict["Dests"], namesHolder, name, 0)
+	}
+	return nil, nil
+}
+
+// lookupNameTree finds the value of key in the name tree node
+func (r *destResolver) lookupNameTree(node interface{}, holder *Object, key string, depth int) (interface{}, *Object) {
+	if depth > maxNameTreeDepth {
+		return nil, nil
+	}
+	p := r.parser
+	value, holder := p.resolve(node, holder)
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	if names, ok := dict["Names"].([]interface{}); ok {
+		for i := 0; i+1 < len(names); i += 2 {
+			name, ok := names[i].(string)
+			if !ok {
+				continue
+			}
+			if name, ok = p.objectString(name, holder); ok && name == key {
+				return names[i+1], holder
+			}
+		}
+	}
+	if kids, ok := dict["Kids"].([]interface{}); ok {
+		for _, kid := range kids {
+			if target, targetHolder := r.lookupNameTree(kid, holder, key, depth+1); target != nil {
+				return target, targetHolder
+			}
+		}
+	}
+	return nil, nil
+}
+
+// catalog returns the document catalog, or nil if there is none
+func (p *Parser) catalog() *Object {
+	if p.trailer == nil {
+		return nil
+	}
+	rootRef, ok := p.trailer["Root"].(*Reference)
+	if !ok {
+		return nil
+	}
+	root, err := p.GetObject(rootRef.ObjectNum)
+	if err != nil || root.Dict == nil {
+		return nil
+	}
+	return root
+}
+
+// resolve follows a reference to the value of the object it names. Along with
+// the value it returns the object holding it, for decrypting its strings:
+// holder for direct values, or the referenced object.
+func (p *Parser) resolve(v interface{}, holder *Object) (interface{}, *Object) {
+	ref, ok := v.(*Reference)
+	if !ok {
+		return v, holder
+	}
+	obj, err := p.GetObject(ref.ObjectNum)
+	if err != nil {
+		return nil, nil
+	}
+	switch obj.Type {
+	case "dict":
+		return obj.Dict, obj
+	case "array":
+		return obj.Array, obj
+	case "string", "name":
+		return obj.String, obj
+	case "number":
+		return obj.Number, obj
+	}
+	if obj.Dict != nil {
+		return obj.Dict, obj
+	}
+	return nil, nil
+}
+
+// pageIndexes maps the object number of each page to its 0-based index
+func (p *Parser) pageIndexes() map[int]int {
+	indexes := make(map[int]int)
+	root := p.catalog()
+	if root == nil {
+		return indexes
+	}
+	pagesRef, ok := root.Dict["Pages"].(*Reference)
+	if !ok {
+		return indexes
+	}
+
+	visited := make(map[int]bool)
+	var walk func(ref *Reference, depth int)
+	walk = func(ref *Reference, depth int) {
+		if depth > maxPageTreeDepth || visited[ref.ObjectNum] {
+			return
+		}
+		visited[ref.ObjectNum] = true
+		node, err := p.GetObject(ref.ObjectNum)
+		if err != nil || node.Dict == nil {
+			return
+		}
+		if nodeType, _ := node.Dict["Type"].(string); nodeType == "/Page" {
+			indexes[ref.ObjectNum] = len(indexes)
+			return
+		}
+		kids, _ := node.Dict["Kids"].([]interface{})
+		for _, kid := range kids {
+			if kidRef, ok := kid.(*Reference); ok {
+				walk(kidRef, depth+1)
+			}
+		}
+	}
+	walk(pagesRef, 0)
+	return indexes
+}
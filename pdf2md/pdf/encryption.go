@@ -218,6 +218,16 @@ func (h *EncryptionHandler) TryEmptyPassword() bool {
 	return false
 }
 
+// Authenticate tries password as the user password and then as the owner
+// password. Returns true if either succeeded.
+func (h *EncryptionHandler) Authenticate(password string) bool {
+	if h.authenticateUser(password) || h.authenticateOwner(password) {
+		h.authenticated = true
+		return true
+	}
+	return false
+}
+
 // IsAuthenticated returns whether we've successfully authenticated
 func (h *EncryptionHandler) IsAuthenticated() bool {
 	return h.authenticated
@@ -313,29 +323,97 @@ func (h *EncryptionHandler) authenticateUserR56(password string) bool {
 		pwBytes = pwBytes[:127]
 	}
 
-	// For R=5: SHA-256(password + validation_salt)
-	// For R=6: More complex iterative hash
-	var computedHash []byte
-	if h.R == 5 {
-		computedHash = h.computeSHA256(pwBytes, validationSalt, nil)
-	} else {
-		computedHash = h.computeHashR6(pwBytes, validationSalt, nil)
+	if !bytes.Equal(h.hashR56(pwBytes, validationSalt, nil), userHash) {
+		return false
+	}
+
+	// Derive the file encryption key by decrypting UE
+	return h.unwrapKey(h.hashR56(pwBytes, keySalt, nil), h.UE)
+}
+
+// authenticateOwner attempts to authenticate with the given owner password
+func (h *EncryptionHandler) authenticateOwner(password string) bool {
+	switch h.R {
+	case 2, 3, 4:
+		return h.authenticateOwnerRC4(password)
+	case 5, 6:
+		return h.authenticateOwnerR56(password)
+	default:
+		return false
+	}
+}
+
+// authenticateOwnerRC4 authenticates for revisions 2 to 4 by recovering the
+// user password from O with a key derived from the owner password
+func (h *EncryptionHandler) authenticateOwnerRC4(password string) bool {
+	if len(h.O) < 32 {
+		return false
+	}
+
+	keyLen := h.KeyLength / 8
+	if keyLen > 16 {
+		keyLen = 16
+	}
+	digest := md5.Sum(h.padPassword(password))
+	if h.R >= 3 {
+		for i := 0; i < 50; i++ {
+			digest = md5.Sum(digest[:])
+		}
+	}
+	key := digest[:keyLen]
+
+	// R=2 encrypts once; R>=3 20 times with the key XORed with 0 to 19
+	userPassword := append([]byte(nil), h.O[:32]...)
+	rounds := 1
+	if h.R >= 3 {
+		rounds = 20
+	}
+	for i := rounds - 1; i >= 0; i-- {
+		iterKey := make([]byte, len(key))
+		for j := range key {
+			iterKey[j] = key[j] ^ byte(i)
+		}
+		c, err := rc4.NewCipher(iterKey)
+		if err != nil {
+			return false
+		}
+		c.XORKeyStream(userPassword, userPassword)
 	}
 
-	if !bytes.Equal(computedHash, userHash) {
+	return h.authenticateUser(string(userPassword))
+}
+
+// authenticateOwnerR56 authenticates for revision 5 or 6 (AES-256). The O
+// value holds the hash and salts, as U does, but hashed with U.
+func (h *EncryptionHandler) authenticateOwnerR56(password string) bool {
+	if len(h.O) < 48 || len(h.U) < 48 {
 		return false
 	}
 
-	// Derive the file encryption key
-	var keyHash []byte
+	pwBytes := []byte(password)
+	if len(pwBytes) > 127 {
+		pwBytes = pwBytes[:127]
+	}
+
+	userKey := h.U[:48]
+	if !bytes.Equal(h.hashR56(pwBytes, h.O[32:40], userKey), h.O[:32]) {
+		return false
+	}
+	return h.unwrapKey(h.hashR56(pwBytes, h.O[40:48], userKey), h.OE)
+}
+
+// hashR56 computes the password hash of revision 5 (SHA-256) or 6 (iterative)
+func (h *EncryptionHandler) hashR56(password, salt, userKey []byte) []byte {
 	if h.R == 5 {
-		keyHash = h.computeSHA256(pwBytes, keySalt, nil)
-	} else {
-		keyHash = h.computeHashR6(pwBytes, keySalt, nil)
+		return h.computeSHA256(password, salt, userKey)
 	}
+	return h.computeHashR6(password, salt, userKey)
+}
 
-	// Decrypt UE to get the file encryption key
-	if len(h.UE) < 32 || len(keyHash) < 32 {
+// unwrapKey sets the file encryption key by decrypting wrapped (UE or OE)
+// with keyHash, using AES-256 in CBC mode with a zero IV
+func (h *EncryptionHandler) unwrapKey(keyHash, wrapped []byte) bool {
+	if len(wrapped) < 32 || len(keyHash) < 32 {
 		return false
 	}
 
@@ -344,12 +422,11 @@ func (h *EncryptionHandler) authenticateUserR56(password string) bool {
 		return false
 	}
 
-	// Use CBC mode with zero IV
 	iv := make([]byte, 16)
 	mode := cipher.NewCBCDecrypter(block, iv)
 
 	h.key = make([]byte, 32)
-	mode.CryptBlocks(h.key, h.UE[:32])
+	mode.CryptBlocks(h.key, wrapped[:32])
 
 	return true
 }
@@ -493,10 +570,11 @@ func (h *EncryptionHandler) computeHashR6(password, salt, userKey []byte) []byte
 			h := sha512.Sum512(data)
 			newHash = h[:]
 		}
-		key = newHash[:32]
+		// The whole hash feeds the next round; only the result is truncated
+		key = newHash
 	}
 
-	return key
+	return key[:32]
 }
 
 // DecryptStream decrypts a stream using the computed key
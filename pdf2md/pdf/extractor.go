@@ -1,11 +1,14 @@
 package pdf
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
 	"unicode/utf16"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
 // TextItem represents an extracted text element with position
@@ -16,6 +19,10 @@ type TextItem struct {
 	Height float64
 	Text   string
 	Font   string
+	MCID   int // Marked-content ID of the enclosing tagged content, or -1 if none
+	// Artifact is set for text in /Artifact marked content, such as running
+	// headers and footers, which is not part of a tagged document's content
+	Artifact bool
 }
 
 // Font represents a PDF font
@@ -36,17 +43,61 @@ type TextExtractor struct {
 	fonts     map[string]*Font
 	xobjects  map[string]*Object // Form XObjects for current page
 	pageIndex int
+	ctx       context.Context // Checked while tokenizing and executing content streams
+	// warnedFonts holds the fonts already reported as lacking ToUnicode maps
+	warnedFonts map[string]bool
+	// properties holds the current page's named marked-content property
+	// lists, and propertiesHolder the object they are stored in
+	properties       map[string]interface{}
+	propertiesHolder *Object
+	// markedContent is the stack of open marked-content sequences
+	markedContent []markedContent
+	// imageMarks records the marked content around the page's image XObjects
+	imageMarks map[string]ImageMark
+}
+
+// markedContent is an open marked-content sequence (BDC or BMC)
+type markedContent struct {
+	mcid          int    // Marked-content ID (/MCID), or -1 if none
+	alt           string // Alternate description (/Alt)
+	actualText    string // Replacement text (/ActualText) for the enclosed text
+	hasActualText bool
+	artifact      bool // Whether the sequence is tagged /Artifact
+	start         int  // Number of text items shown before the sequence
+}
+
+// ImageMark holds the marked content enclosing an image XObject painted on a page
+type ImageMark struct {
+	MCID int    // Marked-content ID of the enclosing tagged content, or -1 if none
+	Alt  string // Alternate description of the enclosing marked content
 }
 
 // NewTextExtractor creates a new text extractor
 func NewTextExtractor(parser *Parser) *TextExtractor {
 	return &TextExtractor{
-		parser:   parser,
-		fonts:    make(map[string]*Font),
-		xobjects: make(map[string]*Object),
+		parser:      parser,
+		fonts:       make(map[string]*Font),
+		xobjects:    make(map[string]*Object),
+		warnedFonts: make(map[string]bool),
+	}
+}
+
+// SetContext sets a context that aborts content stream processing when cancelled
+func (e *TextExtractor) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// ctxErr returns the extractor context's error, or nil if no context is set
+func (e *TextExtractor) ctxErr() error {
+	if e.ctx == nil {
+		return nil
 	}
+	return e.ctx.Err()
 }
 
+// cancelCheckInterval is how many tokens are processed between context checks
+const cancelCheckInterval = 4096
+
 // ExtractPage extracts text items from a page
 func (e *TextExtractor) ExtractPage(pageIndex int) ([]TextItem, error) {
 	e.pageIndex = pageIndex
@@ -63,6 +114,9 @@ func (e *TextExtractor) ExtractPage(pageIndex int) ([]TextItem, error) {
 
 	// Load XObjects for this page (Form XObjects may contain text)
 	e.loadPageXObjects(page)
+	e.loadPageProperties(page)
+	e.markedContent = nil
+	e.imageMarks = make(map[string]ImageMark)
 
 	// Get page content stream(s)
 	content, err := e.getPageContent(page)
@@ -177,6 +231,7 @@ func (e *TextExtractor) parseFont(dict map[string]interface{}) *Font {
 	}
 
 	// Parse ToUnicode CMap
+	var toUnicodeErr error
 	if toUnicode, ok := dict["ToUnicode"]; ok {
 		if ref, ok := toUnicode.(*Reference); ok {
 			toUnicodeObj, err := e.parser.GetObject(ref.ObjectNum)
@@ -184,11 +239,29 @@ func (e *TextExtractor) parseFont(dict map[string]interface{}) *Font {
 				stream, err := e.parser.DecodeStream(toUnicodeObj)
 				if err == nil {
 					font.ToUnicode = ParseCMap(stream)
+				} else {
+					toUnicodeErr = err
 				}
+			} else if err != nil {
+				toUnicodeErr = err
 			}
 		}
 	}
 
+	// Composite and Identity-encoded fonts use glyph IDs that only a
+	// ToUnicode map turns into text
+	subtype, _ := dict["Subtype"].(string)
+	if font.ToUnicode == nil && !e.warnedFonts[font.BaseFont] {
+		switch {
+		case toUnicodeErr != nil:
+			e.warnedFonts[font.BaseFont] = true
+			e.parser.warn(document.WarnFontNoUnicode, "font %s: reading ToUnicode map: %v; its text may be garbled", font.BaseFont, toUnicodeErr)
+		case subtype == "/Type0" || strings.HasPrefix(font.Encoding, "Identity"):
+			e.warnedFonts[font.BaseFont] = true
+			e.parser.warn(document.WarnFontNoUnicode, "font %s has no ToUnicode map; its text may be garbled", font.BaseFont)
+		}
+	}
+
 	return font
 }
 
@@ -208,6 +281,24 @@ func (e *TextExtractor) getResources(page *Object) map[string]interface{} {
 	return nil
 }
 
+// loadPageProperties loads the named property lists of marked content
+// from page resources
+func (e *TextExtractor) loadPageProperties(page *Object) {
+	e.properties, e.propertiesHolder = nil, nil
+	value, holder := e.parser.resolve(page.Dict["Resources"], page)
+	if resources, ok := value.(map[string]interface{}); ok {
+		properties, holder := e.parser.resolve(resources["Properties"], holder)
+		e.properties, _ = properties.(map[string]interface{})
+		e.propertiesHolder = holder
+	}
+}
+
+// ImageMarks returns the marked content enclosing each image XObject painted
+// on the last extracted page, by resource name
+func (e *TextExtractor) ImageMarks() map[string]ImageMark {
+	return e.imageMarks
+}
+
 // loadPageXObjects loads XObjects from page resources
 // Form XObjects may contain text that needs to be extracted
 func (e *TextExtractor) loadPageXObjects(page *Object) {
@@ -435,9 +526,17 @@ func (e *TextExtractor) parseContentStream(content []byte, mediaBox [4]float64)
 
 	// Tokenize and parse
 	tokens := e.tokenize(content)
+	if err := e.ctxErr(); err != nil {
+		return nil, err
+	}
 	var operandStack []interface{}
 
-	for _, token := range tokens {
+	for i, token := range tokens {
+		if i%cancelCheckInterval == 0 {
+			if err := e.ctxErr(); err != nil {
+				return items, err
+			}
+		}
 		if e.isOperator(token) {
 			items = e.executeOperator(token, operandStack, gs, &gsStack, items, mediaBox)
 			operandStack = []interface{}{}
@@ -446,6 +545,11 @@ func (e *TextExtractor) parseContentStream(content []byte, mediaBox [4]float64)
 		}
 	}
 
+	// Close marked content left open by a malformed stream
+	for len(e.markedContent) > 0 {
+		items = e.executeOperator("EMC", nil, gs, &gsStack, items, mediaBox)
+	}
+
 	return items, nil
 }
 
@@ -471,9 +575,17 @@ func (e *TextExtractor) parseFormXObject(content []byte, mediaBox [4]float64, pa
 
 	// Tokenize and parse
 	tokens := e.tokenize(content)
+	if err := e.ctxErr(); err != nil {
+		return nil, err
+	}
 	var operandStack []interface{}
 
-	for _, token := range tokens {
+	for i, token := range tokens {
+		if i%cancelCheckInterval == 0 {
+			if err := e.ctxErr(); err != nil {
+				return items, err
+			}
+		}
 		if e.isOperator(token) {
 			items = e.executeOperator(token, operandStack, gs, &gsStack, items, mediaBox)
 			operandStack = []interface{}{}
@@ -491,6 +603,11 @@ func (e *TextExtractor) tokenize(content []byte) []string {
 	i := 0
 
 	for i < len(content) {
+		// Stop early if the context is cancelled; callers check ctxErr
+		if len(tokens)%cancelCheckInterval == 0 && e.ctxErr() != nil {
+			break
+		}
+
 		// Skip whitespace
 		for i < len(content) && (content[i] == ' ' || content[i] == '\t' || content[i] == '\n' || content[i] == '\r') {
 			i++
@@ -532,9 +649,10 @@ func (e *TextExtractor) tokenize(content []byte) []string {
 		// Hex string
 		if content[i] == '<' {
 			if i+1 < len(content) && content[i+1] == '<' {
-				// Dictionary
-				tokens = append(tokens, "<<")
-				i += 2
+				// Dictionary, kept whole for the object parser
+				start := i
+				i = dictionaryEnd(content, i)
+				tokens = append(tokens, string(content[start:i]))
 				continue
 			}
 			start := i
@@ -594,6 +712,47 @@ func (e *TextExtractor) tokenize(content []byte) []string {
 	return tokens
 }
 
+// dictionaryEnd returns the offset after the dictionary starting at start,
+// skipping nested dictionaries and strings, or the end of content if it is
+// not closed
+func dictionaryEnd(content []byte, start int) int {
+	depth := 0
+	for i := start; i < len(content); i++ {
+		switch content[i] {
+		case '(':
+			// Literal string, which may hold unbalanced brackets
+			for parens := 0; i < len(content); i++ {
+				if content[i] == '\\' {
+					i++
+				} else if content[i] == '(' {
+					parens++
+				} else if content[i] == ')' {
+					if parens--; parens == 0 {
+						break
+					}
+				}
+			}
+		case '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case '<':
+			if i+1 < len(content) && content[i+1] == '<' {
+				depth++
+				i++
+			}
+		case '>':
+			if i+1 < len(content) && content[i+1] == '>' {
+				i++
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+	}
+	return len(content)
+}
+
 func (e *TextExtractor) isDelimiter(b byte) bool {
 	return b == ' ' || b == '\t' || b == '\n' || b == '\r' ||
 		b == '(' || b == ')' || b == '<' || b == '>' ||
@@ -650,7 +809,13 @@ func (e *TextExtractor) parseToken(token string) interface{} {
 		return e.parseHexStringToken(token)
 	}
 
-	if token == "[" || token == "]" || token == "<<" || token == ">>" {
+	if strings.HasPrefix(token, "<<") {
+		// Inline dictionary, such as a marked-content property list
+		dict, _ := e.parser.parseValueFrom([]byte(token)).(map[string]interface{})
+		return dict
+	}
+
+	if token == "[" || token == "]" {
 		return token
 	}
 
@@ -856,7 +1021,7 @@ func (e *TextExtractor) executeOperator(op string, operands []interface{}, gs *G
 		if len(operands) >= 1 {
 			if text, ok := operands[0].(string); ok {
 				item := e.showText(text, gs, mediaBox)
-				if item.Text != "" {
+				if item.Text != "" || e.inActualText() {
 					items = append(items, item)
 				}
 			}
@@ -873,7 +1038,7 @@ func (e *TextExtractor) executeOperator(op string, operands []interface{}, gs *G
 		if len(operands) >= 1 {
 			if text, ok := operands[0].(string); ok {
 				item := e.showText(text, gs, mediaBox)
-				if item.Text != "" {
+				if item.Text != "" || e.inActualText() {
 					items = append(items, item)
 				}
 			}
@@ -888,17 +1053,46 @@ func (e *TextExtractor) executeOperator(op string, operands []interface{}, gs *G
 			gs.LineMatrix = gs.TextMatrix
 			if text, ok := operands[2].(string); ok {
 				item := e.showText(text, gs, mediaBox)
-				if item.Text != "" {
+				if item.Text != "" || e.inActualText() {
 					items = append(items, item)
 				}
 			}
 		}
 
+	case "BDC":
+		// Begin marked content with a property list
+		mc := markedContent{mcid: -1, artifact: isArtifactTag(operands), start: len(items)}
+		if props, holder := e.markedContentProperties(operands); props != nil {
+			if mcid, ok := props["MCID"].(float64); ok && mcid >= 0 {
+				mc.mcid = int(mcid)
+			}
+			mc.alt, _ = e.propertyText(props["Alt"], holder)
+			mc.actualText, mc.hasActualText = e.propertyText(props["ActualText"], holder)
+		}
+		e.markedContent = append(e.markedContent, mc)
+
+	case "BMC":
+		// Begin marked content
+		e.markedContent = append(e.markedContent, markedContent{mcid: -1, artifact: isArtifactTag(operands), start: len(items)})
+
+	case "EMC":
+		// End marked content, replacing the text shown in it by its actual text
+		if n := len(e.markedContent); n > 0 {
+			mc := e.markedContent[n-1]
+			e.markedContent = e.markedContent[:n-1]
+			if mc.hasActualText {
+				items = replaceText(items, mc.start, mc.actualText)
+			}
+		}
+
 	case "Do":
 		// Paint XObject - if it's a Form XObject, extract text from it
 		if len(operands) >= 1 {
 			if xobjName, ok := operands[0].(string); ok {
 				xobjName = strings.TrimPrefix(xobjName, "/")
+				if _, exists := e.xobjects[xobjName]; !exists && e.imageMarks != nil {
+					e.imageMarks[xobjName] = ImageMark{MCID: e.currentMCID(), Alt: e.currentAlt()}
+				}
 				if formObj, exists := e.xobjects[xobjName]; exists {
 					// Get Form XObject content stream
 					stream, err := e.parser.DecodeStream(formObj)
@@ -972,13 +1166,113 @@ func (e *TextExtractor) showText(text string, gs *GraphicsState, mediaBox [4]flo
 	gs.TextMatrix = e.multiplyMatrix([6]float64{1, 0, 0, 1, width, 0}, gs.TextMatrix)
 
 	return TextItem{
-		X:      x,
-		Y:      y,
-		Width:  width,
-		Height: fontSize,
-		Text:   decodedText,
-		Font:   gs.FontName,
+		X:        x,
+		Y:        y,
+		Width:    width,
+		Height:   fontSize,
+		Text:     decodedText,
+		Font:     gs.FontName,
+		MCID:     e.currentMCID(),
+		Artifact: e.inArtifact(),
+	}
+}
+
+// currentMCID returns the marked-content ID of the innermost open marked
+// content that has one, or -1
+func (e *TextExtractor) currentMCID() int {
+	for i := len(e.markedContent) - 1; i >= 0; i-- {
+		if e.markedContent[i].mcid >= 0 {
+			return e.markedContent[i].mcid
+		}
 	}
+	return -1
+}
+
+// currentAlt returns the alternate description of the innermost open
+// marked content that has one, or ""
+func (e *TextExtractor) currentAlt() string {
+	for i := len(e.markedContent) - 1; i >= 0; i-- {
+		if e.markedContent[i].alt != "" {
+			return e.markedContent[i].alt
+		}
+	}
+	return ""
+}
+
+// inArtifact reports whether text is being shown in artifact marked content
+func (e *TextExtractor) inArtifact() bool {
+	for _, mc := range e.markedContent {
+		if mc.artifact {
+			return true
+		}
+	}
+	return false
+}
+
+// isArtifactTag reports whether the operands of BMC or BDC tag an artifact
+func isArtifactTag(operands []interface{}) bool {
+	return len(operands) > 0 && operands[0] == "/Artifact"
+}
+
+// inActualText reports whether text is being shown in marked content with
+// an actual text, whose items are kept even if their glyphs decode to nothing
+func (e *TextExtractor) inActualText() bool {
+	for _, mc := range e.markedContent {
+		if mc.hasActualText {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceText replaces the text items from start on with a single item
+// holding text, placed at the first of them and spanning those on its
+// baseline. An empty text removes the items.
+func replaceText(items []TextItem, start int, text string) []TextItem {
+	if start >= len(items) {
+		return items
+	}
+	if text == "" {
+		return items[:start]
+	}
+	merged := items[start]
+	merged.Text = text
+	for _, item := range items[start+1:] {
+		if math.Abs(item.Y-merged.Y) < merged.Height/2 && item.X >= merged.X {
+			merged.Width = math.Max(merged.Width, item.X+item.Width-merged.X)
+		}
+	}
+	return append(items[:start], merged)
+}
+
+// propertyText returns a text string of a marked-content property list, and
+// whether it is one. holder is the object a named property list is stored in.
+func (e *TextExtractor) propertyText(v interface{}, holder *Object) (string, bool) {
+	s, ok := v.(string)
+	if !ok || strings.HasPrefix(s, "/") {
+		return "", false
+	}
+	if s, ok = e.parser.objectString(s, holder); !ok {
+		return "", false
+	}
+	return decodeTextString(s), true
+}
+
+// markedContentProperties returns the property list of a BDC operator,
+// given inline or as the name of a page resource, or nil if it has none.
+// Named property lists are returned with the object they are stored in.
+func (e *TextExtractor) markedContentProperties(operands []interface{}) (map[string]interface{}, *Object) {
+	if len(operands) < 2 {
+		return nil, nil
+	}
+	if name, ok := operands[1].(string); ok && strings.HasPrefix(name, "/") {
+		props, holder := e.parser.resolve(e.properties[strings.TrimPrefix(name, "/")], e.propertiesHolder)
+		dict, _ := props.(map[string]interface{})
+		return dict, holder
+	}
+
+	dict, _ := operands[1].(map[string]interface{})
+	return dict, nil
 }
 
 func (e *TextExtractor) showTextArray(operands []interface{}, gs *GraphicsState, items []TextItem, mediaBox [4]float64) []TextItem {
@@ -1039,7 +1333,7 @@ func (e *TextExtractor) showTextArray(operands []interface{}, gs *GraphicsState,
 		}
 	}
 
-	if currentText.Len() > 0 {
+	if currentText.Len() > 0 || e.inActualText() {
 		tm := e.multiplyMatrix([6]float64{1, 0, 0, 1, startX, startY}, gs.CTM)
 
 		x := tm[4]
@@ -1052,12 +1346,14 @@ func (e *TextExtractor) showTextArray(operands []interface{}, gs *GraphicsState,
 		width := gs.TextMatrix[4] - startX
 
 		items = append(items, TextItem{
-			X:      x,
-			Y:      y,
-			Width:  width,
-			Height: fontSize,
-			Text:   currentText.String(),
-			Font:   gs.FontName,
+			X:        x,
+			Y:        y,
+			Width:    width,
+			Height:   fontSize,
+			Text:     currentText.String(),
+			Font:     gs.FontName,
+			MCID:     e.currentMCID(),
+			Artifact: e.inArtifact(),
 		})
 	}
 
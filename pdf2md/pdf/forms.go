@@ -0,0 +1,390 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// maxFormFields bounds the fields read from a form, so that cyclic or
+// oversized field trees cannot stall a conversion
+const maxFormFields = 10000
+
+// Form field types
+const (
+	FieldText      = "text"
+	FieldCheckbox  = "checkbox"
+	FieldRadio     = "radio"
+	FieldChoice    = "choice"
+	FieldSignature = "signature"
+)
+
+// Field flags (PDF 32000-1:2008, 12.7.4.2)
+const (
+	flagRadio      = 1 << 15
+	flagPushbutton = 1 << 16
+)
+
+// FormField is a field of the document's interactive form and its value
+type FormField struct {
+	Name  string     // Fully qualified name, e.g. "applicant.name"
+	Label string     // Alternate name shown to users (/TU), if any
+	Type  string     // FieldText, FieldCheckbox, FieldRadio, FieldChoice or FieldSignature
+	Value string     // Current value: text, the selected option, or "Yes"/"No" for checkboxes
+	Page  int        // 0-based page of the field's first widget, or -1 if unknown
+	Rect  [4]float64 // Rectangle of the first widget in user space
+}
+
+// GetFormFields returns the fields of the document's AcroForm, or, for XFA
+// forms without AcroForm fields, the values of the XFA data. Push buttons are
+// left out. It returns nil if the document has no form.
+func (p *Parser) GetFormFields() []FormField {
+	root := p.catalog()
+	if root == nil {
+		return nil
+	}
+	value, holder := p.resolve(root.Dict["AcroForm"], root)
+	acroForm, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	r := &formReader{parser: p, visited: make(map[int]bool)}
+	fieldList, fieldsHolder := p.resolve(acroForm["Fields"], holder)
+	fields, _ := fieldList.([]interface{})
+	for _, field := range fields {
+		r.readField(field, fieldsHolder, "", fieldAttrs{}, 0)
+	}
+	if len(r.fields) == 0 {
+		return p.xfaFields(acroForm["XFA"], holder)
+	}
+	return r.fields
+}
+
+// fieldAttrs holds the inheritable attributes of a field
+type fieldAttrs struct {
+	fieldType   string
+	flags       int
+	value       interface{}
+	valueHolder *Object
+	options     []interface{}
+}
+
+// formReader walks an AcroForm field tree
+type formReader struct {
+	parser      *Parser
+	visited     map[int]bool
+	fields      []FormField
+	annotToPage map[int]int // Widget object number to page index, built on first use
+}
+
+// readField reads a field and its descendants. parent is the qualified name
+// of the parent field and attrs its inheritable attributes.
+func (r *formReader) readField(v interface{}, holder *Object, parent string, attrs fieldAttrs, depth int) {
+	if depth > maxPageTreeDepth || len(r.fields) >= maxFormFields {
+		return
+	}
+	ref, _ := v.(*Reference)
+	if ref != nil {
+		if r.visited[ref.ObjectNum] {
+			return
+		}
+		r.visited[ref.ObjectNum] = true
+	}
+	value, holder := r.parser.resolve(v, holder)
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	name := parent
+	if partial := r.text(dict["T"], holder); partial != "" {
+		if name != "" {
+			name += "."
+		}
+		name += partial
+	}
+	if ft, ok := dict["FT"].(string); ok {
+		attrs.fieldType = ft
+	}
+	if ff, ok := dict["Ff"].(float64); ok {
+		attrs.flags = int(ff)
+	}
+	if value, ok := dict["V"]; ok {
+		attrs.value, attrs.valueHolder = value, holder
+	}
+	if opt, ok := dict["Opt"]; ok {
+		opts, _ := r.parser.resolve(opt, holder)
+		attrs.options, _ = opts.([]interface{})
+	}
+
+	// Kids with names are fields; kids without are the field's widgets
+	kidList, kidsHolder := r.parser.resolve(dict["Kids"], holder)
+	kids, _ := kidList.([]interface{})
+	var widgets []interface{}
+	for _, kid := range kids {
+		kidValue, _ := r.parser.resolve(kid, kidsHolder)
+		if kidDict, ok := kidValue.(map[string]interface{}); ok && kidDict["T"] != nil {
+			r.readField(kid, kidsHolder, name, attrs, depth+1)
+		} else {
+			widgets = append(widgets, kid)
+		}
+	}
+	if len(kids) > 0 && len(widgets) == 0 {
+		return
+	}
+
+	field := FormField{Name: name, Label: r.text(dict["TU"], holder), Page: -1}
+	switch {
+	case attrs.fieldType == "/Tx":
+		field.Type, field.Value = FieldText, r.text(attrs.value, attrs.valueHolder)
+	case attrs.fieldType == "/Btn" && attrs.flags&flagPushbutton != 0:
+		return
+	case attrs.fieldType == "/Btn" && attrs.flags&flagRadio != 0:
+		field.Type, field.Value = FieldRadio, r.buttonState(attrs)
+	case attrs.fieldType == "/Btn":
+		field.Type, field.Value = FieldCheckbox, "No"
+		if r.buttonState(attrs) != "" {
+			field.Value = "Yes"
+		}
+	case attrs.fieldType == "/Ch":
+		field.Type, field.Value = FieldChoice, r.choiceValue(attrs)
+	case attrs.fieldType == "/Sig":
+		field.Type, field.Value = FieldSignature, r.signature(attrs)
+	default:
+		return
+	}
+
+	// The field is its own widget if it has no kids
+	widget, widgetHolder := v, holder
+	if len(widgets) > 0 {
+		widget, widgetHolder = widgets[0], kidsHolder
+	}
+	field.Page, field.Rect = r.widgetPosition(widget, widgetHolder)
+	r.fields = append(r.fields, field)
+}
+
+// text returns a text string value, or "" if v is not a string
+func (r *formReader) text(v interface{}, holder *Object) string {
+	v, holder = r.parser.resolve(v, holder)
+	s, ok := v.(string)
+	if !ok || strings.HasPrefix(s, "/") {
+		return ""
+	}
+	if s, ok = r.parser.objectString(s, holder); !ok {
+		return ""
+	}
+	return strings.TrimSpace(decodeTextString(s))
+}
+
+// buttonState returns the selected option of a checkbox or radio button
+// field, or "" if it is off. Options given by index are replaced by the
+// matching entry of /Opt.
+func (r *formReader) buttonState(attrs fieldAttrs) string {
+	value, _ := r.parser.resolve(attrs.value, attrs.valueHolder)
+	state, _ := value.(string)
+	state = decodeName(state)
+	if state == "" || state == "Off" {
+		return ""
+	}
+	if i, err := strconv.Atoi(state); err == nil && i >= 0 && i < len(attrs.options) {
+		if opt := r.text(attrs.options[i], attrs.valueHolder); opt != "" {
+			return opt
+		}
+	}
+	return state
+}
+
+// choiceValue returns the selected options of a choice field, using the
+// display text of options given as [export display] pairs
+func (r *formReader) choiceValue(attrs fieldAttrs) string {
+	value, holder := r.parser.resolve(attrs.value, attrs.valueHolder)
+	var selected []string
+	switch v := value.(type) {
+	case string:
+		selected = append(selected, r.text(v, holder))
+	case []interface{}:
+		for _, item := range v {
+			selected = append(selected, r.text(item, holder))
+		}
+	}
+
+	for i, s := range selected {
+		for _, opt := range attrs.options {
+			pair, _ := r.parser.resolve(opt, attrs.valueHolder)
+			if p, ok := pair.([]interface{}); ok && len(p) == 2 && r.text(p[0], attrs.valueHolder) == s {
+				if display := r.text(p[1], attrs.valueHolder); display != "" {
+					selected[i] = display
+				}
+			}
+		}
+	}
+	return strings.Join(selected, ", ")
+}
+
+// signature describes the state of a signature field
+func (r *formReader) signature(attrs fieldAttrs) string {
+	value, holder := r.parser.resolve(attrs.value, attrs.valueHolder)
+	sig, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if name := r.text(sig["Name"], holder); name != "" {
+		return "Signed by " + name
+	}
+	return "Signed"
+}
+
+// widgetPosition returns the page index and rectangle of a widget
+func (r *formReader) widgetPosition(v interface{}, holder *Object) (int, [4]float64) {
+	var rect [4]float64
+	value, _ := r.parser.resolve(v, holder)
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return -1, rect
+	}
+	if coords, ok := dict["Rect"].([]interface{}); ok && len(coords) == 4 {
+		for i, c := range coords {
+			rect[i], _ = c.(float64)
+		}
+	}
+
+	pages := r.parser.destResolver().pages
+	if page, ok := dict["P"].(*Reference); ok {
+		if index, ok := pages[page.ObjectNum]; ok {
+			return index, rect
+		}
+	}
+	ref, ok := v.(*Reference)
+	if !ok {
+		return -1, rect
+	}
+	if r.annotToPage == nil {
+		r.annotToPage = make(map[int]int)
+		for pageObjNum, index := range pages {
+			page, err := r.parser.GetObject(pageObjNum)
+			if err != nil || page.Dict == nil {
+				continue
+			}
+			annots, _ := r.parser.resolve(page.Dict["Annots"], page)
+			list, _ := annots.([]interface{})
+			for _, annot := range list {
+				if annotRef, ok := annot.(*Reference); ok {
+					r.annotToPage[annotRef.ObjectNum] = index
+				}
+			}
+		}
+	}
+	if index, ok := r.annotToPage[ref.ObjectNum]; ok {
+		return index, rect
+	}
+	return -1, rect
+}
+
+// xfaFields returns the values of the data packet of an XFA form, named by
+// the path of their elements below the data root
+func (p *Parser) xfaFields(xfa interface{}, holder *Object) []FormField {
+	// XFA is a stream, or an array of packet names and streams that
+	// together form the XML document
+	var streams []interface{}
+	value, holder := p.resolve(xfa, holder)
+	if list, ok := value.([]interface{}); ok {
+		for i := 1; i < len(list); i += 2 {
+			streams = append(streams, list[i])
+		}
+	} else if xfa != nil {
+		streams = append(streams, xfa)
+	}
+	var data bytes.Buffer
+	for _, s := range streams {
+		ref, ok := s.(*Reference)
+		if !ok {
+			continue
+		}
+		obj, err := p.GetObject(ref.ObjectNum)
+		if err != nil {
+			continue
+		}
+		if decoded, err := p.DecodeStream(obj); err == nil {
+			data.Write(decoded)
+		}
+	}
+	if data.Len() == 0 {
+		return nil
+	}
+
+	var fields []FormField
+	var path []string // Element names below the data root
+	var text []string // Character data of each open element
+	var leaf []bool   // Whether each open element has no child elements
+	inData, dataDepth := false, 0
+	decoder := xml.NewDecoder(&data)
+	decoder.Strict = false
+	for len(fields) < maxFormFields {
+		token, err := decoder.Token()
+		if err != nil {
+			break // io.EOF at the end of the data
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case !inData && t.Name.Local == "data":
+				inData, dataDepth = true, 0
+			case inData:
+				if len(leaf) > 0 {
+					leaf[len(leaf)-1] = false
+				}
+				dataDepth++
+				path = append(path, t.Name.Local)
+				text = append(text, "")
+				leaf = append(leaf, true)
+			}
+		case xml.CharData:
+			if inData && len(text) > 0 {
+				text[len(text)-1] += string(t)
+			}
+		case xml.EndElement:
+			if !inData {
+				continue
+			}
+			if dataDepth == 0 {
+				inData = false
+				continue
+			}
+			n := len(path)
+			// Skip the record element that wraps the whole form
+			if leaf[n-1] && n > 1 {
+				fields = append(fields, FormField{
+					Name:  strings.Join(path[1:], "."),
+					Type:  FieldText,
+					Value: strings.TrimSpace(text[n-1]),
+					Page:  -1,
+				})
+			}
+			path, text, leaf = path[:n-1], text[:n-1], leaf[:n-1]
+			dataDepth--
+		}
+	}
+	return fields
+}
+
+// decodeName returns a name without its slash, with #xx escapes decoded
+func decodeName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if !strings.Contains(name, "#") {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if c, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
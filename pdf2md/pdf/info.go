@@ -0,0 +1,83 @@
+package pdf
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// GetInfo returns the entries of the document information dictionary
+// (Title, Author, Subject, Keywords, Creator, Producer, CreationDate, ModDate)
+// as text. It returns nil if the document has no information dictionary.
+func (p *Parser) GetInfo() map[string]string {
+	if p.trailer == nil {
+		return nil
+	}
+
+	var dict map[string]interface{}
+	var holder *Object
+
+	switch info := p.trailer["Info"].(type) {
+	case *Reference:
+		obj, err := p.GetObject(info.ObjectNum)
+		if err != nil || obj.Dict == nil {
+			return nil
+		}
+		dict, holder = obj.Dict, obj
+	case map[string]interface{}:
+		dict = info
+	default:
+		return nil
+	}
+
+	result := make(map[string]string)
+	for key, value := range dict {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if s, ok = p.objectString(s, holder); !ok {
+			continue
+		}
+		if s = strings.TrimSpace(decodeTextString(s)); s != "" {
+			result[key] = s
+		}
+	}
+	return result
+}
+
+// objectString decrypts a string stored in obj, which is nil for strings
+// outside any object such as those of the trailer. Strings in objects stored
+// in object streams are not encrypted individually. It returns false if the
+// string cannot be decrypted.
+func (p *Parser) objectString(s string, obj *Object) (string, bool) {
+	if p.encryption == nil || obj == nil {
+		return s, true
+	}
+	if _, inXRef := p.xref[obj.ObjNum]; !inXRef {
+		return s, true
+	}
+	decrypted, err := p.encryption.DecryptString([]byte(s), obj.ObjNum, obj.GenNum)
+	if err != nil {
+		return "", false
+	}
+	return string(decrypted), true
+}
+
+// decodeTextString decodes a PDF text string, which is either UTF-16BE with
+// a byte order mark or PDFDocEncoding (treated as Latin-1)
+func decodeTextString(s string) string {
+	data := []byte(s)
+	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+		var u16 []uint16
+		for i := 2; i+1 < len(data); i += 2 {
+			u16 = append(u16, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		return string(utf16.Decode(u16))
+	}
+
+	runes := make([]rune, 0, len(data))
+	for _, b := range data {
+		runes = append(runes, rune(b))
+	}
+	return string(runes)
+}
@@ -0,0 +1,71 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// Link is a link annotation of a page
+type Link struct {
+	Rect [4]float64 // Lower-left x and y, upper-right x and y, in user space
+	URI  string     // Target of an external link
+	Page int        // 0-based destination page of an internal link, or -1
+}
+
+// GetPageLinks returns the link annotations of a page that lead to a URI or
+// to a page of the document. Other links, such as those launching
+// applications, are left out.
+func (p *Parser) GetPageLinks(pageIndex int) ([]Link, error) {
+	page, err := p.GetPage(pageIndex)
+	if err != nil {
+		return nil, err
+	}
+	annots, holder := p.resolve(page.Dict["Annots"], page)
+	list, _ := annots.([]interface{})
+
+	var links []Link
+	for _, value := range list {
+		value, annotHolder := p.resolve(value, holder)
+		dict, ok := value.(map[string]interface{})
+		if !ok || dict["Subtype"] != "/Link" {
+			continue
+		}
+		if annotHolder == holder {
+			// A direct dictionary, whose strings are encrypted with its container
+			annotHolder = &Object{Dict: dict}
+			if holder != nil {
+				annotHolder.ObjNum, annotHolder.GenNum = holder.ObjNum, holder.GenNum
+			}
+		}
+
+		link := Link{Page: -1}
+		rect, _ := dict["Rect"].([]interface{})
+		if len(rect) != 4 {
+			continue
+		}
+		for i, v := range rect {
+			n, _ := v.(float64)
+			link.Rect[i] = n
+		}
+		if link.Rect[0] > link.Rect[2] {
+			link.Rect[0], link.Rect[2] = link.Rect[2], link.Rect[0]
+		}
+		if link.Rect[1] > link.Rect[3] {
+			link.Rect[1], link.Rect[3] = link.Rect[3], link.Rect[1]
+		}
+
+		action, actionHolder := p.resolve(dict["A"], annotHolder)
+		if a, ok := action.(map[string]interface{}); ok && a["S"] == "/URI" {
+			if uri, ok := a["URI"].(string); ok {
+				if uri, ok = p.objectString(uri, actionHolder); ok {
+					link.URI = strings.TrimSpace(uri)
+				}
+			}
+		} else {
+			link.Page = p.destResolver().actionPage(annotHolder)
+		}
+		if link.URI != "" || link.Page >= 0 {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
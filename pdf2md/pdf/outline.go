@@ -0,0 +1,74 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// maxOutlineItems bounds the entries read from an outline, so that cyclic or
+// oversized outlines cannot stall a conversion
+const maxOutlineItems = 10000
+
+// OutlineItem is an entry of the document outline (bookmarks)
+type OutlineItem struct {
+	Title string
+	Level int // Nesting depth, 1 for top-level entries
+	Page  int // 0-based index of the destination page, or -1 if unknown
+}
+
+// GetOutline returns the entries of the document outline in reading order,
+// or nil if the document has none. Destinations, including named ones and
+// those of GoTo actions, are resolved to page indexes.
+func (p *Parser) GetOutline() []OutlineItem {
+	root := p.catalog()
+	if root == nil {
+		return nil
+	}
+	outlines, _ := p.resolve(root.Dict["Outlines"], root)
+	dict, ok := outlines.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	r := &outlineReader{
+		parser:  p,
+		dests:   p.destResolver(),
+		visited: make(map[int]bool),
+	}
+	r.readItems(dict["First"], 1)
+	return r.items
+}
+
+// outlineReader walks an outline tree
+type outlineReader struct {
+	parser  *Parser
+	dests   *destResolver
+	visited map[int]bool
+	items   []OutlineItem
+}
+
+// readItems reads the entry referenced by first and its siblings, with their
+// children, at the given level
+func (r *outlineReader) readItems(first interface{}, level int) {
+	next := first
+	for len(r.items) < maxOutlineItems {
+		ref, ok := next.(*Reference)
+		if !ok || r.visited[ref.ObjectNum] {
+			return
+		}
+		r.visited[ref.ObjectNum] = true
+		obj, err := r.parser.GetObject(ref.ObjectNum)
+		if err != nil || obj.Dict == nil {
+			return
+		}
+
+		title := ""
+		if s, ok := obj.Dict["Title"].(string); ok {
+			if s, ok = r.parser.objectString(s, obj); ok {
+				title = strings.Join(strings.Fields(decodeTextString(s)), " ")
+			}
+		}
+		r.items = append(r.items, OutlineItem{Title: title, Level: level, Page: r.dests.actionPage(obj)})
+		r.readItems(obj.Dict["First"], level+1)
+		next = obj.Dict["Next"]
+	}
+}
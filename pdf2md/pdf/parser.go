@@ -6,10 +6,11 @@ import (
 	"compress/lzw"
 	"compress/zlib"
 	"fmt"
-	"io"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
 // Object represents a PDF object
@@ -47,6 +48,11 @@ type Parser struct {
 	trailer       map[string]interface{}
 	parsedObjStms map[int]map[int]*Object // Cached parsed object streams
 	encryption    *EncryptionHandler      // Encryption handler (nil if not encrypted)
+	onWarning     func(document.Warning)  // Receives recoverable problems (nil to ignore)
+	budget        *document.Budget        // Bounds decompressed stream sizes
+	loadingObjStm map[int]bool            // Object streams being parsed, to break reference cycles
+	password      string                  // Password tried when the empty one fails
+	dests         *destResolver           // Resolves destinations, created on first use
 }
 
 // NewParser creates a new PDF parser
@@ -57,9 +63,29 @@ func NewParser(data []byte) *Parser {
 		xref:          make(map[int]int64),
 		objStreamRefs: make(map[int]*ObjectStreamRef),
 		parsedObjStms: make(map[int]map[int]*Object),
+		budget:        document.NewBudget(document.DefaultLimits()),
+		loadingObjStm: make(map[int]bool),
 	}
 }
 
+// SetLimits replaces the default limits on decompressed stream sizes. It
+// must be called before Parse.
+func (p *Parser) SetLimits(limits document.Limits) {
+	p.budget = document.NewBudget(limits)
+}
+
+// SetPassword sets the password for encrypted documents, tried as the user
+// password and then as the owner password. It must be called before Parse.
+func (p *Parser) SetPassword(password string) {
+	p.password = password
+}
+
+// LimitErr returns the error of the first stream that exceeded the limits,
+// if any. Such streams are otherwise treated like other undecodable streams.
+func (p *Parser) LimitErr() error {
+	return p.budget.Err()
+}
+
 // Parse parses the PDF document
 func (p *Parser) Parse() error {
 	// Find and parse xref table
@@ -120,9 +146,10 @@ func (p *Parser) initEncryption() error {
 		return fmt.Errorf("creating encryption handler: %w", err)
 	}
 
-	// Try authenticating with empty password
-	if !handler.TryEmptyPassword() {
-		// PDF requires a password - we can't handle this
+	// Try the empty password, which opens documents that only restrict
+	// permissions, then the password supplied, if any
+	if !handler.TryEmptyPassword() && (p.password == "" || !handler.Authenticate(p.password)) {
+		// PDF requires a password we don't have
 		return nil // Leave encryption as nil, IsEncrypted will return true
 	}
 
@@ -131,6 +158,19 @@ func (p *Parser) initEncryption() error {
 	return nil
 }
 
+// SetWarningHandler sets a function that receives problems the parser works
+// around, such as undecodable images or streams that fail to decrypt
+func (p *Parser) SetWarningHandler(fn func(document.Warning)) {
+	p.onWarning = fn
+}
+
+// warn reports a recoverable problem to the warning handler
+func (p *Parser) warn(code, format string, args ...any) {
+	if p.onWarning != nil {
+		p.onWarning(document.Warning{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
 // getObjectDirect retrieves an object without decryption (for bootstrapping)
 func (p *Parser) getObjectDirect(objNum int) (*Object, error) {
 	if obj, ok := p.objects[objNum]; ok {
@@ -444,7 +484,12 @@ func (p *Parser) getObjectFromStream(objNum int, ref *ObjectStreamRef) (*Object,
 		return nil, fmt.Errorf("object %d not found in object stream %d", objNum, ref.StreamObjNum)
 	}
 
-	// Get the object stream
+	// Get the object stream, which may itself claim to be in an object stream
+	if p.loadingObjStm[ref.StreamObjNum] {
+		return nil, fmt.Errorf("object stream %d refers to itself", ref.StreamObjNum)
+	}
+	p.loadingObjStm[ref.StreamObjNum] = true
+	defer delete(p.loadingObjStm, ref.StreamObjNum)
 	streamObj, err := p.GetObject(ref.StreamObjNum)
 	if err != nil {
 		return nil, fmt.Errorf("getting object stream %d: %w", ref.StreamObjNum, err)
@@ -520,10 +565,15 @@ func (p *Parser) parseObjectStream(streamObjNum int, streamObj *Object) error {
 		}
 	}
 
-	// Parse the index section (pairs of objNum, offset)
+	// Parse the index section (pairs of objNum, offset). N and First come
+	// from the file, so check them against the stream before trusting them.
 	numObjs := int(n)
 	firstOffset := int(first)
+	if firstOffset < 0 || firstOffset > len(stream) {
+		return fmt.Errorf("object stream First %d is outside the stream", firstOffset)
+	}
 	indexData := string(stream[:firstOffset])
+	numObjs = max(min(numObjs, len(indexData)/4+1), 0) // Each pair takes at least 4 bytes
 
 	// Parse object numbers and offsets
 	type objEntry struct {
@@ -577,6 +627,9 @@ func (p *Parser) parseObjectStream(streamObjNum int, streamObj *Object) error {
 		}
 
 		// Parse the object value
+		if entry.offset < 0 || entry.offset > endOffset || endOffset > len(objectData) {
+			continue
+		}
 		objData := objectData[entry.offset:endOffset]
 		obj := &Object{}
 		p.parseObjectValue(objData, obj)
@@ -601,13 +654,7 @@ func (p *Parser) parseObjectValue(data []byte, obj *Object) {
 		return
 	}
 
-	// Temporarily replace p.data to parse
-	origData := p.data
-	p.data = data
-
-	value, _ := p.parseValue(pos)
-
-	p.data = origData
+	value := p.parseValueFrom(data[pos:])
 
 	// Set object based on value type
 	switch v := value.(type) {
@@ -632,6 +679,18 @@ func (p *Parser) parseObjectValue(data []byte, obj *Object) {
 	}
 }
 
+// parseValueFrom parses the value at the start of data, which is outside
+// the file, such as an object of an object stream
+func (p *Parser) parseValueFrom(data []byte) interface{} {
+	// Temporarily replace p.data to parse
+	origData := p.data
+	p.data = data
+	defer func() { p.data = origData }()
+
+	value, _ := p.parseValue(0)
+	return value
+}
+
 // parseObjectAt parses an object at the given offset
 func (p *Parser) parseObjectAt(pos int) (*Object, int, error) {
 	// Skip whitespace
@@ -1057,7 +1116,7 @@ func (p *Parser) decodeFlateDecode(data []byte) ([]byte, error) {
 	}
 	defer func() { _ = r.Close() }()
 
-	return io.ReadAll(r)
+	return p.budget.ReadAll("a stream", r)
 }
 
 // decodeFlateDecodeWithPredictor decodes FlateDecode with PNG predictor
@@ -1094,6 +1153,7 @@ func (p *Parser) decodePNGPredictor(data []byte, columns int) ([]byte, error) {
 	if len(data)%rowSize != 0 {
 		// Try without the extra filter byte per row
 		if len(data)%columns == 0 {
+			p.warn(document.WarnDecodeFallback, "predictor data has no filter bytes; using it as is")
 			return data, nil
 		}
 		return nil, fmt.Errorf("invalid predictor data size: %d not divisible by row size %d", len(data), rowSize)
@@ -1338,6 +1398,7 @@ func (p *Parser) DecodeStream(obj *Object) ([]byte, error) {
 			if err != nil {
 				// If decryption fails, try with original data
 				// (might be unencrypted metadata or XRef stream)
+				p.warn(document.WarnDecryptFailed, "object %d: %v; reading it as is", obj.ObjNum, err)
 			} else {
 				data = decrypted
 			}
@@ -1376,6 +1437,8 @@ func (p *Parser) DecodeStream(obj *Object) ([]byte, error) {
 		case "/Crypt":
 			// Already handled above or identity filter
 			continue
+		default:
+			p.warn(document.WarnDecodeFallback, "object %d: unsupported filter %s; using the data as is", obj.ObjNum, f)
 		}
 		if err != nil {
 			return nil, fmt.Errorf("decoding %s: %w", f, err)
@@ -1533,7 +1596,7 @@ func (p *Parser) decodeLZW(data []byte) ([]byte, error) {
 	r := lzw.NewReader(bytes.NewReader(data), lzw.MSB, 8)
 	defer func() { _ = r.Close() }()
 
-	decoded, err := io.ReadAll(r)
+	decoded, err := p.budget.ReadAll("a stream", r)
 	if err != nil {
 		return nil, fmt.Errorf("LZW decompression failed: %w", err)
 	}
@@ -1730,14 +1793,18 @@ func (p *Parser) ExtractImage(imgObj *Object) (*ImageData, error) {
 		// No filter - raw data
 		format = "png" // Raw data will need PNG wrapping
 
-	default:
-		// Try to decode using DecodeStream
+	case "/ASCII85Decode", "/ASCIIHexDecode", "/LZWDecode":
+		// Decode using DecodeStream
 		decoded, err := p.DecodeStream(imgObj)
 		if err != nil {
 			return nil, fmt.Errorf("unsupported filter %s: %w", filter, err)
 		}
 		data = decoded
 		format = "png"
+
+	default:
+		// CCITTFaxDecode, JBIG2Decode, RunLengthDecode, ...
+		return nil, fmt.Errorf("unsupported filter %s", filter)
 	}
 
 	return &ImageData{
@@ -1759,7 +1826,11 @@ func (p *Parser) GetAllPageImages(pageIndex int) ([]*ImageData, []string, error)
 	}
 
 	xobjects, err := p.GetXObjects(page)
-	if err != nil || xobjects == nil {
+	if err != nil {
+		p.warn(document.WarnImageSkipped, "reading XObjects: %v", err)
+		return nil, nil, nil
+	}
+	if xobjects == nil {
 		return nil, nil, nil
 	}
 
@@ -1774,6 +1845,7 @@ func (p *Parser) GetAllPageImages(pageIndex int) ([]*ImageData, []string, error)
 
 		obj, err := p.GetObject(ref.ObjectNum)
 		if err != nil {
+			p.warn(document.WarnImageSkipped, "XObject %s: %v", strings.TrimPrefix(name, "/"), err)
 			continue
 		}
 
@@ -1785,6 +1857,7 @@ func (p *Parser) GetAllPageImages(pageIndex int) ([]*ImageData, []string, error)
 
 		imgData, err := p.ExtractImage(obj)
 		if err != nil {
+			p.warn(document.WarnImageSkipped, "image %s: %v", strings.TrimPrefix(name, "/"), err)
 			continue
 		}
 
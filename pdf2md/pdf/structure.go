@@ -0,0 +1,135 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// maxStructElements bounds the elements read from a structure tree, so that
+// cyclic or oversized trees cannot stall a conversion
+const maxStructElements = 100000
+
+// StructElement is an element of the logical structure of a tagged PDF, or
+// a reference to marked content of a page if its MCID is set
+type StructElement struct {
+	Type string           // Standard structure type after role mapping, e.g. "H1", "P", "LI"
+	Alt  string           // Alternate description (/Alt), such as a figure's
+	Page int              // 0-based page of the element's content, or -1 if unknown
+	MCID int              // Marked-content ID of a content reference, or -1 for elements
+	Kids []*StructElement // Children and content references in reading order
+}
+
+// GetStructTree returns the top-level elements of the document's structure
+// tree, or nil if the document is not tagged. Custom types are mapped to
+// standard ones through the tree's role map; object references, such as
+// those of link annotations, and marked content in other streams than the
+// page's are left out.
+func (p *Parser) GetStructTree() []*StructElement {
+	root := p.catalog()
+	if root == nil {
+		return nil
+	}
+	value, holder := p.resolve(root.Dict["StructTreeRoot"], root)
+	treeRoot, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	r := &structReader{
+		parser:  p,
+		pages:   p.destResolver().pages,
+		visited: make(map[int]bool),
+	}
+	roleMap, _ := p.resolve(treeRoot["RoleMap"], holder)
+	r.roleMap, _ = roleMap.(map[string]interface{})
+	return r.readKids(treeRoot["K"], holder, -1, 0)
+}
+
+// structReader walks a structure tree
+type structReader struct {
+	parser  *Parser
+	pages   map[int]int // Page object number to 0-based index
+	roleMap map[string]interface{}
+	visited map[int]bool
+	count   int
+}
+
+// readKids reads the /K entry of an element: a kid or an array of kids.
+// page is the page inherited from the element.
+func (r *structReader) readKids(k interface{}, holder *Object, page, depth int) []*StructElement {
+	value, holder := r.parser.resolve(k, holder)
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{k}
+	}
+	var kids []*StructElement
+	for _, kid := range list {
+		if elem := r.readKid(kid, holder, page, depth); elem != nil {
+			kids = append(kids, elem)
+		}
+	}
+	return kids
+}
+
+// readKid reads an element, a marked-content reference or an MCID
+func (r *structReader) readKid(v interface{}, holder *Object, page, depth int) *StructElement {
+	if depth > maxPageTreeDepth || r.count >= maxStructElements {
+		return nil
+	}
+	if ref, ok := v.(*Reference); ok {
+		if r.visited[ref.ObjectNum] {
+			return nil
+		}
+		r.visited[ref.ObjectNum] = true
+	}
+	value, holder := r.parser.resolve(v, holder)
+
+	switch kid := value.(type) {
+	case float64:
+		// A marked-content ID on the inherited page
+		r.count++
+		return &StructElement{Page: page, MCID: int(kid)}
+	case map[string]interface{}:
+		if pg, ok := kid["Pg"].(*Reference); ok {
+			if index, ok := r.pages[pg.ObjectNum]; ok {
+				page = index
+			}
+		}
+		if mcid, ok := kid["MCID"].(float64); ok {
+			if kid["Stm"] != nil {
+				return nil // Marked content of a form XObject or other stream
+			}
+			r.count++
+			return &StructElement{Page: page, MCID: int(mcid)}
+		}
+		if kid["Type"] == "/OBJR" || kid["Type"] == "/MCR" {
+			return nil
+		}
+		structType, ok := kid["S"].(string)
+		if !ok {
+			return nil
+		}
+		r.count++
+		elem := &StructElement{Type: r.standardType(structType), Page: page, MCID: -1}
+		if alt, ok := kid["Alt"].(string); ok {
+			if alt, ok = r.parser.objectString(alt, holder); ok {
+				elem.Alt = strings.TrimSpace(decodeTextString(alt))
+			}
+		}
+		elem.Kids = r.readKids(kid["K"], holder, page, depth+1)
+		return elem
+	}
+	return nil
+}
+
+// standardType maps a structure type through the role map, following
+// chains of mappings up to a few steps
+func (r *structReader) standardType(structType string) string {
+	for i := 0; i < 8; i++ {
+		mapped, ok := r.roleMap[decodeName(structType)].(string)
+		if !ok || mapped == structType {
+			break
+		}
+		structType = mapped
+	}
+	return decodeName(structType)
+}
@@ -0,0 +1,61 @@
+package pdf2md
+
+import (
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
+)
+
+// contentRef identifies the marked content of a page
+type contentRef struct {
+	page, mcid int
+}
+
+// structureContent returns the marked content referenced by elements and
+// their descendants
+func structureContent(elements []*pdf.StructElement, refs map[contentRef]bool) map[contentRef]bool {
+	if refs == nil {
+		refs = make(map[contentRef]bool)
+	}
+	for _, elem := range elements {
+		if elem.MCID >= 0 {
+			refs[contentRef{page: elem.Page, mcid: elem.MCID}] = true
+		}
+		structureContent(elem.Kids, refs)
+	}
+	return refs
+}
+
+// hasTaggedText reports whether any text of pages is marked content
+// referenced by the structure tree
+func hasTaggedText(structure []*pdf.StructElement, pages []*models.Page) bool {
+	if len(structure) == 0 {
+		return false
+	}
+	refs := structureContent(structure, nil)
+	for _, page := range pages {
+		for _, item := range page.Items {
+			if textItem, ok := item.(*models.TextItem); ok && refs[contentRef{page: page.Index, mcid: textItem.MCID}] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// figureAlts returns the alternate description of each figure of the
+// structure tree by the marked content it holds
+func figureAlts(elements []*pdf.StructElement, alts map[contentRef]string) map[contentRef]string {
+	if alts == nil {
+		alts = make(map[contentRef]string)
+	}
+	for _, elem := range elements {
+		if elem.Type == "Figure" && elem.Alt != "" {
+			for ref := range structureContent(elem.Kids, nil) {
+				alts[ref] = elem.Alt
+			}
+			continue
+		}
+		figureAlts(elem.Kids, alts)
+	}
+	return alts
+}
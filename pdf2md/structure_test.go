@@ -0,0 +1,128 @@
+package pdf2md
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/internal/testutil"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
+)
+
+// buildTaggedPDF builds a one-page tagged PDF whose content stream holds
+// the paragraph before the heading, with a running header as an artifact
+func buildTaggedPDF() []byte {
+	content := "/Artifact BMC BT /F1 9 Tf 72 770 Td (Running header) Tj ET EMC\n" +
+		"/P << /MCID 1 >> BDC BT /F1 12 Tf 72 690 Td (This report covers the whole) Tj 0 -14 Td (financial year.) Tj ET EMC\n" +
+		"/Heading1 << /MCID 0 >> BDC BT /F1 12 Tf 72 720 Td (Annual Report) Tj ET EMC\n" +
+		"/LI /MC0 BDC BT /F1 12 Tf 72 640 Td (First item) Tj ET EMC\n" +
+		"/LI << /MCID 3 >> BDC BT /F1 12 Tf 72 626 Td (Second item) Tj ET EMC\n" +
+		"/LI << /MCID 4 >> BDC BT /F1 12 Tf 92 612 Td (Nested item) Tj ET EMC\n" +
+		"/TH << /MCID 5 >> BDC BT /F1 12 Tf 72 580 Td (Name) Tj ET EMC\n" +
+		"/TH << /MCID 6 >> BDC BT /F1 12 Tf 200 580 Td (Value) Tj ET EMC\n" +
+		"/TD << /MCID 7 >> BDC BT /F1 12 Tf 72 566 Td (Alpha) Tj ET EMC\n" +
+		"/TD << /MCID 8 >> BDC BT /F1 12 Tf 200 566 Td (1) Tj ET EMC\n" +
+		"/Figure << /MCID 9 >> BDC q 100 0 0 100 72 400 cm /Im1 Do Q EMC"
+	image := "\xff\xd8\xff\xd9"
+	return testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R /StructTreeRoot 7 0 R /MarkInfo << /Marked true >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R >> /Properties << /MC0 << /MCID 2 >> >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /BitsPerComponent 8 "+
+			"/ColorSpace /DeviceGray /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream", len(image), image),
+		// 7: the structure tree root, mapping the custom heading type to H1
+		"<< /Type /StructTreeRoot /K 8 0 R /RoleMap << /Heading1 /H1 >> >>",
+		"<< /S /Document /Pg 3 0 R /K [" +
+			"<< /S /Heading1 /K 0 >> " +
+			"<< /S /P /K [<< /Type /MCR /Pg 3 0 R /MCID 1 >>] >> " +
+			"<< /S /L /K [<< /S /LI /K [<< /S /LBody /K 2 >>] >> " +
+			"<< /S /LI /K [<< /S /LBody /K [3 << /S /L /K [<< /S /LI /K 4 >>] >>] >>] >>] >> " +
+			"<< /S /Table /K [<< /S /TR /K [<< /S /TH /K 5 >> << /S /TH /K 6 >>] >> " +
+			"<< /S /TR /K [<< /S /TD /K 7 >> << /S /TD /K 8 >>] >>] >> " +
+			"<< /S /Figure /Alt (Company logo) /K 9 >>] >>",
+	}, "")
+}
+
+func TestGetStructTree(t *testing.T) {
+	parser := pdf.NewParser(buildTaggedPDF())
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	tree := parser.GetStructTree()
+	if len(tree) != 1 || tree[0].Type != "Document" {
+		t.Fatalf("GetStructTree() = %v, want a Document element", tree)
+	}
+	var got []string
+	for _, elem := range tree[0].Kids {
+		got = append(got, fmt.Sprintf("%s:%d", elem.Type, len(elem.Kids)))
+	}
+	if want := "H1:1,P:1,L:2,Table:2,Figure:1"; strings.Join(got, ",") != want {
+		t.Errorf("Document kids = %v, want %s", got, want)
+	}
+	if mcr := tree[0].Kids[1].Kids[0]; mcr.Page != 0 || mcr.MCID != 1 {
+		t.Errorf("Paragraph content = page %d MCID %d, want page 0 MCID 1", mcr.Page, mcr.MCID)
+	}
+	if alt := tree[0].Kids[4].Alt; alt != "Company logo" {
+		t.Errorf("Figure Alt = %q, want %q", alt, "Company logo")
+	}
+}
+
+func TestStructureTree(t *testing.T) {
+	data := buildTaggedPDF()
+	markdown, images, err := New(WithStrip(), WithStructureTree(true)).ConvertWithImages(data)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	want := "# Annual Report\n\nThis report covers the whole financial year.\n\n" +
+		"- First item\n- Second item\n  - Nested item\n\n" +
+		"| Name | Value |\n| --- | --- |\n| Alpha | 1 |\n"
+	if !strings.Contains(markdown, want) {
+		t.Errorf("Expected the tagged structure:\n%s\ngot:\n%s", want, markdown)
+	}
+	if strings.Contains(markdown, "Running header") {
+		t.Errorf("Expected artifacts to be left out:\n%s", markdown)
+	}
+	if len(images) != 1 || images[0].AltText != "Company logo" {
+		t.Errorf("Expected an image with the figure's alternate description, got %v", images)
+	}
+
+	// Without the option, the heading is as tall as the body text
+	markdown, err = New(WithStrip()).Convert(data)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if strings.Contains(markdown, "# Annual Report") {
+		t.Errorf("Expected layout-based conversion without a heading:\n%s", markdown)
+	}
+}
+
+func TestStructureTreeKeepsUnreferencedText(t *testing.T) {
+	content := "/Artifact BMC BT /F1 9 Tf 72 770 Td (Page 1) Tj ET EMC\n" +
+		"/H1 << /MCID 0 >> BDC BT /F1 12 Tf 72 720 Td (Tagged heading) Tj ET EMC\n" +
+		"/P << /Lang (en) /A << /O /Layout /BBox [0 0 1 1] >> /MCID 1 >> BDC BT /F1 12 Tf 72 690 Td (Tagged paragraph) Tj ET EMC\n" +
+		"BT /F1 12 Tf 72 600 Td (Untagged note) Tj ET\n" +
+		"/P << /MCID 2 >> BDC BT /F1 12 Tf 72 560 Td (Orphan paragraph) Tj ET EMC"
+	data := testutil.PDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R /StructTreeRoot 6 0 R /MarkInfo << /Marked true >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /StructTreeRoot /K << /S /Document /Pg 3 0 R /K [<< /S /H1 /K 0 >> << /S /P /K 1 >>] >> >>",
+	}, "")
+
+	markdown, err := New(WithStrip(), WithStructureTree(true)).Convert(data)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	want := "# Tagged heading\n\nTagged paragraph\n\nUntagged note\n\nOrphan paragraph\n"
+	if !strings.Contains(markdown, want) {
+		t.Errorf("Expected untagged and unreferenced text after the tagged content:\n%s\ngot:\n%s", want, markdown)
+	}
+	if strings.Contains(markdown, "Page 1") {
+		t.Errorf("Expected artifacts to be left out:\n%s", markdown)
+	}
+}
@@ -0,0 +1,316 @@
+package transform
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tenebris-tech/x2md/pdf2md/models"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
+)
+
+// Structure types by how their content is converted (PDF 32000-1:2008, 14.8.4)
+var (
+	// paragraphTypes are converted to a paragraph each
+	paragraphTypes = map[string]bool{
+		"P": true, "Note": true, "Caption": true, "BlockQuote": true,
+		"TOCI": true, "Code": true, "Formula": true,
+	}
+
+	// inlineTypes are part of the text around them
+	inlineTypes = map[string]bool{
+		"Span": true, "Quote": true, "Link": true, "Annot": true, "Reference": true,
+		"BibEntry": true, "Lbl": true, "Em": true, "Strong": true, "Sub": true,
+		"Ruby": true, "RB": true, "RT": true, "RP": true, "Warichu": true,
+		"WT": true, "WP": true, "Form": true,
+	}
+)
+
+// ApplyStructure builds the blocks of a tagged document from its structure
+// tree instead of detecting lines, headings, lists and tables from the
+// layout. Blocks follow the reading order of the tree, each on the page
+// where its text starts. Artifacts, such as page headers and footers, are
+// left out, as is the text of figures. Other text the tree does not
+// reference, whether untagged or under an MCID no element points to, is
+// appended to its page in content order, so that no body text is lost.
+type ApplyStructure struct {
+	structure []*pdf.StructElement
+}
+
+// NewApplyStructure creates a new ApplyStructure transformation
+func NewApplyStructure(structure []*pdf.StructElement) *ApplyStructure {
+	return &ApplyStructure{structure: structure}
+}
+
+// contentKey identifies the marked content of a page
+type contentKey struct {
+	page, mcid int
+}
+
+// structureBuilder converts the elements of a structure tree to blocks
+type structureBuilder struct {
+	content       map[contentKey][]*models.TextItem
+	pageOf        map[*models.TextItem]*models.Page
+	used          map[*models.TextItem]bool
+	fontToFormats map[string]*models.WordFormat
+	lines         *CompactLines
+}
+
+// Transform replaces the text items of each page with the blocks of the
+// structure elements whose text starts on it
+func (a *ApplyStructure) Transform(result *models.ParseResult) *models.ParseResult {
+	b := &structureBuilder{
+		content:       make(map[contentKey][]*models.TextItem),
+		pageOf:        make(map[*models.TextItem]*models.Page),
+		used:          make(map[*models.TextItem]bool),
+		fontToFormats: result.Globals.FontToFormats,
+		lines:         NewCompactLines(),
+	}
+	pageText := make([][]*models.TextItem, len(result.Pages))
+	for i, page := range result.Pages {
+		for _, item := range page.Items {
+			textItem, ok := item.(*models.TextItem)
+			if !ok || textItem.Artifact {
+				continue
+			}
+			pageText[i] = append(pageText[i], textItem)
+			b.pageOf[textItem] = page
+			if textItem.MCID >= 0 {
+				key := contentKey{page: page.Index, mcid: textItem.MCID}
+				b.content[key] = append(b.content[key], textItem)
+			}
+		}
+		page.Items = nil
+	}
+
+	for _, elem := range a.structure {
+		b.walk(elem, 0)
+	}
+	for _, items := range pageText {
+		b.addUnreferenced(items)
+	}
+	return result
+}
+
+// addUnreferenced adds the text of a page that no element referenced as
+// paragraphs, split where consecutive items leave the line or are more
+// than a line apart
+func (b *structureBuilder) addUnreferenced(items []*models.TextItem) {
+	var paragraph []*models.TextItem
+	for _, item := range items {
+		if b.used[item] {
+			continue
+		}
+		if n := len(paragraph); n > 0 && math.Abs(item.Y-paragraph[n-1].Y) > 2*math.Max(item.Height, paragraph[n-1].Height) {
+			b.addLines(paragraph, nil)
+			paragraph = nil
+		}
+		paragraph = append(paragraph, item)
+	}
+	b.addLines(paragraph, nil)
+}
+
+// walk converts an element. sectDepth is the number of enclosing sections,
+// which gives the level of untyped headings.
+func (b *structureBuilder) walk(elem *pdf.StructElement, sectDepth int) {
+	switch {
+	case headingLevel(elem.Type) > 0:
+		b.addLines(b.collect(elem, nil), models.HeadlineByLevel(headingLevel(elem.Type)))
+	case elem.Type == "H":
+		b.addLines(b.collect(elem, nil), models.HeadlineByLevel(max(sectDepth, 1)))
+	case paragraphTypes[elem.Type]:
+		b.addLines(b.collect(elem, nil), nil)
+	case elem.Type == "L":
+		b.addList(elem, 0)
+	case elem.Type == "Table":
+		b.addTable(elem)
+	case elem.Type == "Figure" || elem.Type == "Artifact":
+		// Figures are converted as images carrying their alternate description
+		b.discard(elem)
+	default:
+		// A grouping element, such as a section. Content directly in it
+		// forms paragraphs between its block-level children.
+		if elem.Type == "Sect" {
+			sectDepth++
+		}
+		var pending []*models.TextItem
+		for _, kid := range elem.Kids {
+			if kid.MCID >= 0 || inlineTypes[kid.Type] {
+				pending = append(pending, b.collect(kid, nil)...)
+				continue
+			}
+			b.addLines(pending, nil)
+			pending = nil
+			b.walk(kid, sectDepth)
+		}
+		b.addLines(pending, nil)
+	}
+}
+
+// headingLevel returns the level of an H1 to H6 heading type, or 0
+func headingLevel(structType string) int {
+	if structType == "Title" {
+		return 1
+	}
+	if len(structType) == 2 && structType[0] == 'H' {
+		if level, err := strconv.Atoi(structType[1:]); err == nil && level >= 1 && level <= 6 {
+			return level
+		}
+	}
+	return 0
+}
+
+// collect returns the text items of an element's content in reading order,
+// leaving out figures. If lists is not nil, nested lists are appended to it
+// instead of being collected. Content is only returned once, so that content
+// referenced twice is not repeated.
+func (b *structureBuilder) collect(elem *pdf.StructElement, lists *[]*pdf.StructElement) []*models.TextItem {
+	if elem.MCID >= 0 {
+		key := contentKey{page: elem.Page, mcid: elem.MCID}
+		items := b.content[key]
+		delete(b.content, key)
+		for _, item := range items {
+			b.used[item] = true
+		}
+		return items
+	}
+	var items []*models.TextItem
+	for _, kid := range elem.Kids {
+		switch {
+		case kid.Type == "Figure" || kid.Type == "Artifact":
+			b.discard(kid)
+		case kid.Type == "L" && lists != nil:
+			*lists = append(*lists, kid)
+		default:
+			items = append(items, b.collect(kid, lists)...)
+		}
+	}
+	return items
+}
+
+// discard marks the content of an element as used without converting it
+func (b *structureBuilder) discard(elem *pdf.StructElement) {
+	if elem.MCID >= 0 {
+		b.collect(elem, nil)
+		return
+	}
+	for _, kid := range elem.Kids {
+		b.discard(kid)
+	}
+}
+
+// line compacts items to a line, or returns nil if they have no text
+func (b *structureBuilder) line(items []*models.TextItem) *models.LineItem {
+	if len(items) == 0 {
+		return nil
+	}
+	return b.lines.compactLine(items, b.fontToFormats)
+}
+
+// addBlock appends a block to the page of the item its text starts with
+func (b *structureBuilder) addBlock(first *models.TextItem, block *models.LineItemBlock) {
+	if page := b.pageOf[first]; page != nil {
+		page.Items = append(page.Items, block)
+	}
+}
+
+// addLines adds a block of the given type holding items, split into the
+// lines they are laid out on
+func (b *structureBuilder) addLines(items []*models.TextItem, blockType *models.BlockType) {
+	if len(items) == 0 {
+		return
+	}
+	block := &models.LineItemBlock{Type: blockType}
+	start := 0
+	for i := 1; i <= len(items); i++ {
+		if i < len(items) && math.Abs(items[i].Y-items[i-1].Y) <= yLineWrapThreshold {
+			continue
+		}
+		if line := b.line(items[start:i]); line != nil {
+			line.Type = blockType
+			block.Items = append(block.Items, line)
+		}
+		start = i
+	}
+	if len(block.Items) > 0 {
+		b.addBlock(items[0], block)
+	}
+}
+
+// addList adds a list item per LI element of a list, followed by the items
+// of the lists nested in it one level deeper
+func (b *structureBuilder) addList(list *pdf.StructElement, level int) {
+	for _, kid := range list.Kids {
+		if kid.Type == "L" {
+			b.addList(kid, level+1)
+			continue
+		}
+		var nested []*pdf.StructElement
+		items := b.collect(kid, &nested)
+		if line := b.line(items); line != nil {
+			line.Type = models.BlockTypeList
+			line.ListLevel = min(level, maxListLevel)
+			setListMarker(line)
+			b.addBlock(items[0], &models.LineItemBlock{Type: models.BlockTypeList, Items: []*models.LineItem{line}})
+		}
+		for _, l := range nested {
+			b.addList(l, level+1)
+		}
+	}
+}
+
+// setListMarker replaces the bullet of a list item with a dash, and gives
+// items without a label one
+func setListMarker(line *models.LineItem) {
+	first := line.Words[0]
+	switch {
+	case isListItemCharacter(first.String):
+		line.Words[0] = &models.Word{String: "-", Type: first.Type, Format: first.Format}
+	case !isOrderedListItem(line.Text()):
+		line.Words = append([]*models.Word{{String: "-"}}, line.Words...)
+	}
+}
+
+// addTable adds a table with a row per TR element. Rows of header cells
+// only are header rows.
+func (b *structureBuilder) addTable(table *pdf.StructElement) {
+	block := &models.LineItemBlock{}
+	var first *models.TextItem
+	for _, row := range tableRows(table, 0) {
+		line := &models.LineItem{IsTableRow: true, IsTableHeader: true}
+		for _, cell := range row.Kids {
+			if cell.Type != "TH" && cell.Type != "TD" {
+				continue
+			}
+			items := b.collect(cell, nil)
+			if len(items) > 0 && first == nil {
+				first = items[0]
+			}
+			line.TableColumns = append(line.TableColumns, strings.TrimSpace(b.lines.combineText(items)))
+			line.IsTableHeader = line.IsTableHeader && cell.Type == "TH"
+		}
+		if len(line.TableColumns) > 0 {
+			block.Items = append(block.Items, line)
+		}
+	}
+	if first != nil {
+		b.addBlock(first, block)
+	}
+}
+
+// tableRows returns the TR elements of a table, including those in its
+// head, body and foot
+func tableRows(elem *pdf.StructElement, depth int) []*pdf.StructElement {
+	var rows []*pdf.StructElement
+	for _, kid := range elem.Kids {
+		switch kid.Type {
+		case "TR":
+			rows = append(rows, kid)
+		case "THead", "TBody", "TFoot":
+			if depth == 0 {
+				rows = append(rows, tableRows(kid, depth+1)...)
+			}
+		}
+	}
+	return rows
+}
@@ -9,7 +9,8 @@ import (
 
 // CalculateGlobalStats calculates global document statistics
 type CalculateGlobalStats struct {
-	fontMap map[string]*pdf.Font
+	fontMap     map[string]*pdf.Font
+	samplePages []*models.Page // Counted in the statistics but not transformed
 }
 
 // NewCalculateGlobalStats creates a new CalculateGlobalStats transformation
@@ -24,8 +25,10 @@ func (c *CalculateGlobalStats) Transform(result *models.ParseResult) *models.Par
 	var maxHeight int
 	var maxHeightFont string
 
+	pages := append(append([]*models.Page(nil), result.Pages...), c.samplePages...)
+
 	// Parse heights and fonts
-	for _, page := range result.Pages {
+	for _, page := range pages {
 		for _, item := range page.Items {
 			textItem, ok := item.(*models.TextItem)
 			if !ok || textItem.Height == 0 {
@@ -48,7 +51,7 @@ func (c *CalculateGlobalStats) Transform(result *models.ParseResult) *models.Par
 
 	// Parse line distances
 	distanceToOccurrence := make(map[int]int)
-	for _, page := range result.Pages {
+	for _, page := range pages {
 		var lastItemOfMostUsedHeight *models.TextItem
 		for _, item := range page.Items {
 			textItem, ok := item.(*models.TextItem)
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/tenebris-tech/x2md/pdf2md/models"
 )
@@ -1569,10 +1570,10 @@ func (c *CompactLines) itemsToWords(items []*models.TextItem, fontToFormats map[
 
 func (c *CompactLines) itemsToWordsWithContext(items []*models.TextItem, fontToFormats map[string]*models.WordFormat, avgFontSize float64) []*models.Word {
 	// Combine text with spacing
-	combinedText := c.combineTextWithContext(items, avgFontSize)
+	combinedText, starts := c.combineItems(items, avgFontSize)
 
 	// Split into words
-	wordStrings := strings.Fields(combinedText)
+	wordStrings, wordStarts := fieldsWithOffsets(combinedText)
 	if len(wordStrings) == 0 {
 		return nil
 	}
@@ -1596,21 +1597,74 @@ func (c *CompactLines) itemsToWordsWithContext(items []*models.TextItem, fontToF
 			wordType = models.WordTypeLink
 		}
 
+		// Words from text covered by a link annotation link to its target
+		link := ""
+		if item := itemAt(items, starts, wordStarts[i]); item != nil && item.Link != "" {
+			wordType = models.WordTypeLink
+			link = item.Link
+		}
+
 		words[i] = &models.Word{
 			String: wordStr,
 			Type:   wordType,
 			Format: format,
+			Link:   link,
 		}
 	}
 
 	return words
 }
 
+// fieldsWithOffsets splits s like strings.Fields and also returns the byte
+// offset of each field
+func fieldsWithOffsets(s string) ([]string, []int) {
+	var fields []string
+	var offsets []int
+	start := -1
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				fields = append(fields, s[start:i])
+				offsets = append(offsets, start)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+		offsets = append(offsets, start)
+	}
+	return fields, offsets
+}
+
+// itemAt returns the item whose text is at offset in the combined text of
+// items, given the offset where each item's text starts
+func itemAt(items []*models.TextItem, starts []int, offset int) *models.TextItem {
+	var found *models.TextItem
+	for i, start := range starts {
+		if start > offset {
+			break
+		}
+		found = items[i]
+	}
+	return found
+}
+
 func (c *CompactLines) combineText(items []*models.TextItem) string {
 	return c.combineTextWithContext(items, 12.0) // Default font size
 }
 
 func (c *CompactLines) combineTextWithContext(items []*models.TextItem, avgFontSize float64) string {
+	text, _ := c.combineItems(items, avgFontSize)
+	return text
+}
+
+// combineItems joins the text of items, adding spaces between words, and
+// returns the offset where each item's text starts in the result
+func (c *CompactLines) combineItems(items []*models.TextItem, avgFontSize float64) (string, []int) {
+	starts := make([]int, len(items))
 	var text strings.Builder
 	var lastItem *models.TextItem
 	endsWithSpace := false // Track trailing space to avoid repeated String() calls
@@ -1620,7 +1674,7 @@ func (c *CompactLines) combineTextWithContext(items []*models.TextItem, avgFontS
 	// Use a more conservative threshold (3x font size) to avoid false breaks
 	wordSpaceThreshold := avgFontSize * 3.0
 
-	for _, item := range items {
+	for i, item := range items {
 		textToAdd := item.Text
 
 		// If text starts with punctuation that attaches to previous word, trim trailing space
@@ -1674,12 +1728,13 @@ func (c *CompactLines) combineTextWithContext(items []*models.TextItem, avgFontS
 			}
 		}
 
+		starts[i] = text.Len()
 		text.WriteString(textToAdd)
 		endsWithSpace = strings.HasSuffix(textToAdd, " ")
 		lastItem = item
 	}
 
-	return text.String()
+	return text.String(), starts
 }
 
 // getEffectiveWidth returns a reasonable width for the text item
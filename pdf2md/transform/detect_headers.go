@@ -4,8 +4,10 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/tenebris-tech/x2md/pdf2md/models"
+	"github.com/tenebris-tech/x2md/pdf2md/pdf"
 )
 
 // Header detection thresholds
@@ -17,10 +19,16 @@ const (
 	// MinHeightDifference is the minimum absolute height difference for header detection.
 	// A line must be at least 4 points taller than body text to be considered a header.
 	MinHeightDifference = 4
+
+	// MaxOutlineTitleLines is the number of consecutive lines an outline title may span
+	MaxOutlineTitleLines = 3
 )
 
-// DetectHeaders detects headlines based on text heights
-type DetectHeaders struct{}
+// DetectHeaders detects headlines from the document outline, or based on
+// text heights if the document has no outline
+type DetectHeaders struct {
+	outline []pdf.OutlineItem
+}
 
 // NewDetectHeaders creates a new DetectHeaders transformation
 func NewDetectHeaders() *DetectHeaders {
@@ -29,6 +37,14 @@ func NewDetectHeaders() *DetectHeaders {
 
 // Transform detects headers
 func (d *DetectHeaders) Transform(result *models.ParseResult) *models.ParseResult {
+	// Outline titles give the heading levels directly. The heuristics are
+	// only used without an outline, so that guessed levels do not mix with
+	// the outline's, even on pages the outline skips.
+	if len(d.outline) > 0 {
+		d.detectFromOutline(result.Pages)
+		return result
+	}
+
 	mostUsedHeight := result.Globals.MostUsedHeight
 	mostUsedFont := result.Globals.MostUsedFont
 	mostUsedDistance := result.Globals.MostUsedDistance
@@ -149,6 +165,76 @@ func (d *DetectHeaders) Transform(result *models.ParseResult) *models.ParseResul
 	return result
 }
 
+// detectFromOutline promotes the lines that match an outline title on the
+// title's destination page to headings at the title's depth
+func (d *DetectHeaders) detectFromOutline(pages []*models.Page) {
+	byIndex := make(map[int]*models.Page, len(pages))
+	for _, page := range pages {
+		byIndex[page.Index] = page
+	}
+
+	for _, entry := range d.outline {
+		title := normalizeTitle(entry.Title)
+		if title == "" {
+			continue
+		}
+		headlineType := models.HeadlineByLevel(min(max(entry.Level, 1), 6))
+
+		candidates := pages
+		if entry.Page >= 0 {
+			candidates = nil
+			if page := byIndex[entry.Page]; page != nil {
+				candidates = []*models.Page{page}
+			}
+		}
+		for _, page := range candidates {
+			if d.markTitle(page, title, headlineType) {
+				break
+			}
+		}
+	}
+}
+
+// markTitle gives headlineType to the first run of untyped lines on page whose
+// text is title, and reports whether there was one
+func (d *DetectHeaders) markTitle(page *models.Page, title string, headlineType *models.BlockType) bool {
+	var lines []*models.LineItem
+	for _, item := range page.Items {
+		if lineItem, ok := item.(*models.LineItem); ok {
+			lines = append(lines, lineItem)
+		}
+	}
+
+	for i := range lines {
+		text := ""
+		for j := i; j < len(lines) && j < i+MaxOutlineTitleLines && lines[j].Type == nil; j++ {
+			text += normalizeTitle(lines[j].Text())
+			if text == title {
+				for _, lineItem := range lines[i : j+1] {
+					lineItem.Type = headlineType
+					lineItem.Annotation = models.DetectedAnnotation
+				}
+				return true
+			}
+			if !strings.HasPrefix(title, text) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// normalizeTitle reduces a title to its lowercase letters and digits, so that
+// outline titles match lines regardless of spacing and punctuation
+func normalizeTitle(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
 func (d *DetectHeaders) findPagesWithMaxHeight(pages []*models.Page, maxHeight int) []*models.Page {
 	seen := make(map[*models.Page]bool)
 	var result []*models.Page
@@ -13,6 +13,19 @@ type PipelineOptions struct {
 	StripTOC            bool
 	StripFootnotes      bool
 	StripBlankPages     bool
+
+	// SamplePages are extra pages that contribute to the global statistics
+	// only, such as unselected pages when converting a page range
+	SamplePages []*models.Page
+
+	// Outline is the document outline. When present, lines matching its
+	// titles become headings at the outline depth instead of being detected
+	// from text heights, on every page.
+	Outline []pdf.OutlineItem
+
+	// Structure is the structure tree of a tagged document. When present,
+	// blocks are built from its elements instead of from the page layout.
+	Structure []*pdf.StructElement
 }
 
 // Transformation is the interface for all transformations
@@ -33,39 +46,41 @@ func NewPipeline(fontMap map[string]*pdf.Font, opts *PipelineOptions) *Pipeline
 	}
 
 	transformations := []Transformation{
-		NewCalculateGlobalStats(fontMap),
-		NewCompactLines(),
+		&CalculateGlobalStats{fontMap: fontMap, samplePages: opts.SamplePages},
 	}
 
-	// Conditionally add stripping transformations
-	if opts.StripHeadersFooters {
-		transformations = append(transformations, NewRemoveRepetitiveElements())
-	}
+	if len(opts.Structure) > 0 {
+		// Tagged documents mark headers and footers as artifacts, which
+		// the structure tree leaves out
+		transformations = append(transformations, NewApplyStructure(opts.Structure))
+	} else {
+		transformations = append(transformations, NewCompactLines())
 
-	transformations = append(transformations,
-		NewDetectTOC(),
-		NewDetectHeaders(),
-		NewDetectListItems(),
-		NewGatherBlocks(),
-	)
+		// Conditionally add stripping transformations
+		if opts.StripHeadersFooters {
+			transformations = append(transformations, NewRemoveRepetitiveElements())
+		}
+
+		transformations = append(transformations,
+			NewDetectTOC(),
+			&DetectHeaders{outline: opts.Outline},
+			NewDetectListItems(),
+			NewGatherBlocks(),
+		)
+	}
 
 	// Add blank page removal if enabled
 	if opts.StripBlankPages {
 		transformations = append(transformations, NewRemoveBlankPages())
 	}
 
-	transformations = append(transformations,
-		NewToTextBlocks(),
-		NewToMarkdown(),
-	)
-
 	return &Pipeline{
 		transformations: transformations,
 		options:         opts,
 	}
 }
 
-// Transform runs all transformations
+// Transform runs all transformations, leaving each page's items as LineItemBlocks
 func (p *Pipeline) Transform(pages []*models.Page) *models.ParseResult {
 	result := &models.ParseResult{
 		Pages:   pages,
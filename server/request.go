@@ -0,0 +1,243 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tenebris-tech/x2md/convert"
+	"github.com/tenebris-tech/x2md/docx2md"
+	"github.com/tenebris-tech/x2md/pdf2md"
+	"github.com/tenebris-tech/x2md/xlsx2md"
+)
+
+// Response formats
+const (
+	// OutputMarkdown returns Markdown with images inlined as data: URIs
+	OutputMarkdown = "markdown"
+	// OutputJSON returns the structured document as JSON, images inlined
+	OutputJSON = "json"
+	// OutputZip returns a zip of document.md and an images/ folder
+	OutputZip = "zip"
+)
+
+// Request holds the options of a conversion request. They are read from a
+// JSON "options" field of a multipart upload and from query parameters with
+// the same names, which take precedence. Unset fields keep the defaults.
+type Request struct {
+	Format string `json:"format"` // Document format (pdf, docx, xlsx); detected if empty
+	Output string `json:"output"` // markdown (default), json or zip
+
+	Images             *bool `json:"images"`              // Extract images (default: true)
+	PreserveFormatting *bool `json:"preserve_formatting"` // Keep bold/italic (default: true)
+	Compact            *bool `json:"compact"`             // Remove excessive blank lines
+
+	// PDF options
+	Strip          []string `json:"strip"` // headers_footers, page_numbers, toc, footnotes, blank_pages
+	DetectLists    *bool    `json:"detect_lists"`
+	DetectHeadings *bool    `json:"detect_headings"`
+	ScanMode       *bool    `json:"scan_mode"`
+	OutlineTOC     *bool    `json:"outline_toc"`    // Add a TOC from the PDF outline
+	FormFields     *bool    `json:"form_fields"`    // Add form fields and their values (default: true)
+	StructureTree  *bool    `json:"structure_tree"` // Convert tagged PDFs by their structure tree
+	Pages          string   `json:"pages"`          // Page range, e.g. "1-5,10,20-"
+
+	// XLSX options
+	ShowFormulas      *bool `json:"show_formulas"`
+	IncludeSheetNames *bool `json:"include_sheet_names"`
+	IncludeHidden     *bool `json:"include_hidden"`
+}
+
+// stripOptions maps Request.Strip values to PDF strip options
+var stripOptions = map[string]pdf2md.StripOption{
+	"headers_footers": pdf2md.HeadersFooters,
+	"page_numbers":    pdf2md.PageNumbers,
+	"toc":             pdf2md.TOC,
+	"footnotes":       pdf2md.Footnotes,
+	"blank_pages":     pdf2md.BlankPages,
+}
+
+// readRequest reads the document and options of a conversion request. The
+// document is the "file" field of a multipart/form-data upload, or else the
+// raw request body.
+func readRequest(r *http.Request) ([]byte, *Request, error) {
+	req := &Request{}
+
+	var data []byte
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return nil, nil, err
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			switch part.FormName() {
+			case "file":
+				if data, err = io.ReadAll(part); err != nil {
+					return nil, nil, err
+				}
+			case "options":
+				if err := json.NewDecoder(part).Decode(req); err != nil {
+					return nil, nil, fmt.Errorf("invalid options: %w", err)
+				}
+			}
+		}
+		if data == nil {
+			return nil, nil, fmt.Errorf(`missing "file" field`)
+		}
+	} else {
+		var err error
+		if data, err = io.ReadAll(r.Body); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("empty document")
+	}
+
+	if err := req.parseQuery(r.URL.Query()); err != nil {
+		return nil, nil, err
+	}
+	return data, req, nil
+}
+
+// parseQuery applies options given as query parameters
+func (req *Request) parseQuery(q url.Values) error {
+	if v := q.Get("format"); v != "" {
+		req.Format = v
+	}
+	if v := q.Get("output"); v != "" {
+		req.Output = v
+	}
+	if v := q.Get("pages"); v != "" {
+		req.Pages = v
+	}
+	if v := q.Get("strip"); v != "" {
+		req.Strip = strings.Split(v, ",")
+	}
+
+	flags := map[string]**bool{
+		"images":              &req.Images,
+		"preserve_formatting": &req.PreserveFormatting,
+		"compact":             &req.Compact,
+		"detect_lists":        &req.DetectLists,
+		"detect_headings":     &req.DetectHeadings,
+		"scan_mode":           &req.ScanMode,
+		"outline_toc":         &req.OutlineTOC,
+		"form_fields":         &req.FormFields,
+		"structure_tree":      &req.StructureTree,
+		"show_formulas":       &req.ShowFormulas,
+		"include_sheet_names": &req.IncludeSheetNames,
+		"include_hidden":      &req.IncludeHidden,
+	}
+	for name, field := range flags {
+		if !q.Has(name) {
+			continue
+		}
+		b, err := strconv.ParseBool(q.Get(name))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q", name, q.Get(name))
+		}
+		*field = &b
+	}
+	return nil
+}
+
+// ConverterOptions translates the request into converter options. It also
+// validates Output, setting it to OutputMarkdown if empty.
+func (req *Request) ConverterOptions() ([]convert.Option, error) {
+	var opts []convert.Option
+	var pdfOpts []pdf2md.Option
+	var docxOpts []docx2md.Option
+	var xlsxOpts []xlsx2md.Option
+
+	switch req.Output {
+	case "", OutputMarkdown:
+		req.Output = OutputMarkdown
+	case OutputJSON:
+		opts = append(opts, convert.WithOutputFormat(convert.OutputJSON))
+	case OutputZip:
+	default:
+		return nil, fmt.Errorf("unknown output %q (use markdown, json or zip)", req.Output)
+	}
+
+	if req.Strip != nil {
+		var strip []pdf2md.StripOption
+		for _, name := range req.Strip {
+			opt, ok := stripOptions[strings.TrimSpace(name)]
+			if !ok && strings.TrimSpace(name) != "" {
+				return nil, fmt.Errorf("unknown strip option %q", name)
+			}
+			if ok {
+				strip = append(strip, opt)
+			}
+		}
+		pdfOpts = append(pdfOpts, pdf2md.WithStrip(strip...))
+	}
+	if req.Images != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithExtractImages(*req.Images))
+		docxOpts = append(docxOpts, docx2md.WithPreserveImages(*req.Images))
+	}
+	if req.PreserveFormatting != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithPreserveFormatting(*req.PreserveFormatting))
+		docxOpts = append(docxOpts, docx2md.WithPreserveFormatting(*req.PreserveFormatting))
+	}
+	if req.Compact != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithCompact(*req.Compact))
+		docxOpts = append(docxOpts, docx2md.WithCompact(*req.Compact))
+		xlsxOpts = append(xlsxOpts, xlsx2md.WithCompact(*req.Compact))
+	}
+	if req.DetectLists != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithDetectLists(*req.DetectLists))
+	}
+	if req.DetectHeadings != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithDetectHeadings(*req.DetectHeadings))
+	}
+	if req.ScanMode != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithScanMode(*req.ScanMode))
+	}
+	if req.FormFields != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithFormFields(*req.FormFields))
+	}
+	if req.OutlineTOC != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithOutlineTOC(*req.OutlineTOC))
+	}
+	if req.StructureTree != nil {
+		pdfOpts = append(pdfOpts, pdf2md.WithStructureTree(*req.StructureTree))
+	}
+	if req.Pages != "" {
+		pdfOpts = append(pdfOpts, pdf2md.WithPages(req.Pages))
+	}
+	if req.ShowFormulas != nil {
+		xlsxOpts = append(xlsxOpts, xlsx2md.WithShowFormulas(*req.ShowFormulas))
+	}
+	if req.IncludeSheetNames != nil {
+		xlsxOpts = append(xlsxOpts, xlsx2md.WithIncludeSheetNames(*req.IncludeSheetNames))
+	}
+	if req.IncludeHidden != nil {
+		xlsxOpts = append(xlsxOpts, xlsx2md.WithIncludeHidden(*req.IncludeHidden))
+	}
+
+	if len(pdfOpts) > 0 {
+		opts = append(opts, convert.WithPDFOptions(pdfOpts...))
+	}
+	if len(docxOpts) > 0 {
+		opts = append(opts, convert.WithDOCXOptions(docxOpts...))
+	}
+	if len(xlsxOpts) > 0 {
+		opts = append(opts, convert.WithXLSXOptions(xlsxOpts...))
+	}
+	return opts, nil
+}
@@ -0,0 +1,308 @@
+// Package server provides an HTTP API for converting documents to Markdown.
+// It exposes the convert package over net/http so that services written in
+// other languages can convert documents without running the CLI per file.
+//
+// Endpoints:
+//
+//	POST /convert  convert an uploaded document (multipart "file" field or raw body)
+//	GET  /healthz  liveness check
+//	GET  /metrics  request counters as JSON
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/tenebris-tech/x2md/convert"
+	"github.com/tenebris-tech/x2md/imageutil"
+)
+
+// Server handles conversion requests. It implements http.Handler.
+type Server struct {
+	options *Options
+	mux     *http.ServeMux
+	// slots limits the number of conversions running at once
+	slots   chan struct{}
+	metrics metrics
+}
+
+// Options holds configuration for the server
+type Options struct {
+	// MaxRequestSize limits the size of a request body in bytes (default: 64 MiB)
+	MaxRequestSize int64
+
+	// MaxConcurrent is the number of requests read and converted at once
+	// (default: one per CPU). Further requests wait for a free slot before
+	// their body is read, which bounds the memory held by request bodies.
+	MaxConcurrent int
+
+	// Timeout limits how long a single conversion may take. Zero means no limit.
+	Timeout time.Duration
+
+	// ConverterOptions are applied to every conversion before the request's options
+	ConverterOptions []convert.Option
+}
+
+// Option is a functional option for configuring the server
+type Option func(*Options)
+
+// DefaultOptions returns the default options
+func DefaultOptions() *Options {
+	return &Options{
+		MaxRequestSize: 64 << 20,
+		MaxConcurrent:  runtime.NumCPU(),
+	}
+}
+
+// WithMaxRequestSize sets the maximum request body size in bytes
+func WithMaxRequestSize(n int64) Option {
+	return func(o *Options) {
+		o.MaxRequestSize = n
+	}
+}
+
+// WithMaxConcurrent sets the number of conversions run at once.
+// A value of zero or less uses one per CPU.
+func WithMaxConcurrent(n int) Option {
+	return func(o *Options) {
+		if n <= 0 {
+			n = runtime.NumCPU()
+		}
+		o.MaxConcurrent = n
+	}
+}
+
+// WithTimeout sets the maximum time allowed for a single conversion
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// WithConverterOptions sets options applied to every conversion
+func WithConverterOptions(opts ...convert.Option) Option {
+	return func(o *Options) {
+		o.ConverterOptions = opts
+	}
+}
+
+// metrics holds request counters
+type metrics struct {
+	requests    atomic.Int64
+	succeeded   atomic.Int64
+	failed      atomic.Int64
+	rejected    atomic.Int64 // Requests refused before conversion (bad input, too large)
+	cancelled   atomic.Int64 // Requests whose client went away before a response
+	inFlight    atomic.Int64
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	totalMillis atomic.Int64
+	started     time.Time
+}
+
+// New creates a new Server with the given options
+func New(opts ...Option) *Server {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	s := &Server{
+		options: options,
+		mux:     http.NewServeMux(),
+		slots:   make(chan struct{}, options.MaxConcurrent),
+	}
+	s.metrics.started = time.Now()
+	s.mux.HandleFunc("POST /convert", s.handleConvert)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	return s
+}
+
+// ServeHTTP dispatches a request to the endpoint handlers
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleConvert converts the uploaded document
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	s.metrics.requests.Add(1)
+
+	// Wait for a slot before reading the body, so that waiting requests do
+	// not hold their documents in memory
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-r.Context().Done():
+		// The client went away; there is no one to respond to
+		s.metrics.cancelled.Add(1)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.options.MaxRequestSize)
+
+	data, req, err := readRequest(r)
+	if err != nil {
+		s.metrics.rejected.Add(1)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.metrics.bytesIn.Add(int64(len(data)))
+
+	opts, err := req.ConverterOptions()
+	if err != nil {
+		s.metrics.rejected.Add(1)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.metrics.inFlight.Add(1)
+	start := time.Now()
+	body, contentType, err := s.convert(r.Context(), data, req, opts)
+	s.metrics.totalMillis.Add(time.Since(start).Milliseconds())
+	s.metrics.inFlight.Add(-1)
+
+	if err != nil && r.Context().Err() != nil {
+		s.metrics.cancelled.Add(1)
+		return
+	}
+	if err != nil {
+		s.metrics.failed.Add(1)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			writeError(w, http.StatusGatewayTimeout, fmt.Errorf("conversion timed out"))
+		case errors.Is(err, convert.ErrUnsupportedFormat):
+			writeError(w, http.StatusUnsupportedMediaType, err)
+		default:
+			writeError(w, http.StatusUnprocessableEntity, err)
+		}
+		return
+	}
+
+	s.metrics.succeeded.Add(1)
+	s.metrics.bytesOut.Add(int64(len(body)))
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body)
+}
+
+// convert runs a conversion and returns the response body and its content type
+func (s *Server) convert(ctx context.Context, data []byte, req *Request, opts []convert.Option) ([]byte, string, error) {
+	if s.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.options.Timeout)
+		defer cancel()
+	}
+
+	// Zip bundles link images into an images/ folder; other outputs inline them
+	var sink imageutil.Sink = imageutil.DataURISink{}
+	var memory *imageutil.MemorySink
+	if req.Output == OutputZip {
+		memory = imageutil.NewMemorySink("images/")
+		sink = memory
+	}
+
+	c := convert.New(append(append([]convert.Option(nil), s.options.ConverterOptions...), opts...)...)
+	reader := bytes.NewReader(data)
+	var buf bytes.Buffer
+	var err error
+	if req.Format != "" {
+		err = c.ConvertFormatToWriter(ctx, req.Format, reader, int64(len(data)), &buf, sink)
+	} else {
+		err = c.ConvertToWriter(ctx, reader, int64(len(data)), &buf, sink)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		return nil, "", err
+	}
+
+	switch req.Output {
+	case OutputJSON:
+		return buf.Bytes(), "application/json", nil
+	case OutputZip:
+		body, err := bundle(buf.Bytes(), memory)
+		return body, "application/zip", err
+	default:
+		return buf.Bytes(), "text/markdown; charset=utf-8", nil
+	}
+}
+
+// bundle packs Markdown and the images in sink into a zip archive
+func bundle(markdown []byte, sink *imageutil.MemorySink) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	f, err := zw.Create("document.md")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(markdown); err != nil {
+		return nil, err
+	}
+
+	for _, name := range sink.Filenames() {
+		f, err := zw.Create("images/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(sink.Get(name).Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// handleHealth reports that the server is running
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// handleMetrics writes the request counters as JSON
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	m := &s.metrics
+	writeJSON(w, http.StatusOK, map[string]int64{
+		"requests_total":      m.requests.Load(),
+		"requests_succeeded":  m.succeeded.Load(),
+		"requests_failed":     m.failed.Load(),
+		"requests_rejected":   m.rejected.Load(),
+		"requests_cancelled":  m.cancelled.Load(),
+		"conversions_running": m.inFlight.Load(),
+		"bytes_in":            m.bytesIn.Load(),
+		"bytes_out":           m.bytesOut.Load(),
+		"conversion_millis":   m.totalMillis.Load(),
+		"max_concurrent":      int64(s.options.MaxConcurrent),
+		"uptime_seconds":      int64(time.Since(m.started).Seconds()),
+	})
+}
+
+// writeError writes an error response as JSON
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tenebris-tech/x2md/convert"
+	"github.com/tenebris-tech/x2md/docx2md"
+	"github.com/tenebris-tech/x2md/internal/testutil"
+)
+
+var testDocx = testutil.Docx(`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Hello world</w:t></w:r></w:p>`)
+
+func TestConvertRawBody(t *testing.T) {
+	s := New()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(testDocx)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "**Hello world**") {
+		t.Errorf("Expected bold text in output, got %q", rec.Body)
+	}
+}
+
+func TestConvertMultipartOptions(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	f, _ := mw.CreateFormFile("file", "test.docx")
+	_, _ = f.Write(testDocx)
+	_ = mw.WriteField("options", `{"preserve_formatting": false, "output": "zip"}`)
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/convert?format=docx", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	New().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("Expected zip response: %v", err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != "document.md" {
+		t.Fatalf("Expected document.md in bundle, got %v", zr.File)
+	}
+	rc, _ := zr.File[0].Open()
+	var md bytes.Buffer
+	_, _ = md.ReadFrom(rc)
+	if strings.Contains(md.String(), "**") || !strings.Contains(md.String(), "Hello world") {
+		t.Errorf("Expected plain text, got %q", md.String())
+	}
+}
+
+func TestConvertMergesServerOptions(t *testing.T) {
+	s := New(WithConverterOptions(convert.WithDOCXOptions(docx2md.WithPreserveFormatting(false))))
+
+	// A request setting other DOCX options keeps the server's
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/convert?compact=true", bytes.NewReader(testDocx)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "**") {
+		t.Errorf("Expected the server's options to apply, got %q", rec.Body)
+	}
+
+	// Request options are applied after the server's
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/convert?preserve_formatting=true", bytes.NewReader(testDocx)))
+	if !strings.Contains(rec.Body.String(), "**Hello world**") {
+		t.Errorf("Expected the request's options to win, got %q", rec.Body)
+	}
+}
+
+func TestConvertJSONOutput(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/convert?output=json", bytes.NewReader(testDocx)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected JSON document: %v", err)
+	}
+	if doc["format"] != "docx" {
+		t.Errorf("Expected docx format, got %v", doc["format"])
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   []byte
+		want   int
+	}{
+		{"unsupported", "/convert", []byte("plain text"), http.StatusUnsupportedMediaType},
+		{"empty", "/convert", nil, http.StatusBadRequest},
+		{"bad option", "/convert?images=maybe", testDocx, http.StatusBadRequest},
+		{"bad output", "/convert?output=pdf", testDocx, http.StatusBadRequest},
+		{"too large", "/convert", bytes.Repeat([]byte("x"), 2048), http.StatusRequestEntityTooLarge},
+	}
+
+	s := New(WithMaxRequestSize(1024 + int64(len(testDocx))))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHealthAndMetrics(t *testing.T) {
+	s := New()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected healthz 200, got %d", rec.Code)
+	}
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(testDocx)))
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var m map[string]int64
+	if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatalf("Expected JSON metrics: %v", err)
+	}
+	if m["requests_total"] != 1 || m["requests_succeeded"] != 1 {
+		t.Errorf("Unexpected metrics: %v", m)
+	}
+}
+
+// countingReader records whether a request body was read
+type countingReader struct {
+	r    io.Reader
+	read bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.read = true
+	return c.r.Read(p)
+}
+
+func TestConvertWaitsBeforeReading(t *testing.T) {
+	s := New(WithMaxConcurrent(1))
+	s.slots <- struct{}{} // Occupy the only slot
+
+	// A client that gives up while waiting is cancelled, its body unread
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	body := &countingReader{r: bytes.NewReader(testDocx)}
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/convert", body).WithContext(ctx))
+	if body.read {
+		t.Error("Expected the body to be left unread while waiting for a slot")
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var m map[string]int64
+	if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatalf("Expected JSON metrics: %v", err)
+	}
+	if m["requests_cancelled"] != 1 || m["requests_failed"] != 0 {
+		t.Errorf("Expected 1 cancelled and 0 failed requests, got %v", m)
+	}
+}
@@ -2,11 +2,15 @@
 package xlsx2md
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
 
+	"github.com/tenebris-tech/x2md/document"
 	"github.com/tenebris-tech/x2md/xlsx2md/xlsx"
 )
 
@@ -41,6 +45,9 @@ type Options struct {
 
 	// OnSheetParsed is called when a sheet is parsed
 	OnSheetParsed func(name string, rows, cols int)
+
+	// Limits bounds the work done for one workbook (default: document.DefaultLimits)
+	Limits document.Limits
 }
 
 // Option is a functional option for configuring the converter
@@ -55,6 +62,7 @@ func DefaultOptions() *Options {
 		IncludeHidden:     true,
 		MarkHidden:        true,
 		ShowFormulas:      true,
+		Limits:            document.DefaultLimits(),
 	}
 }
 
@@ -93,6 +101,14 @@ func WithCompact(compact bool) Option {
 	}
 }
 
+// WithLimits sets the limits for hostile input, such as the most cells and
+// the largest decompressed part
+func WithLimits(limits document.Limits) Option {
+	return func(o *Options) {
+		o.Limits = limits
+	}
+}
+
 // New creates a new Converter with the given options
 func New(opts ...Option) *Converter {
 	options := DefaultOptions()
@@ -113,30 +129,110 @@ func (c *Converter) ConvertFile(inputPath string) (string, error) {
 
 // ConvertFileToFile converts an XLSX file and writes the result to a file
 func (c *Converter) ConvertFileToFile(inputPath, outputPath string) error {
-	markdown, err := c.ConvertFile(inputPath)
+	return c.ConvertFileToFileContext(context.Background(), inputPath, outputPath)
+}
+
+// ConvertFileToFileContext is like ConvertFileToFile but stops when ctx is cancelled.
+// Nothing is written if the conversion is cancelled.
+func (c *Converter) ConvertFileToFileContext(ctx context.Context, inputPath, outputPath string) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+	markdown, err := c.ConvertContext(ctx, data)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(outputPath, []byte(markdown), 0644)
 }
 
+// ConvertReader converts an XLSX read from r to Markdown.
+// The workbook is read fully into memory, as XLSX is a zip archive.
+func (c *Converter) ConvertReader(r io.ReaderAt, size int64) (string, error) {
+	return c.ConvertReaderContext(context.Background(), r, size)
+}
+
+// ConvertReaderContext is like ConvertReader but stops when ctx is cancelled
+func (c *Converter) ConvertReaderContext(ctx context.Context, r io.ReaderAt, size int64) (string, error) {
+	if err := document.CheckLimit("input size", size, c.options.Limits.MaxInputBytes); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return "", fmt.Errorf("reading input: %w", err)
+	}
+	return c.ConvertContext(ctx, data)
+}
+
+// ConvertToWriter converts an XLSX read from r and writes the Markdown to w
+func (c *Converter) ConvertToWriter(ctx context.Context, r io.ReaderAt, size int64, w io.Writer) error {
+	markdown, err := c.ConvertReaderContext(ctx, r, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, markdown)
+	return err
+}
+
 // Convert converts XLSX data to Markdown
 func (c *Converter) Convert(data []byte) (string, error) {
-	workbook, err := xlsx.Parse(data)
+	return c.ConvertContext(context.Background(), data)
+}
+
+// ConvertContext is like Convert but stops when ctx is cancelled.
+// Cancellation is checked between sheets; the returned error is ctx.Err().
+func (c *Converter) ConvertContext(ctx context.Context, data []byte) (string, error) {
+	doc, err := c.ConvertDocumentContext(ctx, data)
 	if err != nil {
 		return "", err
 	}
 
-	var output strings.Builder
-	for sheetIndex, sheet := range workbook.Sheets {
-		if sheetIndex > 0 {
-			output.WriteString(c.options.SheetSeparator)
+	markdown := doc.Markdown(&document.MarkdownOptions{
+		SectionSeparator: c.options.SheetSeparator,
+	})
+
+	// Apply compact formatting if enabled
+	if c.options.Compact {
+		markdown = compactMarkdown(markdown)
+	}
+
+	return markdown, nil
+}
+
+// ConvertDocument converts XLSX data to a structured document.
+// Each sheet becomes a section holding one table per range block; the sheet
+// name is used as the section title if IncludeSheetNames is set.
+func (c *Converter) ConvertDocument(data []byte) (*document.Document, error) {
+	return c.ConvertDocumentContext(context.Background(), data)
+}
+
+// ConvertDocumentContext is like ConvertDocument but stops when ctx is cancelled
+func (c *Converter) ConvertDocumentContext(ctx context.Context, data []byte) (*document.Document, error) {
+	workbook, err := xlsx.ParseWithLimits(ctx, data, c.options.Limits)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil, errors.Is(err, document.ErrLimitExceeded):
+			return nil, err
+		case errors.Is(err, xlsx.ErrEncrypted):
+			return nil, document.MarkError(document.ErrEncrypted, err)
+		default:
+			return nil, document.MarkError(document.ErrCorrupt, err)
 		}
+	}
 
-		if c.options.IncludeSheetNames && sheet.Name != "" {
-			output.WriteString(fmt.Sprintf("## %s\n\n", sheet.Name))
+	doc := &document.Document{
+		Format:   "xlsx",
+		Metadata: workbookMetadata(workbook.Properties),
+	}
+	for _, sheet := range workbook.Sheets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
+		section := doc.AddSection(0, sheet.Name)
+		if c.options.IncludeSheetNames {
+			section.Title = sheet.Name
+		}
 		if sheet.MaxRow == 0 || sheet.MaxCol == 0 {
 			continue
 		}
@@ -149,16 +245,9 @@ func (c *Converter) Convert(data []byte) (string, error) {
 			c.options.OnSheetParsed(sheet.Name, sheet.MaxRow, outputCols)
 		}
 
-		blocks := buildRangeBlocks(sheet)
-		for blockIndex, block := range blocks {
-			if blockIndex > 0 {
-				output.WriteString("\n")
-			}
-
-			blockTitle := formatBlockTitle(sheet.Name, block)
-			if blockTitle != "" {
-				output.WriteString(blockTitle)
-				output.WriteString("\n")
+		for _, block := range buildRangeBlocks(sheet) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
 			}
 
 			cols := visibleColumns(block, sheet, c.options.IncludeHidden)
@@ -167,29 +256,41 @@ func (c *Converter) Convert(data []byte) (string, error) {
 				continue
 			}
 
+			table := &document.Block{
+				Type:  document.BlockTable,
+				Title: blockTitle(sheet.Name, block),
+			}
 			headers := append([]string{"Row"}, columnLabels(cols, sheet, c.options.MarkHidden)...)
-			writeMarkdownRow(&output, headers)
-			writeSeparatorRow(&output, len(headers))
-
+			table.Rows = append(table.Rows, &document.Row{Header: true, Cells: headers})
 			for _, row := range rows {
-				rowLabel := formatRowLabel(row, sheet, c.options.MarkHidden)
-				values := []string{rowLabel}
+				values := []string{formatRowLabel(row, sheet, c.options.MarkHidden)}
 				for _, col := range cols {
-					values = append(values, cellDisplay(sheet, row, col, c.options.ShowFormulas))
+					values = append(values, cellValue(sheet, row, col, c.options.ShowFormulas))
 				}
-				writeMarkdownRow(&output, values)
+				table.Rows = append(table.Rows, &document.Row{Cells: values})
 			}
+			section.Blocks = append(section.Blocks, table)
 		}
 	}
 
-	markdown := output.String()
-
-	// Apply compact formatting if enabled
-	if c.options.Compact {
-		markdown = compactMarkdown(markdown)
-	}
+	return doc, nil
+}
 
-	return markdown, nil
+// workbookMetadata converts workbook properties to document metadata.
+// It returns nil if no properties are set.
+func workbookMetadata(props xlsx.CoreProperties) *document.Metadata {
+	meta := &document.Metadata{
+		Title:    props.Title,
+		Author:   props.Creator,
+		Subject:  props.Subject,
+		Keywords: props.Keywords,
+		Created:  props.Created,
+		Modified: props.Modified,
+	}
+	if *meta == (document.Metadata{}) {
+		return nil
+	}
+	return meta
 }
 
 // compactMarkdown reduces excessive blank lines in markdown.
@@ -283,15 +384,15 @@ func buildRangeBlocks(sheet *xlsx.Sheet) []rangeBlock {
 	return blocks
 }
 
-func formatBlockTitle(sheetName string, block rangeBlock) string {
+func blockTitle(sheetName string, block rangeBlock) string {
 	rangeRef := formatRangeRef(block.StartRow, block.StartCol, block.EndRow, block.EndCol)
 	if block.Kind == "table" {
 		if block.Name != "" {
-			return fmt.Sprintf("### Table: %s!%s (%s)", sheetName, rangeRef, block.Name)
+			return fmt.Sprintf("Table: %s!%s (%s)", sheetName, rangeRef, block.Name)
 		}
-		return fmt.Sprintf("### Table: %s!%s", sheetName, rangeRef)
+		return fmt.Sprintf("Table: %s!%s", sheetName, rangeRef)
 	}
-	return fmt.Sprintf("### Range: %s!%s", sheetName, rangeRef)
+	return fmt.Sprintf("Range: %s!%s", sheetName, rangeRef)
 }
 
 func visibleColumns(block rangeBlock, sheet *xlsx.Sheet, includeHidden bool) []int {
@@ -393,7 +494,7 @@ func formatRowLabel(row int, sheet *xlsx.Sheet, markHidden bool) string {
 	return label
 }
 
-func cellDisplay(sheet *xlsx.Sheet, row int, col int, showFormulas bool) string {
+func cellValue(sheet *xlsx.Sheet, row int, col int, showFormulas bool) string {
 	cell, ok := sheet.Cells[row][col]
 	if !ok {
 		return ""
@@ -416,7 +517,7 @@ func cellDisplay(sheet *xlsx.Sheet, row int, col int, showFormulas bool) string
 		value = "[merged]"
 	}
 
-	return sanitizeCell(value)
+	return value
 }
 
 func formatRangeRef(startRow int, startCol int, endRow int, endCol int) string {
@@ -445,30 +546,3 @@ func columnIndexToLetters(col int) string {
 	}
 	return string(runes)
 }
-
-func writeMarkdownRow(output *strings.Builder, values []string) {
-	output.WriteString("|")
-	for _, value := range values {
-		output.WriteString(" ")
-		output.WriteString(value)
-		output.WriteString(" |")
-	}
-	output.WriteString("\n")
-}
-
-func writeSeparatorRow(output *strings.Builder, cols int) {
-	output.WriteString("|")
-	for i := 0; i < cols; i++ {
-		output.WriteString(" --- |")
-	}
-	output.WriteString("\n")
-}
-
-func sanitizeCell(value string) string {
-	trimmed := strings.TrimSpace(value)
-	trimmed = strings.ReplaceAll(trimmed, "\r\n", "\n")
-	trimmed = strings.ReplaceAll(trimmed, "\r", "\n")
-	trimmed = strings.ReplaceAll(trimmed, "\n", "<br>")
-	trimmed = strings.ReplaceAll(trimmed, "|", "\\|")
-	return trimmed
-}
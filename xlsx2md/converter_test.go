@@ -3,8 +3,12 @@ package xlsx2md
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
 func createTestXlsx() []byte {
@@ -234,3 +238,55 @@ func TestConvertXLSXExcludeHidden(t *testing.T) {
 		t.Errorf("Expected hidden rows/cols excluded, got: %s", markdown)
 	}
 }
+
+func TestConvertXLSXContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	converter := New()
+	_, err := converter.ConvertContext(ctx, createTestXlsx())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestConvertToWriter(t *testing.T) {
+	data := createTestXlsx()
+
+	var out bytes.Buffer
+	converter := New()
+	if err := converter.ConvertToWriter(context.Background(), bytes.NewReader(data), int64(len(data)), &out); err != nil {
+		t.Fatalf("ConvertToWriter failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "| 2 | Alice | 42 |") {
+		t.Errorf("Expected data row in streamed output, got: %s", out.String())
+	}
+}
+
+func TestConvertDocument(t *testing.T) {
+	doc, err := New().ConvertDocument(createStructuredXlsx())
+	if err != nil {
+		t.Fatalf("ConvertDocument failed: %v", err)
+	}
+	if doc.Format != "xlsx" || len(doc.Sections) == 0 || doc.Sections[0].Name != "Sheet1" {
+		t.Fatalf("Expected a Sheet1 section, got %+v", doc.Sections)
+	}
+
+	blocks := doc.Sections[0].Blocks
+	if len(blocks) == 0 || blocks[0].Type != document.BlockTable {
+		t.Fatalf("Expected table blocks, got %+v", blocks)
+	}
+	if !strings.HasPrefix(blocks[0].Title, "Table: Sheet1!A1:B3") {
+		t.Errorf("Unexpected table title: %q", blocks[0].Title)
+	}
+
+	var found bool
+	for _, row := range blocks[0].Rows {
+		if !row.Header && len(row.Cells) == 3 && row.Cells[0] == "2" && row.Cells[2] == "2 (=SUM(A2:A3))" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected formula row in table, got %+v", blocks[0].Rows)
+	}
+}
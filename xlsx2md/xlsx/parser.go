@@ -3,16 +3,34 @@ package xlsx
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"math"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
+// ErrEncrypted is returned by Parse for password-protected workbooks
+var ErrEncrypted = errors.New("encrypted XLSX files are not supported")
+
 type Workbook struct {
-	Sheets []*Sheet
+	Sheets     []*Sheet
+	Properties CoreProperties
+}
+
+// CoreProperties holds workbook properties from docProps/core.xml
+type CoreProperties struct {
+	Title    string `xml:"title"`
+	Subject  string `xml:"subject"`
+	Creator  string `xml:"creator"`
+	Keywords string `xml:"keywords"`
+	Created  string `xml:"created"`
+	Modified string `xml:"modified"`
 }
 
 type Sheet struct {
@@ -173,58 +191,95 @@ type styleInfo struct {
 }
 
 func Parse(data []byte) (*Workbook, error) {
+	return ParseContext(context.Background(), data)
+}
+
+// maxColumn is the last column a worksheet can have (XFD)
+const maxColumn = 16384
+
+// archive is an open workbook with the limits on reading it
+type archive struct {
+	files  map[string]*zip.File
+	limits document.Limits
+	budget *document.Budget
+	cells  int // Cells read so far, for MaxCells
+}
+
+// ParseContext is like Parse but stops between sheets when ctx is cancelled.
+// It applies document.DefaultLimits.
+func ParseContext(ctx context.Context, data []byte) (*Workbook, error) {
+	return ParseWithLimits(ctx, data, document.DefaultLimits())
+}
+
+// ParseWithLimits is like ParseContext but applies the given limits.
+// Workbooks that exceed them fail with document.ErrLimitExceeded.
+func ParseWithLimits(ctx context.Context, data []byte, limits document.Limits) (*Workbook, error) {
+	if err := document.CheckLimit("input size", int64(len(data)), limits.MaxInputBytes); err != nil {
+		return nil, err
+	}
 	reader := bytes.NewReader(data)
 	zipReader, err := zip.NewReader(reader, int64(len(data)))
 	if err != nil {
 		if isOLEEncrypted(data) {
-			return nil, fmt.Errorf("encrypted XLSX files are not supported")
+			return nil, ErrEncrypted
 		}
 		return nil, fmt.Errorf("opening XLSX archive: %w", err)
 	}
 
-	files := make(map[string]*zip.File)
+	a := &archive{
+		files:  make(map[string]*zip.File),
+		limits: limits,
+		budget: document.NewBudget(limits),
+	}
 	for _, f := range zipReader.File {
-		files[f.Name] = f
+		a.files[f.Name] = f
 	}
 
-	if _, ok := files["xl/workbook.xml"]; !ok {
+	if _, ok := a.files["xl/workbook.xml"]; !ok {
 		return nil, fmt.Errorf("not a valid XLSX file: missing xl/workbook.xml")
 	}
 
-	sharedStrings, err := readSharedStrings(files)
+	sharedStrings, err := a.readSharedStrings()
 	if err != nil {
 		return nil, err
 	}
 
-	styles, err := readStyles(files)
+	styles, err := a.readStyles()
 	if err != nil {
 		return nil, err
 	}
 
-	workbookData, err := readWorkbook(files)
+	workbookData, err := a.readWorkbook()
 	if err != nil {
 		return nil, err
 	}
+	if err := document.CheckLimit("sheet count", len(workbookData.Sheets), limits.MaxSheets); err != nil {
+		return nil, err
+	}
 
-	rels, err := readWorkbookRels(files)
+	rels, err := a.readWorkbookRels()
 	if err != nil {
 		return nil, err
 	}
 
 	var sheets []*Sheet
 	for _, sheet := range workbookData.Sheets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		target := rels[sheet.ID]
 		if target == "" {
 			continue
 		}
 
 		path := normalizeTargetPath(target)
-		file, ok := files[path]
+		file, ok := a.files[path]
 		if !ok {
 			continue
 		}
 
-		parsedSheet, err := readSheet(file, sharedStrings, styles, files)
+		parsedSheet, err := a.readSheet(file, sharedStrings, styles)
 		if err != nil {
 			return nil, err
 		}
@@ -232,22 +287,44 @@ func Parse(data []byte) (*Workbook, error) {
 		sheets = append(sheets, parsedSheet)
 	}
 
-	return &Workbook{Sheets: sheets}, nil
+	properties := a.readCoreProperties()
+	if err := a.budget.Err(); err != nil {
+		return nil, err
+	}
+	return &Workbook{Sheets: sheets, Properties: properties}, nil
+}
+
+// readCoreProperties reads docProps/core.xml. The part is optional, so a
+// missing or invalid part yields empty properties.
+func (a *archive) readCoreProperties() CoreProperties {
+	var props CoreProperties
+	file, ok := a.files["docProps/core.xml"]
+	if !ok {
+		return props
+	}
+	data, err := a.readZipFile(file)
+	if err != nil {
+		return props
+	}
+	if err := unmarshalXLSXXML(data, &props, a.limits.MaxXMLDepth); err != nil {
+		return CoreProperties{}
+	}
+	return props
 }
 
-func readSharedStrings(files map[string]*zip.File) ([]string, error) {
-	file, ok := files["xl/sharedStrings.xml"]
+func (a *archive) readSharedStrings() ([]string, error) {
+	file, ok := a.files["xl/sharedStrings.xml"]
 	if !ok {
 		return nil, nil
 	}
 
-	data, err := readZipFile(file)
+	data, err := a.readZipFile(file)
 	if err != nil {
 		return nil, err
 	}
 
 	var sst sharedStrings
-	if err := unmarshalXLSXXML(data, &sst); err != nil {
+	if err := unmarshalXLSXXML(data, &sst, a.limits.MaxXMLDepth); err != nil {
 		return nil, fmt.Errorf("parsing shared strings: %w", err)
 	}
 
@@ -259,38 +336,38 @@ func readSharedStrings(files map[string]*zip.File) ([]string, error) {
 	return stringsOut, nil
 }
 
-func readWorkbook(files map[string]*zip.File) (*workbook, error) {
-	file, ok := files["xl/workbook.xml"]
+func (a *archive) readWorkbook() (*workbook, error) {
+	file, ok := a.files["xl/workbook.xml"]
 	if !ok {
 		return nil, fmt.Errorf("workbook.xml not found")
 	}
 
-	data, err := readZipFile(file)
+	data, err := a.readZipFile(file)
 	if err != nil {
 		return nil, err
 	}
 
 	var wb workbook
-	if err := unmarshalXLSXXML(data, &wb); err != nil {
+	if err := unmarshalXLSXXML(data, &wb, a.limits.MaxXMLDepth); err != nil {
 		return nil, fmt.Errorf("parsing workbook: %w", err)
 	}
 
 	return &wb, nil
 }
 
-func readWorkbookRels(files map[string]*zip.File) (map[string]string, error) {
-	file, ok := files["xl/_rels/workbook.xml.rels"]
+func (a *archive) readWorkbookRels() (map[string]string, error) {
+	file, ok := a.files["xl/_rels/workbook.xml.rels"]
 	if !ok {
 		return nil, fmt.Errorf("workbook relationships not found")
 	}
 
-	data, err := readZipFile(file)
+	data, err := a.readZipFile(file)
 	if err != nil {
 		return nil, err
 	}
 
 	var rels relationships
-	if err := unmarshalXLSXXML(data, &rels); err != nil {
+	if err := unmarshalXLSXXML(data, &rels, a.limits.MaxXMLDepth); err != nil {
 		return nil, fmt.Errorf("parsing workbook relationships: %w", err)
 	}
 
@@ -301,19 +378,19 @@ func readWorkbookRels(files map[string]*zip.File) (map[string]string, error) {
 	return out, nil
 }
 
-func readStyles(files map[string]*zip.File) (*styleInfo, error) {
-	file, ok := files["xl/styles.xml"]
+func (a *archive) readStyles() (*styleInfo, error) {
+	file, ok := a.files["xl/styles.xml"]
 	if !ok {
 		return nil, nil
 	}
 
-	data, err := readZipFile(file)
+	data, err := a.readZipFile(file)
 	if err != nil {
 		return nil, err
 	}
 
 	var styles styleSheet
-	if err := unmarshalXLSXXML(data, &styles); err != nil {
+	if err := unmarshalXLSXXML(data, &styles, a.limits.MaxXMLDepth); err != nil {
 		return nil, fmt.Errorf("parsing styles: %w", err)
 	}
 
@@ -334,14 +411,14 @@ func readStyles(files map[string]*zip.File) (*styleInfo, error) {
 	return info, nil
 }
 
-func readSheet(file *zip.File, sharedStrings []string, styles *styleInfo, files map[string]*zip.File) (*Sheet, error) {
-	data, err := readZipFile(file)
+func (a *archive) readSheet(file *zip.File, sharedStrings []string, styles *styleInfo) (*Sheet, error) {
+	data, err := a.readZipFile(file)
 	if err != nil {
 		return nil, err
 	}
 
 	var ws worksheet
-	if err := unmarshalXLSXXML(data, &ws); err != nil {
+	if err := unmarshalXLSXXML(data, &ws, a.limits.MaxXMLDepth); err != nil {
 		return nil, fmt.Errorf("parsing worksheet: %w", err)
 	}
 
@@ -355,7 +432,7 @@ func readSheet(file *zip.File, sharedStrings []string, styles *styleInfo, files
 		if !col.Hidden {
 			continue
 		}
-		for colIndex := col.Min; colIndex <= col.Max; colIndex++ {
+		for colIndex := col.Min; colIndex <= min(col.Max, maxColumn); colIndex++ {
 			sheet.HiddenCols[colIndex] = true
 		}
 	}
@@ -373,6 +450,12 @@ func readSheet(file *zip.File, sharedStrings []string, styles *styleInfo, files
 			if rowIndex == 0 || colIndex == 0 {
 				continue
 			}
+			if err := document.CheckLimit("row number", rowIndex, a.limits.MaxRows); err != nil {
+				return nil, err
+			}
+			if err := a.addCells(1); err != nil {
+				return nil, err
+			}
 
 			value := resolveCellValue(cell, sharedStrings, styles)
 
@@ -398,10 +481,22 @@ func readSheet(file *zip.File, sharedStrings []string, styles *styleInfo, files
 	}
 
 	sheet.Merges = parseMergeRanges(ws.MergeCells)
+	for _, merge := range sheet.Merges {
+		rows, cols := merge.EndRow-merge.StartRow+1, merge.EndCol-merge.StartCol+1
+		if rows <= 0 || cols <= 0 {
+			continue
+		}
+		if err := document.CheckLimit("row number", merge.EndRow, a.limits.MaxRows); err != nil {
+			return nil, err
+		}
+		if err := a.addCells(min(rows, math.MaxInt/cols) * cols); err != nil {
+			return nil, err
+		}
+	}
 	applyMerges(sheet)
 
 	if len(ws.TableParts.Parts) > 0 {
-		tables, err := readTableDefinitions(file.Name, ws.TableParts.Parts, files)
+		tables, err := a.readTableDefinitions(file.Name, ws.TableParts.Parts)
 		if err != nil {
 			return nil, err
 		}
@@ -411,14 +506,14 @@ func readSheet(file *zip.File, sharedStrings []string, styles *styleInfo, files
 	return sheet, nil
 }
 
-func readZipFile(file *zip.File) ([]byte, error) {
+func (a *archive) readZipFile(file *zip.File) ([]byte, error) {
 	rc, err := file.Open()
 	if err != nil {
 		return nil, fmt.Errorf("opening %s: %w", file.Name, err)
 	}
 	defer func() { _ = rc.Close() }()
 
-	data, err := io.ReadAll(rc)
+	data, err := a.budget.ReadAll(file.Name, rc)
 	if err != nil {
 		return nil, fmt.Errorf("reading %s: %w", file.Name, err)
 	}
@@ -426,6 +521,12 @@ func readZipFile(file *zip.File) ([]byte, error) {
 	return data, nil
 }
 
+// addCells counts n more cells against the MaxCells limit
+func (a *archive) addCells(n int) error {
+	a.cells += n
+	return document.CheckLimit("cell count", a.cells, a.limits.MaxCells)
+}
+
 func normalizeTargetPath(target string) string {
 	path := strings.TrimPrefix(target, "/")
 	if !strings.HasPrefix(path, "xl/") {
@@ -439,20 +540,20 @@ func sheetRelsPath(sheetPath string) string {
 	return filepath.ToSlash(filepath.Join("xl/worksheets/_rels", base+".rels"))
 }
 
-func readSheetRels(sheetPath string, files map[string]*zip.File) (map[string]string, error) {
+func (a *archive) readSheetRels(sheetPath string) (map[string]string, error) {
 	relsPath := sheetRelsPath(sheetPath)
-	file, ok := files[relsPath]
+	file, ok := a.files[relsPath]
 	if !ok {
 		return nil, nil
 	}
 
-	data, err := readZipFile(file)
+	data, err := a.readZipFile(file)
 	if err != nil {
 		return nil, err
 	}
 
 	var rels relationships
-	if err := unmarshalXLSXXML(data, &rels); err != nil {
+	if err := unmarshalXLSXXML(data, &rels, a.limits.MaxXMLDepth); err != nil {
 		return nil, fmt.Errorf("parsing sheet relationships: %w", err)
 	}
 
@@ -463,8 +564,8 @@ func readSheetRels(sheetPath string, files map[string]*zip.File) (map[string]str
 	return out, nil
 }
 
-func readTableDefinitions(sheetPath string, parts []tablePart, files map[string]*zip.File) ([]Table, error) {
-	rels, err := readSheetRels(sheetPath, files)
+func (a *archive) readTableDefinitions(sheetPath string, parts []tablePart) ([]Table, error) {
+	rels, err := a.readSheetRels(sheetPath)
 	if err != nil {
 		return nil, err
 	}
@@ -476,18 +577,18 @@ func readTableDefinitions(sheetPath string, parts []tablePart, files map[string]
 			continue
 		}
 		path := resolveRelationshipTarget(sheetPath, target)
-		file, ok := files[path]
+		file, ok := a.files[path]
 		if !ok {
 			continue
 		}
 
-		data, err := readZipFile(file)
+		data, err := a.readZipFile(file)
 		if err != nil {
 			return nil, err
 		}
 
 		var def tableDefinition
-		if err := unmarshalXLSXXML(data, &def); err != nil {
+		if err := unmarshalXLSXXML(data, &def, a.limits.MaxXMLDepth); err != nil {
 			return nil, fmt.Errorf("parsing table definition: %w", err)
 		}
 
@@ -3,8 +3,12 @@ package xlsx
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
 func TestParseEncryptedXLSX(t *testing.T) {
@@ -45,6 +49,26 @@ func TestParseTableDefinition(t *testing.T) {
 	}
 }
 
+func TestParseWithLimits(t *testing.T) {
+	data := createTableXlsx()
+	tests := []document.Limits{
+		{MaxRows: 2},
+		{MaxCells: 5},
+		{MaxXMLDepth: 3},
+		{MaxStreamBytes: 64},
+	}
+	for _, limits := range tests {
+		_, err := ParseWithLimits(context.Background(), data, limits)
+		if !errors.Is(err, document.ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded with %+v, got %v", limits, err)
+		}
+	}
+
+	if _, err := ParseWithLimits(context.Background(), data, document.Limits{MaxRows: 3, MaxCells: 6}); err != nil {
+		t.Errorf("Expected the workbook to fit its exact size, got %v", err)
+	}
+}
+
 func createTableXlsx() []byte {
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)
@@ -5,18 +5,23 @@ import (
 	"encoding/xml"
 	"io"
 	"strings"
+
+	"github.com/tenebris-tech/x2md/document"
 )
 
-func unmarshalXLSXXML(data []byte, v interface{}) error {
+// unmarshalXLSXXML decodes data into v, ignoring namespaces. Elements nested
+// deeper than maxDepth (0 for no limit) fail with ErrLimitExceeded.
+func unmarshalXLSXXML(data []byte, v interface{}, maxDepth int) error {
 	decoder := xml.NewDecoder(bytes.NewReader(data))
 	decoder.Strict = false
 	decoder.Entity = xml.HTMLEntity
 
-	return decodeWithNamespaceStripping(decoder, v)
+	return decodeWithNamespaceStripping(decoder, v, maxDepth)
 }
 
-func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}) error {
+func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}, maxDepth int) error {
 	var tokens []xml.Token
+	depth := 0
 	for {
 		tok, err := decoder.Token()
 		if err == io.EOF {
@@ -28,6 +33,10 @@ func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}) error {
 
 		switch t := tok.(type) {
 		case xml.StartElement:
+			depth++
+			if err := document.CheckLimit("XML nesting depth", depth, maxDepth); err != nil {
+				return err
+			}
 			t.Name.Local = stripNamespacePrefix(t.Name.Local)
 			t.Name.Space = ""
 			for i := range t.Attr {
@@ -36,6 +45,7 @@ func decodeWithNamespaceStripping(decoder *xml.Decoder, v interface{}) error {
 			}
 			tok = t
 		case xml.EndElement:
+			depth--
 			t.Name.Local = stripNamespacePrefix(t.Name.Local)
 			t.Name.Space = ""
 			tok = t